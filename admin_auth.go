@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/token"
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Key used to store the authenticated operator in the echo.Context.
+const operatorContextKey = "operator"
+
+// newAdminAuthMiddleware protects the back office with HTTP basic auth. The
+// operator configured by ADMIN_USER and ADMIN_PASSWORD is always an admin and
+// can create the other operators, which are stored in the database. The
+// operator is stored in the context, see authenticatedOperator.
+func newAdminAuthMiddleware(operators db.OperatorStore, user, password string) echo.MiddlewareFunc {
+	return middleware.BasicAuth(func(u, p string, c echo.Context) (bool, error) {
+		userOK := subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		if userOK && passwordOK {
+			c.Set(operatorContextKey, &db.Operator{ID: user, Name: user, Role: db.RoleAdmin})
+			return true, nil
+		}
+		operator, err := operators.FindOperator(u)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return false, nil
+		case err != nil:
+			log.Printf("failed to find operator (%s), error %v\n", u, err)
+			return false, err
+		}
+		if !operator.DisabledAt.IsZero() || bcrypt.CompareHashAndPassword([]byte(operator.PasswordHash), []byte(p)) != nil {
+			return false, nil
+		}
+		c.Set(operatorContextKey, operator)
+		return true, nil
+	})
+}
+
+// requireRole only lets operators with at least the given role through.
+func requireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !authenticatedOperator(c).Can(role) {
+				return c.String(http.StatusForbidden, "Seu papel não permite esta operação.")
+			}
+			return next(c)
+		}
+	}
+}
+
+// sameOriginMiddleware rejects forms posted from other sites. Browsers send
+// the basic auth credentials along with any request to the back office, so
+// they could otherwise be used to make changes on behalf of operators. Forms
+// without Origin nor Referer headers are rejected too, as their origin can
+// not be told.
+func sameOriginMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return next(c)
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			origin = r.Header.Get("Referer")
+		}
+		u, err := url.Parse(origin)
+		if origin == "" || err != nil || u.Host != r.Host {
+			return c.String(http.StatusForbidden, "Origem da requisição não permitida.")
+		}
+		return next(c)
+	}
+}
+
+// authenticatedOperator returns the operator resolved by the admin auth
+// middleware.
+func authenticatedOperator(c echo.Context) *db.Operator {
+	return c.Get(operatorContextKey).(*db.Operator)
+}
+
+// registerAdminRoutes registers the back office routes. Viewers can browse
+// everything, editors can change candidatures, revoke sessions, moderate
+// profile changes and photos, handle reports and messages, requeue emails and
+// adjust the campaign calendar and admins can also roll profiles back and
+// manage operators.
+func registerAdminRoutes(g *echo.Group, dbClient db.Store, calendar *campaignCalendar, tokenService *token.Token, user, password string) {
+	g.Use(newAdminAuthMiddleware(dbClient, user, password), sameOriginMiddleware)
+	viewer, editor, admin := requireRole(db.RoleViewer), requireRole(db.RoleEditor), requireRole(db.RoleAdmin)
+	g.GET("", func(c echo.Context) error {
+		return c.Redirect(http.StatusSeeOther, "/admin/candidaturas")
+	}, viewer)
+	g.GET("/candidaturas", newAdminCandidaturasHandler(dbClient), viewer)
+	g.GET("/candidaturas/:year/:id", newAdminCandidaturaHandler(dbClient), viewer)
+	g.POST("/candidaturas/:year/:id", newAdminUpdateCandidaturaHandler(dbClient), editor)
+	g.POST("/candidaturas/:year/:id/resetar-termo", newAdminResetTermsHandler(dbClient), editor)
+	g.POST("/candidaturas/:year/:id/reenviar-acesso", newAdminResendAccessHandler(dbClient, tokenService), editor)
+	g.POST("/candidaturas/:year/:id/revogar-sessoes", newAdminRevokeSessionsHandler(dbClient), editor)
+	g.POST("/candidaturas/:year/:id/desativar", newAdminDisableProfileHandler(dbClient), editor)
+	g.POST("/candidaturas/:year/:id/reativar", newAdminEnableProfileHandler(dbClient), editor)
+	g.POST("/candidaturas/:year/:id/revisoes/:number/restaurar", newAdminRestoreRevisionHandler(dbClient), admin)
+	g.GET("/moderacao", newAdminModeracaoHandler(dbClient), viewer)
+	g.GET("/moderacao/:id", newAdminSubmissaoHandler(dbClient), viewer)
+	g.POST("/moderacao/:id/aprovar", newAdminApproveSubmissionHandler(dbClient), editor)
+	g.POST("/moderacao/:id/rejeitar", newAdminRejectSubmissionHandler(dbClient), editor)
+	g.POST("/moderacao/fotos/:id/aprovar", newAdminApprovePhotoHandler(dbClient), editor)
+	g.POST("/moderacao/fotos/:id/rejeitar", newAdminRejectPhotoHandler(dbClient), editor)
+	g.GET("/denuncias", newAdminTicketsHandler(dbClient, db.TicketProfileReport, "admin-denuncias.html"), viewer)
+	g.GET("/denuncias/:id", newAdminDenunciaHandler(dbClient), viewer)
+	g.POST("/denuncias/:id", newAdminUpdateTicketHandler(dbClient, db.TicketProfileReport), editor)
+	g.GET("/fale-conosco", newAdminTicketsHandler(dbClient, db.TicketContact, "admin-fale-conosco.html"), viewer)
+	g.GET("/fale-conosco/:id", newAdminFaleConoscoTicketHandler(dbClient), viewer)
+	g.POST("/fale-conosco/:id", newAdminUpdateTicketHandler(dbClient, db.TicketContact), editor)
+	g.POST("/fale-conosco/:id/responder", newAdminReplyTicketHandler(dbClient), editor)
+	g.GET("/auditoria", newAdminAuditHandler(dbClient), viewer)
+	g.GET("/emails", newAdminEmailsHandler(dbClient), viewer)
+	g.POST("/emails/:id/reenviar", newAdminRequeueEmailHandler(dbClient), editor)
+	g.GET("/calendario", newAdminCalendarioHandler(calendar), viewer)
+	g.POST("/calendario", newAdminUpdateCalendarioHandler(dbClient, calendar), editor)
+	g.GET("/operadores", newAdminOperatorsHandler(dbClient), admin)
+	g.POST("/operadores", newAdminCreateOperatorHandler(dbClient), admin)
+	g.POST("/operadores/:id/desativar", newAdminDisableOperatorHandler(dbClient), admin)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/labstack/echo"
+)
+
+// calendarInputLayout is the layout of the datetime-local inputs of the
+// calendar form.
+const calendarInputLayout = "2006-01-02T15:04"
+
+// calendarRow is a phase of the calendar as shown in the back office.
+type calendarRow struct {
+	Phase  string
+	Label  string
+	Opens  string
+	Closes string
+	Closed bool
+	Open   bool
+}
+
+// calendarYear returns the election year of the ano parameter, the current
+// one by default.
+func calendarYear(c echo.Context) (int, error) {
+	if v := c.FormValue("ano"); v != "" {
+		return strconv.Atoi(v)
+	}
+	return globals.Year, nil
+}
+
+// GET /admin/calendario shows the campaign calendar of the year.
+func newAdminCalendarioHandler(calendar *campaignCalendar) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		year, err := calendarYear(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "ano inválido")
+		}
+		cal, err := calendar.calendar(year)
+		if err != nil {
+			log.Printf("failed to find calendar (%d), error %v\n", year, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		now := time.Now()
+		var rows []*calendarRow
+		for _, phase := range db.Phases {
+			w := cal.Window(phase)
+			row := &calendarRow{Phase: phase, Label: phasesUI[phase], Closed: w.Closed, Open: w.Open(now)}
+			if !w.Opens.IsZero() {
+				row.Opens = w.Opens.In(calendarLocation).Format(calendarInputLayout)
+			}
+			if !w.Closes.IsZero() {
+				row.Closes = w.Closes.In(calendarLocation).Format(calendarInputLayout)
+			}
+			rows = append(rows, row)
+		}
+		operator := authenticatedOperator(c)
+		return c.Render(http.StatusOK, "admin-calendario.html", map[string]interface{}{
+			"Calendar":     cal,
+			"ElectionYear": year,
+			"Rows":         rows,
+			"CanEdit":      operator.Can(db.RoleEditor),
+			"Operator":     operator,
+		})
+	}
+}
+
+// POST /admin/calendario replaces the campaign calendar of the year. Each
+// phase is sent as the <phase>_abre and <phase>_fecha dates, in the time of
+// Brasília and empty for no limit, and the <phase>_suspenso flag. The windows
+// changed are recorded in the audit trail.
+func newAdminUpdateCalendarioHandler(dbClient db.Store, calendar *campaignCalendar) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		year, err := calendarYear(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "ano inválido")
+		}
+		before, err := calendar.calendar(year)
+		if err != nil {
+			log.Printf("failed to find calendar (%d), error %v\n", year, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		operator := authenticatedOperator(c)
+		cal := &db.Calendar{
+			Year:      year,
+			Windows:   make(map[string]*db.CalendarWindow),
+			UpdatedBy: operator.ID,
+			UpdatedAt: time.Now().UTC(),
+		}
+		for _, phase := range db.Phases {
+			w := &db.CalendarWindow{Closed: c.FormValue(phase+"_suspenso") == "1"}
+			if w.Opens, err = parseCalendarInput(c.FormValue(phase + "_abre")); err != nil {
+				return c.String(http.StatusBadRequest, fmt.Sprintf("Data de abertura de %s inválida.", phasesUI[phase]))
+			}
+			if w.Closes, err = parseCalendarInput(c.FormValue(phase + "_fecha")); err != nil {
+				return c.String(http.StatusBadRequest, fmt.Sprintf("Data de encerramento de %s inválida.", phasesUI[phase]))
+			}
+			if !w.Opens.IsZero() && !w.Closes.IsZero() && !w.Closes.After(w.Opens) {
+				return c.String(http.StatusBadRequest, fmt.Sprintf("O encerramento de %s precisa ser depois da abertura.", phasesUI[phase]))
+			}
+			cal.Windows[phase] = w
+		}
+		if err := dbClient.SaveCalendar(cal); err != nil {
+			log.Printf("failed to save calendar (%d), error %v\n", year, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		var changes []*db.FieldChange
+		for _, phase := range db.Phases {
+			b, a := formatCalendarWindow(before.Window(phase)), formatCalendarWindow(cal.Window(phase))
+			if b != a {
+				changes = append(changes, &db.FieldChange{Field: phasesUI[phase], Before: b, After: a})
+			}
+		}
+		if err := audit(dbClient, operatorActor(operator), "", db.AuditUpdateCalendar, year, "", changes); err != nil {
+			log.Printf("failed to audit calendar updated (%d), error %v\n", year, err)
+		}
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/admin/calendario?ano=%d", year))
+	}
+}
+
+// formatCalendarWindow describes the window for the audit trail, with the
+// dates in the time of Brasília.
+func formatCalendarWindow(w *db.CalendarWindow) string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "sem limite"
+		}
+		return t.In(calendarLocation).Format("02/01/2006 15:04")
+	}
+	s := fmt.Sprintf("abre: %s, fecha: %s", format(w.Opens), format(w.Closes))
+	if w.Closed {
+		s += ", suspenso"
+	}
+	return s
+}
+
+// parseCalendarInput parses the date of a datetime-local input in the time
+// of Brasília. Empty values are zero times.
+func parseCalendarInput(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(calendarInputLayout, v, calendarLocation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/token"
+	"github.com/labstack/echo"
+)
+
+const (
+	adminSearchPageSize = 50
+	adminAuditPageSize  = 200
+)
+
+// Sequential IDs have 11 or 12 digits, ballot numbers have up to 5.
+var sequentialIDRegex = regexp.MustCompile(`^[0-9]{9,}$`)
+
+// GET /admin/candidaturas searches candidatures by email, sequential ID,
+// name or ballot number (q query param) in the election year (ano query
+// param, the current election by default).
+func newAdminCandidaturasHandler(dbClient db.CandidateStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		q := strings.TrimSpace(c.QueryParam("q"))
+		year := globals.Year
+		if ano := c.QueryParam("ano"); ano != "" {
+			y, err := strconv.Atoi(ano)
+			if err != nil {
+				return c.String(http.StatusBadRequest, "ano inválido")
+			}
+			year = y
+		}
+		candidates, err := searchCandidaturesForAdmin(dbClient, q, year)
+		if err != nil {
+			log.Printf("failed to search candidatures for admin (%s), error %v\n", q, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-candidaturas.html", map[string]interface{}{
+			"Query":      q,
+			"Year":       year,
+			"Candidates": candidates,
+			"Operator":   authenticatedOperator(c),
+		})
+	}
+}
+
+func searchCandidaturesForAdmin(dbClient db.CandidateStore, q string, year int) ([]*descritor.CandidateForDB, error) {
+	switch {
+	case q == "":
+		return nil, nil
+	case strings.Contains(q, "@"):
+		return dbClient.FindCandidatesByEmail(strings.ToUpper(q), year)
+	case sequentialIDRegex.MatchString(q):
+		candidate, err := dbClient.FindCandidateBySequencialIDAndYear(year, q)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return nil, nil
+		case err != nil:
+			return nil, err
+		}
+		return []*descritor.CandidateForDB{candidate}, nil
+	}
+	query := map[string]interface{}{"name": q, "year": year}
+	transparent, err := dbClient.FindTransparentCandidatures(query, db.Page{Number: 1, Size: adminSearchPageSize})
+	if err != nil {
+		return nil, err
+	}
+	query = map[string]interface{}{"name": q, "year": year}
+	opaque, err := dbClient.FindNonTransparentCandidatures(query, db.Page{Number: 1, Size: adminSearchPageSize})
+	if err != nil {
+		return nil, err
+	}
+	return append(transparent.Candidatures, opaque.Candidatures...), nil
+}
+
+// findAdminCandidate returns the candidature identified by the year and id
+// path params, writing the error response when it fails.
+func findAdminCandidate(c echo.Context, dbClient db.CandidateStore) (*descritor.CandidateForDB, error) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return nil, c.String(http.StatusBadRequest, "ano inválido")
+	}
+	candidate, err := dbClient.FindCandidateBySequencialIDAndYear(year, c.Param("id"))
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		return nil, c.String(http.StatusNotFound, "Candidatura não encontrada.")
+	case err != nil:
+		log.Printf("failed to find candidature (%s/%d), error %v\n", c.Param("id"), year, err)
+		return nil, c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+	}
+	return candidate, nil
+}
+
+func adminCandidatePath(candidate *descritor.CandidateForDB) string {
+	return fmt.Sprintf("/admin/candidaturas/%d/%s", candidate.Year, candidate.SequencialCandidate)
+}
+
+// GET /admin/candidaturas/:year/:id shows the candidature, its profile and
+// audit trail.
+func newAdminCandidaturaHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		disabled, err := dbClient.FindDisabledProfile(candidate.Year, candidate.SequencialCandidate)
+		if err != nil && err.(*exception.Exception).Code != exception.NotFound {
+			log.Printf("failed to find disabled profile (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		entries, err := dbClient.ListAuditEntries(candidate.Year, candidate.SequencialCandidate, adminAuditPageSize)
+		if err != nil {
+			log.Printf("failed to list audit entries (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		revisions, err := dbClient.ListProfileRevisions(candidate.Year, candidate.SequencialCandidate)
+		if err != nil {
+			log.Printf("failed to list profile revisions (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		operator := authenticatedOperator(c)
+		// An empty row lets operators add a proposal and a contact.
+		proposalRows := append(candidate.Proposals, &descritor.Proposal{})
+		contactRows := append(candidate.Contacts, &descritor.Contact{})
+		return c.Render(http.StatusOK, "admin-candidatura.html", map[string]interface{}{
+			"Candidate":      candidate,
+			"ProposalRows":   proposalRows,
+			"ContactRows":    contactRows,
+			"Disabled":       disabled,
+			"AuditEntries":   entries,
+			"Revisions":      revisions,
+			"CanRestore":     operator.Can(db.RoleAdmin),
+			"SocialNetworks": socialNetworksUI,
+			"Tags":           tags,
+			"CanEdit":        operator.Can(db.RoleEditor),
+			"CanResend":      candidate.Year == globals.Year,
+			"Operator":       operator,
+		})
+	}
+}
+
+// POST /admin/candidaturas/:year/:id updates the biography, proposals and
+// contacts of the candidature. Proposals and contacts are sent as lists of
+// topico/proposta and rede/contato fields, empty ones are ignored.
+func newAdminUpdateCandidaturaHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		form, err := c.FormParams()
+		if err != nil {
+			return c.String(http.StatusBadRequest, "formulário inválido")
+		}
+		bio := strings.TrimSpace(c.FormValue("biografia"))
+		if utf8.RuneCountInString(bio) > maxBiographyTextSize {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Tamanho máximo do campo mini-biografia é de %d caracteres.", maxBiographyTextSize))
+		}
+		var proposals []*descritor.Proposal
+		topics, descriptions := form["topico"], form["proposta"]
+		for i := 0; i < len(topics) && i < len(descriptions); i++ {
+			topic, description := strings.TrimSpace(topics[i]), strings.TrimSpace(descriptions[i])
+			if topic == "" && description == "" {
+				continue
+			}
+			if topic == "" || description == "" {
+				return c.String(http.StatusBadRequest, "Cada proposta precisa de pauta e descrição.")
+			}
+			if n := utf8.RuneCountInString(description); n > maxProposalsTextSize {
+				return c.String(http.StatusBadRequest, fmt.Sprintf("Tamanho da proposta da pauta %s é de %d caracteres. O tamanho máximo permitido é de %d.", topic, n, maxProposalsTextSize))
+			}
+			proposals = append(proposals, &descritor.Proposal{Topic: topic, Description: description})
+		}
+		if len(proposals) > maxProposals {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("O número máximo de propostas é %d.", maxProposals))
+		}
+		errs := newFormErrors()
+		contacts := parseContacts(form["rede"], form["contato"], errs)
+		if !errs.empty() {
+			return c.String(http.StatusBadRequest, strings.Join(errs.Messages(), "\n"))
+		}
+		candidate.Biography = bio
+		candidate.Proposals = proposals
+		candidate.Contacts = contacts
+		candidate.Transparency = 0
+		if len(proposals) > 0 {
+			candidate.Transparency = 100
+		}
+		if err := saveCandidateProfile(dbClient, candidate, operatorActor(authenticatedOperator(c)), "", db.AuditUpdateProfile); err != nil {
+			log.Printf("failed to update candidate profile (%s) from admin, error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// POST /admin/candidaturas/:year/:id/resetar-termo makes the candidate
+// accept the terms again on the next access.
+func newAdminResetTermsHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		candidate.AcceptedTerms = time.Time{}
+		if err := saveCandidateProfile(dbClient, candidate, operatorActor(authenticatedOperator(c)), "", db.AuditResetTerms); err != nil {
+			log.Printf("failed to reset accepted terms (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// POST /admin/candidaturas/:year/:id/reenviar-acesso queues a new access
+// link to the email of the candidature.
+func newAdminResendAccessHandler(dbClient db.Store, tokenService *token.Token) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		if candidate.Year != globals.Year {
+			return c.String(http.StatusBadRequest, "Só é possível enviar acesso a candidaturas da eleição atual.")
+		}
+		address := strings.ToLower(candidate.Email)
+		link, err := newLoginLink(dbClient, tokenService, address, candidate.SequencialCandidate, c.RealIP(), c.Request().UserAgent())
+		if err != nil {
+			log.Printf("failed to get login link for candidature (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		message, err := buildProfileAccessEmail(address, []*descritor.CandidateForDB{candidate}, []string{link})
+		if err != nil {
+			log.Printf("failed to build access email (%s), error %v\n", address, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		message.From = emailFrom
+		message.To = []string{candidate.Email}
+		if _, err := enqueueEmail(dbClient, "", message); err != nil {
+			log.Printf("failed on queueing email (%s), error %v\n", address, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		if err := audit(dbClient, operatorActor(authenticatedOperator(c)), "", db.AuditResendAccess, candidate.Year, candidate.SequencialCandidate, nil); err != nil {
+			log.Printf("failed to audit access resent (%s), error %v\n", candidate.SequencialCandidate, err)
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// POST /admin/candidaturas/:year/:id/revogar-sessoes revokes every session
+// and unused login link of the candidature. Other candidatures of the same
+// email stay signed in.
+func newAdminRevokeSessionsHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		if err := dbClient.RevokeAllByCandidature(candidate.Year, candidate.SequencialCandidate, time.Now().UTC()); err != nil {
+			log.Printf("failed to revoke sessions (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		if err := audit(dbClient, operatorActor(authenticatedOperator(c)), "", db.AuditRevokeSessions, candidate.Year, candidate.SequencialCandidate, nil); err != nil {
+			log.Printf("failed to audit sessions revoked (%s), error %v\n", candidate.SequencialCandidate, err)
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// POST /admin/candidaturas/:year/:id/desativar disables the profile of the
+// candidature, the reason (motivo form field) is required. Sessions and
+// unused login links of the candidature are revoked.
+func newAdminDisableProfileHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		reason := strings.TrimSpace(c.FormValue("motivo"))
+		if reason == "" {
+			return c.String(http.StatusBadRequest, "O motivo é obrigatório.")
+		}
+		operator := authenticatedOperator(c)
+		now := time.Now().UTC()
+		if err := dbClient.DisableProfile(&db.DisabledProfile{
+			Year:         candidate.Year,
+			SequentialID: candidate.SequencialCandidate,
+			Reason:       reason,
+			DisabledBy:   operator.ID,
+			DisabledAt:   now,
+		}); err != nil {
+			log.Printf("failed to disable profile (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		if err := dbClient.RevokeAllByCandidature(candidate.Year, candidate.SequencialCandidate, now); err != nil {
+			log.Printf("failed to revoke sessions (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		changes := []*db.FieldChange{{Field: "motivo da desativação", After: reason}}
+		if err := audit(dbClient, operatorActor(operator), "", db.AuditDisableProfile, candidate.Year, candidate.SequencialCandidate, changes); err != nil {
+			log.Printf("failed to audit profile disabled (%s), error %v\n", candidate.SequencialCandidate, err)
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// POST /admin/candidaturas/:year/:id/reativar enables a disabled profile.
+func newAdminEnableProfileHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		err = dbClient.EnableProfile(candidate.Year, candidate.SequencialCandidate)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusNotFound, "Perfil não está desativado.")
+		case err != nil:
+			log.Printf("failed to enable profile (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		if err := audit(dbClient, operatorActor(authenticatedOperator(c)), "", db.AuditEnableProfile, candidate.Year, candidate.SequencialCandidate, nil); err != nil {
+			log.Printf("failed to audit profile enabled (%s), error %v\n", candidate.SequencialCandidate, err)
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// POST /admin/candidaturas/:year/:id/revisoes/:number/restaurar rolls the
+// profile back to a previous revision.
+func newAdminRestoreRevisionHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate, err := findAdminCandidate(c, dbClient)
+		if candidate == nil {
+			return err
+		}
+		number, err := strconv.Atoi(c.Param("number"))
+		if err != nil {
+			return c.String(http.StatusBadRequest, "revisão inválida")
+		}
+		revision, err := dbClient.FindProfileRevision(candidate.Year, candidate.SequencialCandidate, number)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusNotFound, "Revisão não encontrada.")
+		case err != nil:
+			log.Printf("failed to find profile revision (%s/%d), error %v\n", candidate.SequencialCandidate, number, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		if err := restoreProfileRevision(dbClient, candidate, revision, operatorActor(authenticatedOperator(c))); err != nil {
+			log.Printf("failed to restore profile revision (%s/%d), error %v\n", candidate.SequencialCandidate, number, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, adminCandidatePath(candidate))
+	}
+}
+
+// GET /admin/auditoria lists the latest changes to all profiles.
+func newAdminAuditHandler(auditStore db.AuditStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		entries, err := auditStore.ListAuditEntries(0, "", adminAuditPageSize)
+		if err != nil {
+			log.Printf("failed to list audit entries, error %v\n", err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-auditoria.html", map[string]interface{}{
+			"AuditEntries": entries,
+			"Operator":     authenticatedOperator(c),
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+const adminEmailsPageSize = 100
+
+// GET /admin/emails lists the emails of the outbox, optionally filtered by
+// the status query param.
+func newAdminEmailsHandler(outbox db.OutboxStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status := c.QueryParam("status")
+		switch status {
+		case "", db.EmailQueued, db.EmailSent, db.EmailDead:
+		default:
+			return c.String(http.StatusBadRequest, "status inválido")
+		}
+		emails, err := outbox.ListEmails(status, adminEmailsPageSize)
+		if err != nil {
+			log.Printf("failed to list outbox emails, error %v\n", err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-emails.html", map[string]interface{}{
+			"Emails":   emails,
+			"Status":   status,
+			"Statuses": []string{db.EmailQueued, db.EmailSent, db.EmailDead},
+			"Operator": authenticatedOperator(c),
+		})
+	}
+}
+
+// POST /admin/emails/:id/reenviar puts a dead email back in the queue.
+func newAdminRequeueEmailHandler(outbox db.OutboxStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := outbox.RequeueEmail(c.Param("id"), time.Now().UTC())
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusNotFound, "Email não encontrado ou não está com falha.")
+		case err != nil:
+			log.Printf("failed to requeue email (%s), error %v\n", c.Param("id"), err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, "/admin/emails?status="+db.EmailQueued)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+const adminSubmissionsPageSize = 100
+
+// GET /admin/moderacao lists the profile submissions and photos with the
+// status query param, pending ones by default, oldest first.
+func newAdminModeracaoHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status := c.QueryParam("status")
+		switch status {
+		case "":
+			status = db.SubmissionPending
+		case db.SubmissionPending, db.SubmissionApproved, db.SubmissionRejected, db.SubmissionSuperseded:
+		default:
+			return c.String(http.StatusBadRequest, "status inválido")
+		}
+		list, err := dbClient.ListSubmissions(status, adminSubmissionsPageSize)
+		if err != nil {
+			log.Printf("failed to list profile submissions, error %v\n", err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		photos, err := dbClient.ListPhotoSubmissions(status, adminSubmissionsPageSize)
+		if err != nil {
+			log.Printf("failed to list photo submissions, error %v\n", err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-moderacao.html", map[string]interface{}{
+			"Submissions": list,
+			"Photos":      photos,
+			"CardSize":    photoCardSize,
+			"Status":      status,
+			"Statuses":    []string{db.SubmissionPending, db.SubmissionApproved, db.SubmissionRejected, db.SubmissionSuperseded},
+			"Operator":    authenticatedOperator(c),
+		})
+	}
+}
+
+// findAdminSubmission returns the submission of the id path param. On
+// failure it returns a nil submission and the error response.
+func findAdminSubmission(c echo.Context, submissions db.ModerationStore) (*db.ProfileSubmission, error) {
+	s, err := submissions.FindSubmission(c.Param("id"))
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		return nil, c.String(http.StatusNotFound, "Submissão não encontrada.")
+	case err != nil:
+		log.Printf("failed to find profile submission (%s), error %v\n", c.Param("id"), err)
+		return nil, c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+	}
+	return s, nil
+}
+
+// GET /admin/moderacao/:id shows the submission and what it changes in the
+// published profile.
+func newAdminSubmissaoHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s, err := findAdminSubmission(c, dbClient)
+		if s == nil {
+			return err
+		}
+		candidate, err := dbClient.FindCandidateBySequencialIDAndYear(s.Year, s.SequentialID)
+		if err != nil {
+			log.Printf("failed to find candidate of submission (%s), error %v\n", s.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		operator := authenticatedOperator(c)
+		return c.Render(http.StatusOK, "admin-submissao.html", map[string]interface{}{
+			"Submission": s,
+			"Candidate":  candidate,
+			"Changes":    submissionChanges(candidate, s),
+			"CanReview":  s.Status == db.SubmissionPending && operator.Can(db.RoleEditor),
+			"Operator":   operator,
+		})
+	}
+}
+
+// POST /admin/moderacao/:id/aprovar publishes a pending submission.
+func newAdminApproveSubmissionHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s, err := findAdminSubmission(c, dbClient)
+		if s == nil {
+			return err
+		}
+		err = approveSubmission(dbClient, s, authenticatedOperator(c))
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusConflict, "Submissão já moderada.")
+		case err != nil:
+			log.Printf("failed to approve profile submission (%s), error %v\n", s.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, "/admin/moderacao")
+	}
+}
+
+// POST /admin/moderacao/:id/rejeitar rejects a pending submission, the
+// reason (motivo form field) is required and emailed to the candidate.
+func newAdminRejectSubmissionHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s, err := findAdminSubmission(c, dbClient)
+		if s == nil {
+			return err
+		}
+		reason := strings.TrimSpace(c.FormValue("motivo"))
+		if reason == "" {
+			return c.String(http.StatusBadRequest, "O motivo é obrigatório.")
+		}
+		err = rejectSubmission(dbClient, s, authenticatedOperator(c), reason)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusConflict, "Submissão já moderada.")
+		case err != nil:
+			log.Printf("failed to reject profile submission (%s), error %v\n", s.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, "/admin/moderacao")
+	}
+}
+
+// findAdminPhoto returns the photo submission of the id path param. On
+// failure it returns a nil submission and the error response.
+func findAdminPhoto(c echo.Context, photos db.PhotoStore) (*db.PhotoSubmission, error) {
+	s, err := photos.FindPhotoSubmission(c.Param("id"))
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		return nil, c.String(http.StatusNotFound, "Foto não encontrada.")
+	case err != nil:
+		log.Printf("failed to find photo submission (%s), error %v\n", c.Param("id"), err)
+		return nil, c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+	}
+	return s, nil
+}
+
+// POST /admin/moderacao/fotos/:id/aprovar replaces the photo of the
+// candidature with a pending one.
+func newAdminApprovePhotoHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s, err := findAdminPhoto(c, dbClient)
+		if s == nil {
+			return err
+		}
+		err = approvePhoto(dbClient, s, authenticatedOperator(c))
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusConflict, "Foto já moderada.")
+		case err != nil:
+			log.Printf("failed to approve photo submission (%s), error %v\n", s.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, "/admin/moderacao")
+	}
+}
+
+// POST /admin/moderacao/fotos/:id/rejeitar rejects a pending photo, the
+// reason (motivo form field) is required and emailed to the candidate.
+func newAdminRejectPhotoHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s, err := findAdminPhoto(c, dbClient)
+		if s == nil {
+			return err
+		}
+		reason := strings.TrimSpace(c.FormValue("motivo"))
+		if reason == "" {
+			return c.String(http.StatusBadRequest, "O motivo é obrigatório.")
+		}
+		err = rejectPhoto(dbClient, s, authenticatedOperator(c), reason)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusConflict, "Foto já moderada.")
+		case err != nil:
+			log.Printf("failed to reject photo submission (%s), error %v\n", s.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, "/admin/moderacao")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minOperatorPasswordSize = 12
+
+// GET /admin/operadores lists the operators.
+func newAdminOperatorsHandler(operators db.OperatorStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		list, err := operators.ListOperators()
+		if err != nil {
+			log.Printf("failed to list operators, error %v\n", err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-operadores.html", map[string]interface{}{
+			"Operators": list,
+			"Roles":     db.Roles,
+			"Operator":  authenticatedOperator(c),
+		})
+	}
+}
+
+// POST /admin/operadores creates an operator. The login (email form field)
+// is the email of the operator.
+func newAdminCreateOperatorHandler(operators db.OperatorStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := strings.ToLower(strings.TrimSpace(c.FormValue("email")))
+		name := strings.TrimSpace(c.FormValue("nome"))
+		role := c.FormValue("papel")
+		password := c.FormValue("senha")
+		if !emailRegex.MatchString(id) || name == "" {
+			return c.String(http.StatusBadRequest, "Email e nome são campos obrigatórios.")
+		}
+		if !db.ValidRole(role) {
+			return c.String(http.StatusBadRequest, "papel inválido")
+		}
+		if len(password) < minOperatorPasswordSize {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("A senha precisa ter ao menos %d caracteres.", minOperatorPasswordSize))
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Printf("failed to hash operator password, error %v\n", err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		err = operators.CreateOperator(&db.Operator{
+			ID:           id,
+			Name:         name,
+			Role:         role,
+			PasswordHash: string(hash),
+			CreatedAt:    time.Now().UTC(),
+			CreatedBy:    authenticatedOperator(c).ID,
+		})
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.Conflict:
+			return c.String(http.StatusConflict, "Já existe um operador com este email.")
+		case err != nil:
+			log.Printf("failed to create operator (%s), error %v\n", id, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		log.Printf("operator %s created by %s with role %s\n", id, authenticatedOperator(c).ID, role)
+		return c.Redirect(http.StatusSeeOther, "/admin/operadores")
+	}
+}
+
+// POST /admin/operadores/:id/desativar disables an operator.
+func newAdminDisableOperatorHandler(operators db.OperatorStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := operators.DisableOperator(c.Param("id"), time.Now().UTC())
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return c.String(http.StatusNotFound, "Operador não encontrado.")
+		case err != nil:
+			log.Printf("failed to disable operator (%s), error %v\n", c.Param("id"), err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		log.Printf("operator %s disabled by %s\n", c.Param("id"), authenticatedOperator(c).ID)
+		return c.Redirect(http.StatusSeeOther, "/admin/operadores")
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/token"
+	"github.com/labstack/echo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testOperatorPassword = "operator password"
+
+func adminRequest(t *testing.T, e *echo.Echo, method, target, user string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	password := testOperatorPassword
+	if user == testAdminUser {
+		password = testAdminPassword
+	}
+	req.SetBasicAuth(user, password)
+	req.Header.Set("Origin", "http://"+req.Host)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func createTestOperator(t *testing.T, store db.OperatorStore, id, role string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testOperatorPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := store.CreateOperator(&db.Operator{ID: id, Name: id, Role: role, PasswordHash: string(hash)}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	e, store := newTestServer(t)
+	createTestOperator(t, store, "leitor@exemplo.com", db.RoleViewer)
+	createTestOperator(t, store, "editor@exemplo.com", db.RoleEditor)
+	createTestOperator(t, store, "antigo@exemplo.com", db.RoleAdmin)
+	if err := store.DisableOperator("antigo@exemplo.com", time.Now()); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	resetTerms := "/admin/candidaturas/2020/20000000001/resetar-termo"
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		user   string
+		status int
+	}{
+		{"viewer searches", http.MethodGet, "/admin/candidaturas?q=maria", "leitor@exemplo.com", http.StatusOK},
+		{"viewer can not edit", http.MethodPost, resetTerms, "leitor@exemplo.com", http.StatusForbidden},
+		{"viewer can not manage operators", http.MethodGet, "/admin/operadores", "leitor@exemplo.com", http.StatusForbidden},
+		{"editor edits", http.MethodPost, resetTerms, "editor@exemplo.com", http.StatusSeeOther},
+		{"editor can not manage operators", http.MethodGet, "/admin/operadores", "editor@exemplo.com", http.StatusForbidden},
+		{"admin manages operators", http.MethodGet, "/admin/operadores", testAdminUser, http.StatusOK},
+		{"disabled operator", http.MethodGet, "/admin/candidaturas", "antigo@exemplo.com", http.StatusUnauthorized},
+		{"unknown operator", http.MethodGet, "/admin/candidaturas", "ninguem@exemplo.com", http.StatusUnauthorized},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := adminRequest(t, e, tt.method, tt.target, tt.user, nil); rec.Code != tt.status {
+				t.Errorf("want status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+
+	for _, origin := range []string{"http://evil.example.com", ""} {
+		req := httptest.NewRequest(http.MethodPost, resetTerms, nil)
+		req.SetBasicAuth(testAdminUser, testAdminPassword)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("want status %d for form from origin %q, got %d", http.StatusForbidden, origin, rec.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, resetTerms, nil)
+	req.SetBasicAuth(testAdminUser, testAdminPassword)
+	req.Header.Set("Referer", "http://"+req.Host+"/admin/candidaturas")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("want form with same origin referer accepted, got status %d", rec.Code)
+	}
+
+	form := url.Values{"email": {"Novo@Exemplo.com"}, "nome": {"Novo"}, "papel": {db.RoleEditor}, "senha": {testOperatorPassword}}
+	if rec := adminRequest(t, e, http.MethodPost, "/admin/operadores", testAdminUser, form); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after creating operator, got %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, "/admin/operadores", testAdminUser, form); rec.Code != http.StatusConflict {
+		t.Errorf("want status %d for repeated operator, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodGet, "/admin/candidaturas", "novo@exemplo.com", nil); rec.Code != http.StatusOK {
+		t.Errorf("want new operator to sign in, got status %d", rec.Code)
+	}
+}
+
+func TestAdminCandidatura(t *testing.T) {
+	e, store := newTestServer(t)
+	path := "/admin/candidaturas/2020/20000000001"
+	for _, q := range []string{"maria.jose@exemplo.com", "20000000001", "professora", "12345"} {
+		rec := adminRequest(t, e, http.MethodGet, "/admin/candidaturas?q="+url.QueryEscape(q), testAdminUser, nil)
+		if !strings.Contains(rec.Body.String(), path) {
+			t.Errorf("want candidature found searching for %s", q)
+		}
+	}
+
+	form := url.Values{
+		"biografia": {"Professora e pesquisadora."},
+		"topico":    {"Educação", ""},
+		"proposta":  {"Ampliar o número de creches em tempo integral.", ""},
+		"rede":      {"instagram", "email"},
+		"contato":   {"professoramariajose", ""},
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, form); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after update, got status %d", rec.Code)
+	}
+	candidate, err := store.FindCandidateBySequencialIDAndYear(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if candidate.Biography != "Professora e pesquisadora." || len(candidate.Proposals) != 1 || len(candidate.Contacts) != 1 {
+		t.Errorf("want profile updated, got %+v", candidate)
+	}
+	form.Set("rede", "orkut")
+	if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, form); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for invalid contact, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	if rec := adminRequest(t, e, http.MethodPost, path+"/reenviar-acesso", testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Errorf("want redirect after resending access, got status %d", rec.Code)
+	}
+	if emails, _ := store.ListEmails("", 10); len(emails) != 1 || emails[0].To[0] != "MARIA.JOSE@EXEMPLO.COM" {
+		t.Errorf("want access email queued, got %v", emails)
+	}
+
+	if rec := adminRequest(t, e, http.MethodPost, path+"/desativar", testAdminUser, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d disabling without reason, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path+"/desativar", testAdminUser, url.Values{"motivo": {"conteúdo falso"}}); rec.Code != http.StatusSeeOther {
+		t.Errorf("want redirect after disabling, got status %d", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/c/2020/20000000001", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Professora e pesquisadora.") {
+		t.Errorf("want biography of disabled profile hidden, got status %d", rec.Code)
+	}
+	link, err := newLoginLink(store, tokenService, "maria.jose@exemplo.com", "20000000001", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	u, _ := url.Parse(link)
+	rec = postForm(t, e, "/entrar", url.Values{"token": {u.Query().Get("token")}})
+	rec2 := httptest.NewRecorder()
+	e.ServeHTTP(rec2, httptest.NewRequest(http.MethodGet, rec.Header().Get("Location"), nil))
+	if rec2.Code != http.StatusForbidden {
+		t.Errorf("want status %d for candidate of disabled profile, got %d", http.StatusForbidden, rec2.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path+"/reativar", testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Errorf("want redirect after enabling, got status %d", rec.Code)
+	}
+
+	entries, err := store.ListAuditEntries(2020, "20000000001", 10)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+		if entry.Actor != "operador:"+testAdminUser {
+			t.Errorf("want actor operador:%s, got %s", testAdminUser, entry.Actor)
+		}
+	}
+	want := []string{db.AuditEnableProfile, db.AuditDisableProfile, db.AuditResendAccess, db.AuditUpdateProfile}
+	if strings.Join(actions, " ") != strings.Join(want, " ") {
+		t.Errorf("want audit trail %v, got %v", want, actions)
+	}
+	changes := entries[3].Changes
+	if len(changes) != 2 || changes[0].Field != "biografia" || changes[0].Before != "Professora da rede pública há 15 anos." {
+		t.Errorf("want biography and proposals changes audited, got %+v", changes)
+	}
+	if rec := adminRequest(t, e, http.MethodGet, path, testAdminUser, nil); !strings.Contains(rec.Body.String(), "Professora da rede pública há 15 anos.") {
+		t.Errorf("want audit trail in the candidature page")
+	}
+}
+
+func TestAdminRevokeSessions(t *testing.T) {
+	e, store := newTestServer(t)
+	path := "/admin/candidaturas/2020/20000000001"
+	login := func() string {
+		link, err := newLoginLink(store, tokenService, "maria.jose@exemplo.com", "20000000001", "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		u, _ := url.Parse(link)
+		return u.Query().Get("token")
+	}
+	rec := postForm(t, e, "/entrar", url.Values{"token": {login()}})
+	location := rec.Header().Get("Location")
+	unused := login()
+
+	createTestOperator(t, store, "leitor@exemplo.com", db.RoleViewer)
+	if rec := adminRequest(t, e, http.MethodPost, path+"/revogar-sessoes", "leitor@exemplo.com", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d for viewer, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path+"/revogar-sessoes", testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after revoking sessions, got status %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, location, nil))
+	if !strings.Contains(rec.Body.String(), "Código de acesso inválido") {
+		t.Errorf("want revoked session token to be rejected")
+	}
+	if rec := postForm(t, e, "/entrar", url.Values{"token": {unused}}); rec.Code == http.StatusSeeOther {
+		t.Errorf("want unused login link revoked")
+	}
+	if entries, _ := store.ListAuditEntries(2020, "20000000001", 10); len(entries) != 1 || entries[0].Action != db.AuditRevokeSessions {
+		t.Errorf("want sessions revoked audited, got %v", entries)
+	}
+
+	// Disabling the profile also revokes the links sent before.
+	unused = login()
+	if rec := adminRequest(t, e, http.MethodPost, path+"/desativar", testAdminUser, url.Values{"motivo": {"conteúdo falso"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after disabling, got status %d", rec.Code)
+	}
+	if rec := postForm(t, e, "/entrar", url.Values{"token": {unused}}); rec.Code == http.StatusSeeOther {
+		t.Errorf("want login link revoked when disabling the profile")
+	}
+
+	// Other candidatures of the same email stay signed in.
+	link, err := newLoginLink(store, tokenService, "comite@exemplo.com", "20000000003", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	u, _ := url.Parse(link)
+	other := postForm(t, e, "/entrar", url.Values{"token": {u.Query().Get("token")}}).Header().Get("Location")
+	if rec := adminRequest(t, e, http.MethodPost, "/admin/candidaturas/2020/20000000002/revogar-sessoes", testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after revoking sessions, got status %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, other, nil))
+	if strings.Contains(rec.Body.String(), "Código de acesso inválido") {
+		t.Errorf("want session of another candidature of the email kept")
+	}
+}
+
+func TestProfileRevisions(t *testing.T) {
+	e, store := newTestServer(t)
+	path := "/admin/candidaturas/2020/20000000001"
+	update := func(bio string) {
+		form := url.Values{"biografia": {bio}, "topico": {"Educação"}, "proposta": {"Creches."}, "rede": {"instagram"}, "contato": {"professoramariajose"}}
+		if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, form); rec.Code != http.StatusSeeOther {
+			t.Fatalf("want redirect after update, got status %d", rec.Code)
+		}
+	}
+	update("Primeira mudança.")
+	update("Segunda mudança.")
+	revisions, err := store.ListProfileRevisions(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(revisions) != 3 || revisions[2].Actor != importActor || revisions[2].Biography != "Professora da rede pública há 15 anos." {
+		t.Fatalf("want original profile and 2 revisions, got %v", revisions)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/c/2020/20000000001/historico", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "Primeira mudança.") || !strings.Contains(body, "Segunda mudança.") {
+		t.Errorf("want changes in the public history, got status %d", rec.Code)
+	}
+	if strings.Contains(body, testAdminUser) {
+		t.Errorf("want operators not identified in the public history")
+	}
+
+	createTestOperator(t, store, "editor@exemplo.com", db.RoleEditor)
+	restore := path + "/revisoes/1/restaurar"
+	if rec := adminRequest(t, e, http.MethodPost, restore, "editor@exemplo.com", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d for editor rolling back, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, restore, testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after rolling back, got status %d", rec.Code)
+	}
+	candidate, err := store.FindCandidateBySequencialIDAndYear(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if candidate.Biography != "Professora da rede pública há 15 anos." || len(candidate.Proposals) != 2 {
+		t.Errorf("want original profile restored, got %+v", candidate)
+	}
+	if r, _ := store.FindProfileRevision(2020, "20000000001", 4); r == nil || r.RestoredFrom != 1 {
+		t.Errorf("want rollback stored as revision 4, got %+v", r)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path+"/revisoes/9/restaurar", testAdminUser, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("want status %d for unknown revision, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestProfileRevisionsRecordCandidateSession(t *testing.T) {
+	e, store := newTestServer(t)
+	link, err := newLoginLink(store, tokenService, "comite@exemplo.com", "20000000002", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	u, _ := url.Parse(link)
+	rec := postForm(t, e, "/entrar", url.Values{"token": {u.Query().Get("token")}})
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	accessToken := location.Query().Get("access_token")
+	if rec := postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after accepting terms, got status %d", rec.Code)
+	}
+	claims, err := parseToken(tokenService, accessToken, token.SessionKind)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	revisions, err := store.ListProfileRevisions(2020, "20000000002")
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("want original profile and 1 revision, got %v (error %v)", revisions, err)
+	}
+	if revisions[0].Actor != "candidato:comite@exemplo.com" || revisions[0].SessionID != claims["jti"] || revisions[0].AcceptedTerms.IsZero() {
+		t.Errorf("want revision made by the candidate session %s, got %+v", claims["jti"], revisions[0])
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+const adminTicketsPageSize = 100
+
+// Paths of the tickets of each kind in the back office.
+var adminTicketPaths = map[string]string{
+	db.TicketProfileReport: "/admin/denuncias",
+	db.TicketContact:       "/admin/fale-conosco",
+}
+
+// GET /admin/denuncias and /admin/fale-conosco list the tickets of the kind
+// with the status query param, open ones by default.
+func newAdminTicketsHandler(tickets db.TicketStore, kind, templateName string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status := c.QueryParam("status")
+		if status == "" {
+			status = db.TicketOpen
+		}
+		if !db.ValidTicketStatus(status) {
+			return c.String(http.StatusBadRequest, "status inválido")
+		}
+		list, err := tickets.ListTickets(kind, status, adminTicketsPageSize)
+		if err != nil {
+			log.Printf("failed to list tickets (%s), error %v\n", kind, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, templateName, map[string]interface{}{
+			"Tickets":    list,
+			"Status":     status,
+			"Statuses":   db.TicketStatuses,
+			"Categories": reportCategoriesUI,
+			"Operator":   authenticatedOperator(c),
+		})
+	}
+}
+
+// findAdminTicket returns the ticket of the kind with the id path param. On
+// failure it returns a nil ticket and the error response.
+func findAdminTicket(c echo.Context, tickets db.TicketStore, kind string) (*db.Ticket, error) {
+	t, err := tickets.FindTicket(c.Param("id"))
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		t = nil
+	case err != nil:
+		log.Printf("failed to find ticket (%s), error %v\n", c.Param("id"), err)
+		return nil, c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+	}
+	if t == nil || t.Kind != kind {
+		return nil, c.String(http.StatusNotFound, "Ticket não encontrado.")
+	}
+	return t, nil
+}
+
+// adminTicketData returns the data shared by the pages of tickets: the
+// ticket, its candidature and who it can be assigned to.
+func adminTicketData(c echo.Context, dbClient db.Store, t *db.Ticket) (map[string]interface{}, error) {
+	candidate, err := dbClient.FindCandidateBySequencialIDAndYear(t.Year, t.SequentialID)
+	if err != nil {
+		return nil, err
+	}
+	operator := authenticatedOperator(c)
+	assignees, err := ticketAssignees(dbClient, operator, t)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Ticket":     t,
+		"TicketPath": adminTicketPaths[t.Kind] + "/" + t.ID,
+		"Candidate":  candidate,
+		"Categories": reportCategoriesUI,
+		"Statuses":   db.TicketStatuses,
+		"Assignees":  assignees,
+		"CanEdit":    operator.Can(db.RoleEditor),
+		"Operator":   operator,
+	}, nil
+}
+
+// ticketAssignees returns the IDs of the operators who can handle tickets:
+// the enabled editors and admins, the current operator and the current
+// assignee of the ticket.
+func ticketAssignees(operators db.OperatorStore, current *db.Operator, t *db.Ticket) ([]string, error) {
+	list, err := operators.ListOperators()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	add(current.ID)
+	add(t.Assignee)
+	for _, o := range list {
+		if o.Can(db.RoleEditor) {
+			add(o.ID)
+		}
+	}
+	return ids, nil
+}
+
+// GET /admin/denuncias/:id shows the reports of the ticket and its history.
+func newAdminDenunciaHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, err := findAdminTicket(c, dbClient, db.TicketProfileReport)
+		if t == nil {
+			return err
+		}
+		data, err := adminTicketData(c, dbClient, t)
+		if err != nil {
+			log.Printf("failed to load ticket (%s), error %v\n", t.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-denuncia.html", data)
+	}
+}
+
+// GET /admin/fale-conosco/:id shows the conversation with the candidate.
+func newAdminFaleConoscoTicketHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, err := findAdminTicket(c, dbClient, db.TicketContact)
+		if t == nil {
+			return err
+		}
+		data, err := adminTicketData(c, dbClient, t)
+		if err != nil {
+			log.Printf("failed to load ticket (%s), error %v\n", t.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Render(http.StatusOK, "admin-fale-conosco-ticket.html", data)
+	}
+}
+
+// POST /admin/denuncias/:id and /admin/fale-conosco/:id change the status
+// and the assignee (responsavel form field, empty to leave the ticket
+// unassigned) of the ticket, with an optional note (nota form field).
+func newAdminUpdateTicketHandler(dbClient db.Store, kind string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, err := findAdminTicket(c, dbClient, kind)
+		if t == nil {
+			return err
+		}
+		status := c.FormValue("status")
+		if !db.ValidTicketStatus(status) {
+			return c.String(http.StatusBadRequest, "status inválido")
+		}
+		operator := authenticatedOperator(c)
+		assignee := c.FormValue("responsavel")
+		if assignee != "" {
+			assignees, err := ticketAssignees(dbClient, operator, t)
+			if err != nil {
+				log.Printf("failed to list ticket assignees, error %v\n", err)
+				return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+			}
+			valid := false
+			for _, id := range assignees {
+				valid = valid || id == assignee
+			}
+			if !valid {
+				return c.String(http.StatusBadRequest, "responsável inválido")
+			}
+		}
+		event := &db.TicketEvent{
+			CreatedAt: time.Now().UTC(),
+			Actor:     operatorActor(operator),
+			Status:    status,
+			Note:      strings.TrimSpace(c.FormValue("nota")),
+		}
+		err = dbClient.UpdateTicketStatus(t.ID, assignee, event)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.Conflict:
+			return c.String(http.StatusConflict, "Já existe outro ticket aberto desta categoria para a candidatura.")
+		case err != nil:
+			log.Printf("failed to update ticket (%s), error %v\n", t.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		return c.Redirect(http.StatusSeeOther, adminTicketPaths[kind]+"/"+t.ID)
+	}
+}
+
+// POST /admin/fale-conosco/:id/responder adds the reply of the team to the
+// conversation and emails it to the candidate.
+func newAdminReplyTicketHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, err := findAdminTicket(c, dbClient, db.TicketContact)
+		if t == nil {
+			return err
+		}
+		text := strings.TrimSpace(c.FormValue("mensagem"))
+		if text == "" {
+			return c.String(http.StatusBadRequest, "A mensagem é obrigatória.")
+		}
+		candidate, err := dbClient.FindCandidateBySequencialIDAndYear(t.Year, t.SequentialID)
+		if err != nil {
+			log.Printf("failed to find candidate of ticket (%s), error %v\n", t.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		message, err := buildFaleConoscoReplyEmail(candidate, t.Subject, text)
+		if err != nil {
+			log.Printf("failed to build reply email (%s), error %v\n", t.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		message.From = emailFrom
+		message.To = []string{candidate.Email}
+		reply := &db.TicketMessage{
+			CreatedAt: time.Now().UTC(),
+			Author:    operatorActor(authenticatedOperator(c)),
+			FromTeam:  true,
+			Text:      text,
+		}
+		if err := dbClient.AddTicketMessage(t.ID, reply); err != nil {
+			log.Printf("failed to add reply to ticket (%s), error %v\n", t.ID, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		// The reply is already in the conversation, failing here would make
+		// the operator send it again.
+		key := fmt.Sprintf("resposta:%s:%d", t.ID, reply.CreatedAt.UnixNano())
+		if _, err := enqueueEmail(dbClient, key, message); err != nil {
+			log.Printf("failed on queueing reply email (%s), error %v\n", t.ID, err)
+		}
+		return c.Redirect(http.StatusSeeOther, adminTicketPaths[db.TicketContact]+"/"+t.ID)
+	}
+}
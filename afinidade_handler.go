@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/labstack/echo"
+)
+
+// The affinity quiz ranks the transparent candidatures of a city and role by
+// the causes the voter cares about. Answers are kept in the query string,
+// each cause as causa=<peso>:<pauta>, so results can be shared by URL. Gender
+// and party preferences count as causes of the lowest weight.
+
+const (
+	maxAffinityResults = 30 // candidatures shown.
+	affinityCauseParam = "causa"
+)
+
+var (
+	causeWeightsUI = map[int]string{
+		1: "Pouco importante",
+		2: "Importante",
+		3: "Muito importante",
+	}
+	gendersUI = map[string]string{
+		"FEMININO":  "Mulheres",
+		"MASCULINO": "Homens",
+	}
+)
+
+type affinityCause struct {
+	Topic  string
+	Weight int
+}
+
+type affinityQuery struct {
+	Year   int
+	State  string
+	City   string
+	Role   string
+	Gender string
+	Party  string
+	Causes []*affinityCause
+}
+
+// complete reports whether the query has what is needed to rank
+// candidatures.
+func (q *affinityQuery) complete() bool {
+	return q.State != "" && q.City != "" && q.Role != "" && len(q.Causes) > 0
+}
+
+// weight returns the weight given to the topic, zero if not chosen.
+func (q *affinityQuery) weight(topic string) int {
+	for _, cause := range q.Causes {
+		if cause.Topic == topic {
+			return cause.Weight
+		}
+	}
+	return 0
+}
+
+// affinityMatch is a reason why a candidature matches the voter.
+type affinityMatch struct {
+	Label       string
+	Weight      string
+	Description string
+}
+
+// affinityCauseRow is a cause of the quiz form, with one option for each
+// weight.
+type affinityCauseRow struct {
+	Topic   string
+	Options []*affinityOption
+}
+
+type affinityOption struct {
+	Value    string
+	Label    string
+	Selected bool
+}
+
+type affinityResult struct {
+	Card    *candidateCard
+	Score   int // percent of the weights matched.
+	Matches []*affinityMatch
+}
+
+// parseAffinityQuery returns the answers of the quiz. Unknown causes and
+// weights are ignored, so old links keep working as tags change.
+func parseAffinityQuery(c echo.Context) (*affinityQuery, error) {
+	q := &affinityQuery{
+		Year:   globals.Year,
+		State:  strings.ToUpper(c.QueryParam("estado")),
+		City:   c.QueryParam("cidade"),
+		Role:   c.QueryParam("cargo"),
+		Gender: c.QueryParam("genero"),
+		Party:  strings.TrimSpace(c.QueryParam("partido")),
+	}
+	if v := c.QueryParam("ano"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		q.Year = year
+	}
+	if _, ok := uiRoles[q.Role]; !ok {
+		q.Role = ""
+	}
+	if _, ok := gendersUI[q.Gender]; !ok {
+		q.Gender = ""
+	}
+	valid := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		valid[t] = true
+	}
+	for _, v := range c.QueryParams()[affinityCauseParam] {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		weight, err := strconv.Atoi(parts[0])
+		if _, ok := causeWeightsUI[weight]; err != nil || !ok || !valid[parts[1]] || q.weight(parts[1]) > 0 {
+			continue
+		}
+		q.Causes = append(q.Causes, &affinityCause{Topic: parts[1], Weight: weight})
+	}
+	return q, nil
+}
+
+// rankCandidates scores the candidatures by the weights of the causes of
+// their proposals and of the preferences they match, best first.
+func rankCandidates(candidates []*descritor.CandidateForDB, q *affinityQuery) []*affinityResult {
+	total := 0
+	for _, cause := range q.Causes {
+		total += cause.Weight
+	}
+	if q.Gender != "" {
+		total++
+	}
+	if q.Party != "" {
+		total++
+	}
+	var results []*affinityResult
+	for _, candidate := range candidates {
+		r := &affinityResult{Card: newCandidateCard(candidate)}
+		score := 0
+		seen := make(map[string]bool)
+		for _, p := range candidate.Proposals {
+			w := q.weight(p.Topic)
+			if w == 0 || seen[p.Topic] {
+				continue
+			}
+			seen[p.Topic] = true
+			score += w
+			r.Matches = append(r.Matches, &affinityMatch{Label: p.Topic, Weight: causeWeightsUI[w], Description: p.Description})
+		}
+		if score == 0 {
+			continue
+		}
+		if q.Gender != "" && candidate.Gender == q.Gender {
+			score++
+			r.Matches = append(r.Matches, &affinityMatch{Label: "Gênero preferido", Weight: causeWeightsUI[1], Description: gendersUI[q.Gender]})
+		}
+		if q.Party != "" && strings.EqualFold(candidate.Party, q.Party) {
+			score++
+			r.Matches = append(r.Matches, &affinityMatch{Label: "Partido preferido", Weight: causeWeightsUI[1], Description: candidate.Party})
+		}
+		r.Score = score * 100 / total
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Card.Name < results[j].Card.Name
+	})
+	return results
+}
+
+// findAffinityResults ranks every transparent candidature of the city and role
+// with proposals in any of the causes. Only the best ones are then fetched in
+// full, to be shown.
+func findAffinityResults(dbClient db.CandidateStore, q *affinityQuery) ([]*affinityResult, error) {
+	var topics []string
+	for _, cause := range q.Causes {
+		topics = append(topics, cause.Topic)
+	}
+	candidates, err := dbClient.FindCandidaturesForRanking(map[string]interface{}{
+		"year":  q.Year,
+		"state": q.State,
+		"city":  q.City,
+		"role":  q.Role,
+		"tags":  topics,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ranked := rankCandidates(candidates, q)
+	if len(ranked) > maxAffinityResults {
+		ranked = ranked[:maxAffinityResults]
+	}
+	best := make([]*descritor.CandidateForDB, len(ranked))
+	for i, r := range ranked {
+		if best[i], err = dbClient.FindCandidateBySequencialIDAndYear(q.Year, r.Card.SequentialID); err != nil {
+			return nil, err
+		}
+	}
+	return rankCandidates(best, q), nil
+}
+
+// GET /afinidade shows the quiz and, once answered, the candidatures that
+// best match the voter.
+func newAfinidadeHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		q, err := parseAffinityQuery(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Ano fornecido é inválido.")
+		}
+		var cities []string
+		if q.State != "" {
+			if cities, err = dbClient.GetCities(q.State); err != nil {
+				log.Printf("failed to find cities (%s), error %v\n", q.State, err)
+				return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+			}
+		}
+		var results []*affinityResult
+		if q.complete() {
+			if results, err = findAffinityResults(dbClient, q); err != nil {
+				log.Printf("failed to find candidatures for the quiz (%s/%s), error %v\n", q.City, q.State, err)
+				return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+			}
+		}
+		var causes []*affinityCauseRow
+		for _, t := range tags {
+			row := &affinityCauseRow{Topic: t}
+			for w := 1; w <= len(causeWeightsUI); w++ {
+				row.Options = append(row.Options, &affinityOption{Value: affinityCauseValue(w, t), Label: causeWeightsUI[w], Selected: q.weight(t) == w})
+			}
+			causes = append(causes, row)
+		}
+		return c.Render(http.StatusOK, "afinidade.html", map[string]interface{}{
+			"Query":         q,
+			"Complete":      q.complete(),
+			"Results":       results,
+			"AllStates":     uiStates,
+			"AllRoles":      uiRoles,
+			"Genders":       gendersUI,
+			"CitiesOfState": cities,
+			"Causes":        causes,
+			"ShareURL":      siteURL + affinityURL(q),
+		})
+	}
+}
+
+// affinityCauseValue returns the value of the cause in the quiz URL.
+func affinityCauseValue(weight int, topic string) string {
+	return fmt.Sprintf("%d:%s", weight, topic)
+}
+
+// affinityURL returns the stateless URL of the quiz answers.
+func affinityURL(q *affinityQuery) string {
+	v := url.Values{}
+	v.Set("ano", strconv.Itoa(q.Year))
+	v.Set("estado", q.State)
+	v.Set("cidade", q.City)
+	v.Set("cargo", q.Role)
+	for _, cause := range q.Causes {
+		v.Add(affinityCauseParam, affinityCauseValue(cause.Weight, cause.Topic))
+	}
+	if q.Gender != "" {
+		v.Set("genero", q.Gender)
+	}
+	if q.Party != "" {
+		v.Set("partido", q.Party)
+	}
+	return "/afinidade?" + v.Encode()
+}
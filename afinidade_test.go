@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+)
+
+func TestRankCandidates(t *testing.T) {
+	candidates := []*descritor.CandidateForDB{
+		{SequencialCandidate: "1", BallotName: "A", Gender: "MASCULINO", Party: "PARTIDO A", Proposals: []*descritor.Proposal{{Topic: "Saúde", Description: "postos"}}},
+		{SequencialCandidate: "2", BallotName: "B", Gender: "FEMININO", Party: "PARTIDO B", Proposals: []*descritor.Proposal{{Topic: "Educação", Description: "creches"}, {Topic: "Saúde", Description: "médicos"}}},
+		{SequencialCandidate: "3", BallotName: "C", Gender: "FEMININO", Proposals: []*descritor.Proposal{{Topic: "Cultura", Description: "teatros"}}},
+	}
+	q := &affinityQuery{
+		Gender: "FEMININO",
+		Causes: []*affinityCause{{Topic: "Educação", Weight: 3}, {Topic: "Saúde", Weight: 1}},
+	}
+	results := rankCandidates(candidates, q)
+	if len(results) != 2 {
+		t.Fatalf("want 2 results, got %d", len(results))
+	}
+	for i, want := range []struct {
+		id      string
+		score   int
+		matches int
+	}{{"2", 100, 3}, {"1", 20, 1}} {
+		if got := results[i]; got.Card.SequentialID != want.id || got.Score != want.score || len(got.Matches) != want.matches {
+			t.Errorf("want result %d to be %s with score %d and %d matches, got %s with score %d and %d matches", i, want.id, want.score, want.matches, got.Card.SequentialID, got.Score, len(got.Matches))
+		}
+	}
+}
+
+func TestAfinidade(t *testing.T) {
+	e, store := newTestServer(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/afinidade")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Direitos das Mulheres") {
+		t.Errorf("want quiz with the causes, got status %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "de afinidade") {
+		t.Errorf("want no results before the quiz is answered")
+	}
+
+	path := "/afinidade?estado=AL&cidade=MACEI%C3%93&cargo=vereador&causa=3%3AEduca%C3%A7%C3%A3o&causa=9%3ASa%C3%BAde&causa=2%3Ainv%C3%A1lida"
+	rec = get(path)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !strings.Contains(body, "PROFESSORA MARIA JOSÉ") || !strings.Contains(body, "100% de afinidade") {
+		t.Errorf("want candidature with proposals for the cause ranked, got %q", body)
+	}
+	for _, unwanted := range []string{"JOÃO DO POSTO", "DR. ANTÔNIO", "PEDRO DA BICICLETA"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("want %s not ranked", unwanted)
+		}
+	}
+	if !strings.Contains(body, "causa=3%3AEduca%C3%A7%C3%A3o") || strings.Contains(body, "inv%C3%A1lida") {
+		t.Errorf("want share URL with the valid causes only")
+	}
+
+	if err := store.DisableProfile(&db.DisabledProfile{ID: db.DisabledProfileID(2020, "20000000001"), Year: 2020, SequentialID: "20000000001", DisabledAt: time.Now()}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if body := get(path).Body.String(); strings.Contains(body, "PROFESSORA MARIA JOSÉ") {
+		t.Errorf("want disabled profile not ranked")
+	}
+
+	if rec := get("/afinidade?ano=dois"); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for invalid year, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
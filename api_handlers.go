@@ -0,0 +1,171 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/search"
+	"github.com/labstack/echo"
+)
+
+// JSON API consumed by mobile and bot clients. Responses use the same
+// candidateCard shape used by the HTML pages and errors are always
+// serialized as exception.Exception.
+
+type apiSearchResponse struct {
+	Seed                     int64            `json:"seed"`
+	TransparentCandidates    []*candidateCard `json:"transparent_candidates"`
+	TransparentPagination    *pagination      `json:"transparent_pagination"`
+	NonTransparentCandidates []*candidateCard `json:"non_transparent_candidates"`
+	NonTransparentPagination *pagination      `json:"non_transparent_pagination"`
+}
+
+type apiCandidateResponse struct {
+	*candidateCard
+	Year      int                   `json:"year"`
+	Biography string                `json:"biography"`
+	Proposals []*descritor.Proposal `json:"proposals"`
+	Contacts  []*descritor.Contact  `json:"contacts"`
+}
+
+type apiComparisonResponse struct {
+	Year       int                     `json:"year"`
+	Candidates []*apiCandidateResponse `json:"candidates"`
+	Topics     []*comparedTopic        `json:"topics"`
+}
+
+type apiStatesResponse struct {
+	States []string `json:"states"`
+}
+
+type apiCitiesResponse struct {
+	State  string   `json:"state"`
+	Cities []string `json:"cities"`
+}
+
+func registerAPIRoutes(g *echo.Group, dbClient db.Store, suggestions *search.PrefixIndex) {
+	g.GET("/candidatos", newAPISearchHandler(dbClient))
+	g.GET("/sugestoes", newAPISuggestionsHandler(suggestions))
+	g.GET("/c/:year/:id", newAPICandidateHandler(dbClient))
+	g.GET("/comparar", newAPIComparisonHandler(dbClient))
+	g.GET("/estados", newAPIStatesHandler(dbClient))
+	g.GET("/estados/:estado/cidades", newAPICitiesHandler(dbClient))
+}
+
+// apiError writes err as a JSON body. Errors which are not an
+// exception.Exception are logged and hidden behind a generic message.
+func apiError(c echo.Context, err error) error {
+	e, ok := err.(*exception.Exception)
+	if !ok {
+		log.Printf("unexpected error on api request (%s):%q\n", c.Request().URL, err)
+		e = &exception.Exception{Code: exception.Unknown, Message: "Erro inesperado. Por favor, tente novamente mais tarde."}
+	}
+	return c.JSON(e.Code, e)
+}
+
+func newAPISearchHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		resultSet, err := filterCandidates(c, dbClient)
+		if err != nil {
+			return apiError(c, err)
+		}
+		resp := apiSearchResponse{
+			Seed:                     resultSet.seed,
+			TransparentCandidates:    resultSet.transparentCandidatures,
+			TransparentPagination:    resultSet.transparentPagination,
+			NonTransparentCandidates: resultSet.nonTransparentCandidatures,
+			NonTransparentPagination: resultSet.nonTransparentPagination,
+		}
+		// Stable output: clients should always get arrays.
+		if resp.TransparentCandidates == nil {
+			resp.TransparentCandidates = []*candidateCard{}
+		}
+		if resp.NonTransparentCandidates == nil {
+			resp.NonTransparentCandidates = []*candidateCard{}
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+func newAPICandidateHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		year, err := strconv.Atoi(c.Param("year"))
+		if err != nil {
+			return apiError(c, exception.New(exception.InvalidParameters, "Ano fornecido é inválido.", nil))
+		}
+		candidate, err := findPublicCandidate(dbClient, year, c.Param("id"))
+		if err != nil {
+			if e, ok := err.(*exception.Exception); ok && e.Code == exception.NotFound {
+				return apiError(c, exception.New(exception.NotFound, "Candidatura não encontrada.", nil))
+			}
+			return apiError(c, err)
+		}
+		return c.JSON(http.StatusOK, newAPICandidateResponse(candidate))
+	}
+}
+
+func newAPICandidateResponse(candidate *descritor.CandidateForDB) *apiCandidateResponse {
+	return &apiCandidateResponse{
+		candidateCard: newCandidateCard(candidate),
+		Year:          candidate.Year,
+		Biography:     candidate.Biography,
+		Proposals:     candidate.Proposals,
+		Contacts:      candidate.Contacts,
+	}
+}
+
+// newAPIComparisonHandler returns the candidatures of /comparar, with the
+// proposals of each topic in the order of the candidatures.
+func newAPIComparisonHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cmp, err := queryComparison(c, dbClient)
+		if err != nil {
+			return apiError(c, err)
+		}
+		resp := apiComparisonResponse{Year: cmp.Year, Candidates: []*apiCandidateResponse{}, Topics: cmp.Topics}
+		for _, candidate := range cmp.Candidates {
+			resp.Candidates = append(resp.Candidates, newAPICandidateResponse(candidate))
+		}
+		// Stable output: clients should always get arrays.
+		if resp.Topics == nil {
+			resp.Topics = []*comparedTopic{}
+		}
+		for _, t := range resp.Topics {
+			for i, p := range t.Proposals {
+				if p == nil {
+					t.Proposals[i] = []string{}
+				}
+			}
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+func newAPIStatesHandler(dbClient db.CandidateStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		states, err := dbClient.GetStates()
+		if err != nil {
+			return apiError(c, err)
+		}
+		return c.JSON(http.StatusOK, apiStatesResponse{States: states})
+	}
+}
+
+func newAPICitiesHandler(dbClient db.CandidateStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := strings.ToUpper(c.Param("estado"))
+		cities, err := dbClient.GetCities(state)
+		if err != nil {
+			if e, ok := err.(*exception.Exception); ok && e.Code == exception.NotFound {
+				return apiError(c, exception.New(exception.NotFound, "Estado não encontrado.", nil))
+			}
+			return apiError(c, err)
+		}
+		return c.JSON(http.StatusOK, apiCitiesResponse{State: state, Cities: cities})
+	}
+}
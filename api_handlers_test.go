@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestAPISearch(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/candidatos?ano=2020&estado=al&tags=Sa%C3%BAde", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var resp apiSearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(resp.TransparentCandidates) != 1 || resp.TransparentCandidates[0].SequentialID != "20000000003" {
+		t.Errorf("want only candidate 20000000003, got %+v", resp.TransparentCandidates)
+	}
+	if resp.NonTransparentCandidates == nil {
+		t.Errorf("want empty non transparent list, got null")
+	}
+	if resp.TransparentPagination.Total != 1 || resp.TransparentPagination.NextURL != "" {
+		t.Errorf("want a single page with one candidature, got %+v", resp.TransparentPagination)
+	}
+}
+
+func TestAPISearchLeavesOutDisabledProfiles(t *testing.T) {
+	e, store := newTestServer(t)
+	if err := store.DisableProfile(&db.DisabledProfile{Year: 2020, SequentialID: "20000000003", Reason: "spam"}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/candidatos?ano=2020&estado=AL&cidade=MACEI%C3%93", nil))
+	var resp apiSearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for _, card := range append(resp.TransparentCandidates, resp.NonTransparentCandidates...) {
+		if card.SequentialID == "20000000003" {
+			t.Errorf("want disabled profile left out, got %+v", card)
+		}
+	}
+	if resp.TransparentPagination.Total != 1 {
+		t.Errorf("want disabled profile left out of the total, got %+v", resp.TransparentPagination)
+	}
+}
+
+func TestAPISearchPagination(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/candidatos?ano=2020&estado=AL&semente=7", nil))
+	var resp apiSearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if resp.Seed != 7 {
+		t.Errorf("want seed from the query string, got %d", resp.Seed)
+	}
+	p := resp.TransparentPagination
+	if p.Page != 1 || p.Total != 2 || p.TotalPages != 1 {
+		t.Errorf("want first and only page with 2 candidatures, got %+v", p)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/candidatos?ano=2020&estado=AL", nil))
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != searchSeedCookie {
+		t.Errorf("want the search seed to be stored in a session cookie, got %v", cookies)
+	}
+}
+
+func TestAPICandidate(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/c/2020/20000000001", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if resp["sequential_id"] != "20000000001" || resp["city"] != "Maceió" {
+		t.Errorf("want candidate card fields at the top level, got %v", resp)
+	}
+	if _, ok := resp["proposals"]; !ok {
+		t.Errorf("want proposals, got %v", resp)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	e, _ := newTestServer(t)
+	testCases := []struct {
+		target string
+		code   int
+	}{
+		{"/api/v1/c/2020/1", exception.NotFound},
+		{"/api/v1/c/abc/1", exception.InvalidParameters},
+		{"/api/v1/candidatos?ano=abc", exception.InvalidParameters},
+		{"/api/v1/estados/XX/cidades", exception.NotFound},
+	}
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		var resp exception.Exception
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: want error nil, got %q", tc.target, err)
+		}
+		if rec.Code != tc.code || resp.Code != tc.code || resp.Message == "" {
+			t.Errorf("%s: want status and code %d, got %d and %+v", tc.target, tc.code, rec.Code, resp)
+		}
+	}
+}
+
+func TestAPIStatesAndCities(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/estados", nil))
+	var states apiStatesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(states.States) != 2 {
+		t.Errorf("want 2 states, got %v", states.States)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/estados/pe/cidades", nil))
+	var cities apiCitiesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &cities); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if cities.State != "PE" || len(cities.Cities) != 2 {
+		t.Errorf("want 2 cities of PE, got %+v", cities)
+	}
+}
+
+func TestAPISuggestions(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/sugestoes?q=jose&estado=al&ano=2020", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var resp apiSuggestionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(resp.Suggestions) != 2 || resp.Suggestions[0].Text != "PROFESSORA MARIA JOSÉ" || resp.Suggestions[0].SequentialID != "20000000001" {
+		t.Errorf("want ballot name and civil name of candidate 20000000001, got %+v", resp.Suggestions)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/sugestoes?q=jose&limite=100", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for invalid limit, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
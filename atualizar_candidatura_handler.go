@@ -1,17 +1,17 @@
 package main
 
 import (
-	"encoding/base64"
-	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/blob"
 	"github.com/candidatos-info/site/db"
 	"github.com/candidatos-info/site/exception"
-	"github.com/candidatos-info/site/token"
+	"github.com/candidatos-info/site/photo"
 	"github.com/labstack/echo"
 )
 
@@ -24,6 +24,8 @@ const (
 	bioFieldName         = "biography"
 	contactFieldName     = "contact"
 	providerFieldName    = "provider"
+	photoFieldName       = "foto"
+	newContactRows       = 2
 )
 
 type atualizarCandidaturaParams struct {
@@ -45,76 +47,49 @@ var (
 	}
 )
 
-func newAtualizarCandidaturaFormHandler(dbClient *db.Client) echo.HandlerFunc {
+// newAtualizarCandidaturaFormHandler updates the profile of the candidate.
+// With moderation enabled, changes to the biography and proposals are sent to
+// the moderation queue instead of being published, and saving the published
+// ones discards the changes waiting for moderation. Invalid values show the
+// form again, as submitted.
+func newAtualizarCandidaturaFormHandler(dbClient db.Store, tags []string, photoUploads, moderation bool) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		encodedAccessToken := c.FormValue("token")
-		accessTokenBytes, err := base64.StdEncoding.DecodeString(encodedAccessToken)
+		candidate := authenticatedCandidate(c)
+		form, err := c.FormParams()
 		if err != nil {
-			log.Printf("error decoding access token %s", string(encodedAccessToken))
+			log.Printf("failed to parse profile form (%s), error %v\n", candidate.SequencialCandidate, err)
 			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
 				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
 				"Success":  false,
 			})
 		}
-		if !tokenService.IsValid(string(accessTokenBytes)) {
-			log.Printf("invalid access token")
-			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": "Código de acesso inválido",
-				"Success":  false,
-			})
-		}
-		claims, err := token.GetClaims(string(accessTokenBytes))
-		if err != nil {
-			log.Printf("failed to extract token claims, error %v\n", err)
-			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-				"Success":  false,
-			})
+		params, errs := validateProfileForm(form)
+		if !errs.empty() {
+			candidate.Biography = params.Bio
+			candidate.Proposals = params.Proposals
+			rows := submittedContacts(form[providerFieldName], form[contactFieldName])
+			return renderProfileForm(c, dbClient, candidate, rows, tags, photoUploads, false, errs)
 		}
-		var candidate *descritor.CandidateForDB
-		if s, ok := claims["seqid"]; ok {
-			candidate, err = dbClient.FindCandidateBySequencialIDAndYear(globals.Year, s)
-			if err != nil {
-				log.Printf("Failed find candidate on DB (seqID:%s, year:%d), error %q\n", s, globals.Year, err)
-				if err != nil {
-					return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-						"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-						"Success":  false,
-					})
-				}
-			}
+		if moderation && needsModeration(candidate, params.Bio, params.Proposals) {
+			return submitProfileForModeration(c, dbClient, candidate, params)
 		}
-		if candidate == nil { // fallback on the old behavior.
-			email := claims["email"]
-			candidate, err = dbClient.GetCandidateByEmail(email, globals.Year)
-			if err != nil {
-				log.Printf("failed find candidate on DB (email:%s), error %v\n", email, err)
-				switch {
-				case err != nil && err.(*exception.Exception).Code == exception.NotFound:
-					return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-						"ErrorMsg": fmt.Sprintf("Não encontramos um cadastro de candidatura através do email %s. Por favor verifique se o email está correto.", email),
-						"Success":  false,
-					})
-				case err != nil:
-					return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-						"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-						"Success":  false,
-					})
-				}
+		if moderation {
+			// Otherwise approving them later would overwrite this save.
+			if err := dbClient.SupersedeSubmissions(candidate.Year, candidate.SequencialCandidate); err != nil {
+				log.Printf("failed to supersede submissions (%s), error %v\n", candidate.SequencialCandidate, err)
+				return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+					"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+					"Success":  false,
+				})
 			}
 		}
-		// Processing and validating form values.
-		params, err := parseFormValues(c)
-		if err != nil {
-			return err
-		}
 		candidate.Biography = params.Bio
 		candidate.Proposals = params.Proposals
 		candidate.Contacts = params.Contacts
 		candidate.Transparency = 100 // Since we made all fields mandatory, if the candidate has registered, its transparency will be 100%
 
 		// Updating candidates DB
-		if _, err := dbClient.UpdateCandidateProfile(candidate); err != nil {
+		if err := saveCandidateProfile(dbClient, candidate, candidateActor(candidate), authenticatedSessionID(c), db.AuditUpdateProfile); err != nil {
 			log.Printf("failed to update candidates profile, erro %v\n", err)
 			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
 				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
@@ -124,81 +99,91 @@ func newAtualizarCandidaturaFormHandler(dbClient *db.Client) echo.HandlerFunc {
 		return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
 			"ErrorMsg":     "Seus dados foram atualizados com sucesso!",
 			"Success":      true,
+			"Year":         candidate.Year,
 			"SequentialID": candidate.SequencialCandidate,
 		})
 	}
 }
 
-func parseFormValues(ctx echo.Context) (atualizarCandidaturaParams, error) {
-	numTags, err := strconv.Atoi(ctx.FormValue(numTagsFieldName))
-	if err != nil {
-		log.Printf("invalid num tags %s :%s, error %v\n", numTagsFieldName, ctx.FormValue(numTagsFieldName), err)
-		return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-			"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-			"Success":  false,
-		})
+// submitProfileForModeration publishes the contacts, which are not moderated,
+// and sends the biography and proposals to the moderation queue.
+func submitProfileForModeration(c echo.Context, dbClient db.Store, candidate *descritor.CandidateForDB, params *atualizarCandidaturaParams) error {
+	sessionID := authenticatedSessionID(c)
+	if formatContacts(candidate.Contacts) != formatContacts(params.Contacts) {
+		candidate.Contacts = params.Contacts
+		if err := saveCandidateProfile(dbClient, candidate, candidateActor(candidate), sessionID, db.AuditUpdateProfile); err != nil {
+			log.Printf("failed to update candidates contacts, erro %v\n", err)
+			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+				"Success":  false,
+			})
+		}
 	}
-	if numTags == 0 {
-		return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-			"ErrorMsg": "É necessário o preenchimento de, ao menos, uma pauta.",
+	if err := submitProfile(dbClient, candidate, sessionID, params.Bio, params.Proposals); err != nil {
+		log.Printf("failed to submit candidates profile to moderation, erro %v\n", err)
+		return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+			"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
 			"Success":  false,
 		})
 	}
-	var props []*descritor.Proposal
-	for i := 0; i < numTags; i++ {
-		p := descritor.Proposal{
-			Topic:       ctx.FormValue(fmt.Sprintf("descriptions[%d][tag]", i)),
-			Description: ctx.FormValue(fmt.Sprintf("descriptions[%d][description]", i)),
+	return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+		"Success":      true,
+		"Moderation":   true,
+		"Year":         candidate.Year,
+		"SequentialID": candidate.SequencialCandidate,
+	})
+}
+
+// POST /atualizar-candidatura/foto sends the uploaded photo to the moderation
+// queue. Photos are always moderated before replacing the one of the TSE.
+func newAtualizarFotoHandler(dbClient db.Store, files blob.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		candidate := authenticatedCandidate(c)
+		header, err := c.FormFile(photoFieldName)
+		if err != nil {
+			return c.Render(http.StatusBadRequest, "atualizar-candidato-success.html", map[string]interface{}{
+				"ErrorMsg": "Selecione uma foto para enviar.",
+				"Success":  false,
+			})
 		}
-		if len(p.Description) == 0 {
-			return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": fmt.Sprintf("O campo proposta da pauta %s é obrigatório", p.Topic),
+		f, err := header.Open()
+		if err != nil {
+			log.Printf("failed to open uploaded photo (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
 				"Success":  false,
 			})
 		}
-		if len(p.Description) > maxProposalsTextSize {
-			return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": fmt.Sprintf("Tamanho da proposta da pauta %s é de %d caracteres. O tamanho máximo permitido é de %d.", p.Topic, len(p.Description), maxProposalsTextSize),
+		defer f.Close()
+		data, err := ioutil.ReadAll(io.LimitReader(f, photo.MaxFileSize+1))
+		if err != nil {
+			log.Printf("failed to read uploaded photo (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
 				"Success":  false,
 			})
 		}
-		props = append(props, &p)
-	}
-	bio := ctx.FormValue(bioFieldName)
-	if len(bio) == 0 {
-		return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-			"ErrorMsg": "Biografia é um campo obrigatório. Por favor, preencher",
-			"Success":  false,
-		})
-	}
-	if len(bio) > maxBiographyTextSize {
-		return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-			"ErrorMsg": fmt.Sprintf("Tamanho máximo do campo mini-biografia é de %d caracteres.", maxBiographyTextSize),
-			"Success":  false,
-		})
-	}
-	contact := ctx.FormValue(contactFieldName)
-	if len(contact) == 0 {
-		return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-			"ErrorMsg": "Contato é um campo obrigatório. Por favor, preencher",
-			"Success":  false,
-		})
-	}
-	if len(contact) > maxContactsTextSize {
-		return atualizarCandidaturaParams{}, ctx.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-			"ErrorMsg": fmt.Sprintf("Tamanho máximo do campo contato é de %d caracteres.", maxContactsTextSize),
-			"Success":  false,
+		switch err := submitPhoto(dbClient, files, candidate, authenticatedSessionID(c), data).(type) {
+		case nil:
+		case *photo.Error:
+			return c.Render(http.StatusBadRequest, "atualizar-candidato-success.html", map[string]interface{}{
+				"ErrorMsg": err.Error(),
+				"Success":  false,
+			})
+		default:
+			log.Printf("failed to submit photo (%s), error %v\n", candidate.SequencialCandidate, err)
+			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+				"Success":  false,
+			})
+		}
+		return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+			"Success":      true,
+			"Photo":        true,
+			"Year":         candidate.Year,
+			"SequentialID": candidate.SequencialCandidate,
 		})
 	}
-	return atualizarCandidaturaParams{
-		NumTags: numTags,
-		Bio:     bio,
-		Contacts: []*descritor.Contact{&descritor.Contact{
-			SocialNetwork: ctx.FormValue(providerFieldName),
-			Value:         contact,
-		}},
-		Proposals: props,
-	}, nil
 }
 
 func mapMonthsToPortuguese(month time.Month) string {
@@ -231,65 +216,22 @@ func mapMonthsToPortuguese(month time.Month) string {
 	return ""
 }
 
-func newAtualizarCandidaturaHandler(dbClient *db.Client, tags []string) echo.HandlerFunc {
+// newAtualizarCandidaturaHandler shows the terms of use, if not accepted
+// yet, or the profile form, each one while its phase of the campaign
+// calendar is open. Changes waiting for moderation are shown in place of the
+// published ones, so candidates can keep editing them. The photo form is
+// only shown with photo uploads enabled.
+func newAtualizarCandidaturaHandler(dbClient db.Store, calendar *campaignCalendar, tags []string, photoUploads bool) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		encodedAccessToken := c.QueryParam("access_token")
-		accessTokenBytes, err := base64.StdEncoding.DecodeString(encodedAccessToken)
-		if err != nil {
-			log.Printf("error decoding access token %s", string(encodedAccessToken))
-			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-				"Success":  false,
-			})
-		}
-		if !tokenService.IsValid(string(accessTokenBytes)) {
-			log.Printf("invalid access token")
-			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": "Código de acesso inválido",
-				"Success":  false,
-			})
-		}
-		claims, err := token.GetClaims(string(accessTokenBytes))
-		if err != nil {
-			log.Printf("failed to extract claims, error %v\n", err)
-			return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-				"Success":  false,
-			})
-		}
-		var foundCandidate *descritor.CandidateForDB
-		if s, ok := claims["seqid"]; ok {
-			foundCandidate, err = dbClient.FindCandidateBySequencialIDAndYear(globals.Year, s)
-			if err != nil {
-				log.Printf("Failed find candidate on DB (seqID:%s, year:%d), error %q\n", s, globals.Year, err)
-				if err != nil {
-					return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-						"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-						"Success":  false,
-					})
-				}
-			}
+		encodedAccessToken := authenticatedAccessToken(c)
+		foundCandidate := authenticatedCandidate(c)
+		phase := db.PhaseEdit
+		if foundCandidate.AcceptedTerms.IsZero() {
+			phase = db.PhaseTerms
 		}
-		if foundCandidate == nil { // fallback on the old behavior.
-			email := claims["email"]
-			foundCandidate, err = dbClient.GetCandidateByEmail(email, globals.Year)
-			if err != nil {
-				log.Printf("failed find candidate on DB (email:%s), error %v\n", email, err)
-				switch {
-				case err != nil && err.(*exception.Exception).Code == exception.NotFound:
-					return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-						"ErrorMsg": fmt.Sprintf("Não encontramos um cadastro de candidatura através do email %s. Por favor verifique se o email está correto.", email),
-						"Success":  false,
-					})
-				case err != nil:
-					return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
-						"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-						"Success":  false,
-					})
-				}
-			}
+		if closed, err := checkPhase(c, calendar, foundCandidate.Year, phase); closed {
+			return err
 		}
-
 		_, month, day := time.Now().Date()
 		if foundCandidate.AcceptedTerms.IsZero() {
 			return c.Render(http.StatusOK, "aceitar-termo.html", map[string]interface{}{
@@ -299,15 +241,65 @@ func newAtualizarCandidaturaHandler(dbClient *db.Client, tags []string) echo.Han
 				"termsAcceptanceMonth": mapMonthsToPortuguese(month),
 			})
 		}
-		r := c.Render(http.StatusOK, "atualizar-candidato.html", map[string]interface{}{
-			"Token":                encodedAccessToken,
-			"AllTags":              tags,
-			"Candidato":            foundCandidate,
-			"MaxProposals":         maxProposals,
-			"MaxProposalsTextSize": maxProposalsTextSize,
-			"SocialNetworks":       socialNetworksUI,
+		return renderProfileForm(c, dbClient, foundCandidate, foundCandidate.Contacts, tags, photoUploads, true, newFormErrors())
+	}
+}
+
+// renderProfileForm shows the profile form with the values of the candidate
+// and the contact rows. If showPending, changes waiting for moderation are
+// shown in place of the biography and proposals of the candidate.
+func renderProfileForm(c echo.Context, dbClient db.Store, candidate *descritor.CandidateForDB, contacts []*descritor.Contact, tags []string, photoUploads, showPending bool, errs *formErrors) error {
+	status := http.StatusOK
+	if !errs.empty() {
+		status = http.StatusBadRequest
+	}
+	pending, err := dbClient.FindPendingSubmission(candidate.Year, candidate.SequencialCandidate)
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		pending = nil
+	case err != nil:
+		log.Printf("failed to find pending submission (%s), error %v\n", candidate.SequencialCandidate, err)
+		return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+			"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+			"Success":  false,
 		})
-		fmt.Println(r)
-		return r
+	case showPending:
+		candidate.Biography = pending.Biography
+		candidate.Proposals = pending.Proposals
+	}
+	pendingPhoto, err := dbClient.FindPendingPhotoSubmission(candidate.Year, candidate.SequencialCandidate)
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		pendingPhoto = nil
+	case err != nil:
+		log.Printf("failed to find pending photo (%s), error %v\n", candidate.SequencialCandidate, err)
+		return c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+			"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+			"Success":  false,
+		})
+	}
+	// Empty rows let candidates add contacts without scripts.
+	rows := append([]*descritor.Contact{}, contacts...)
+	for i := 0; i < newContactRows && len(rows) < maxContacts; i++ {
+		rows = append(rows, &descritor.Contact{})
 	}
+	return c.Render(status, "atualizar-candidato.html", map[string]interface{}{
+		"Token":                authenticatedAccessToken(c),
+		"AllTags":              tags,
+		"Candidato":            candidate,
+		"PendingSubmission":    pending,
+		"PhotoUploads":         photoUploads,
+		"PendingPhoto":         pendingPhoto,
+		"PhotoURL":             photoRenditionURL(candidate.PhotoURL, photoCardSize),
+		"PhotoMinWidth":        photo.MinWidth,
+		"PhotoMinHeight":       photo.MinHeight,
+		"PhotoMaxSizeMB":       photo.MaxFileSize >> 20,
+		"MaxProposals":         maxProposals,
+		"MaxProposalsTextSize": maxProposalsTextSize,
+		"MaxBiographyTextSize": maxBiographyTextSize,
+		"MaxContactsTextSize":  maxContactsTextSize,
+		"SocialNetworks":       socialNetworksUI,
+		"ContactRows":          rows,
+		"Errors":               errs,
+	})
 }
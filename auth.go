@@ -0,0 +1,162 @@
+package main
+
+import (
+	crand "crypto/rand"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/token"
+	"github.com/labstack/echo"
+)
+
+const (
+	loginTokenTTL = time.Hour          // magic links are short lived and single use.
+	sessionTTL    = 7 * 24 * time.Hour // sessions can be revoked at any time.
+)
+
+// newID returns a random identifier to be used by login tokens and sessions.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate random id, error %v", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// newLoginLink issues a new single use login token to the email, bound to the
+// candidature of the current election with the given sequential ID, stores it
+// and returns the magic link that must be sent by email.
+func newLoginLink(sessions db.SessionStore, tokenService *token.Token, email, seqID, ip, userAgent string) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	if err := sessions.CreateLoginToken(&db.LoginToken{
+		ID:              id,
+		Email:           strings.ToLower(email),
+		Year:            globals.Year,
+		SequentialID:    seqID,
+		IssuedAt:        now,
+		ExpiresAt:       now.Add(loginTokenTTL),
+		IssuedIP:        ip,
+		IssuedUserAgent: userAgent,
+	}); err != nil {
+		return "", err
+	}
+	loginToken, err := tokenService.Issue(token.LoginKind, id, strings.ToLower(email), seqID, loginTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/entrar?token=%s", siteURL, url.QueryEscape(b64.StdEncoding.EncodeToString([]byte(loginToken)))), nil
+}
+
+// parseToken decodes and verifies a base64 encoded token of the given kind,
+// returning its claims. Errors are token.ErrExpired or token.ErrInvalid.
+func parseToken(tokenService *token.Token, encodedToken, kind string) (map[string]string, error) {
+	tokenBytes, err := b64.StdEncoding.DecodeString(encodedToken)
+	if err != nil {
+		log.Printf("error decoding token %s", encodedToken)
+		return nil, token.ErrInvalid
+	}
+	claims, err := tokenService.GetClaims(string(tokenBytes))
+	if err != nil {
+		return nil, err
+	}
+	if claims["kind"] != kind || claims["jti"] == "" {
+		return nil, token.ErrInvalid
+	}
+	return claims, nil
+}
+
+// GET /entrar shows a confirmation page instead of using the login token
+// right away, so link scanners of email providers do not consume it.
+func entrarGET(c echo.Context) error {
+	return c.Render(http.StatusOK, "entrar.html", map[string]interface{}{
+		"Token": c.QueryParam("token"),
+	})
+}
+
+// POST /entrar exchanges a login token for a new session.
+func newEntrarFormHandler(sessions db.SessionStore, tokenService *token.Token) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		invalidLink := map[string]interface{}{
+			"Text": "Link de acesso inválido, expirado ou já utilizado. Por favor, solicite um novo link em /sou-candidato.",
+		}
+		claims, err := parseToken(tokenService, c.FormValue("token"), token.LoginKind)
+		if err != nil {
+			return c.Render(http.StatusOK, "sou-candidato-success.html", invalidLink)
+		}
+		now := time.Now().UTC()
+		loginToken, err := sessions.UseLoginToken(claims["jti"], now, c.RealIP(), c.Request().UserAgent())
+		if err != nil {
+			log.Printf("failed to use login token (%s):%q\n", claims["jti"], err)
+			return c.Render(http.StatusOK, "sou-candidato-success.html", invalidLink)
+		}
+		id, err := newID()
+		if err != nil {
+			log.Printf("failed to create session id:%q\n", err)
+			return c.Render(http.StatusOK, "sou-candidato-success.html", map[string]interface{}{
+				"Text": "Erro inesperado. Por favor tentar novamente mais tarde.",
+			})
+		}
+		if err := sessions.CreateSession(&db.Session{
+			ID:           id,
+			Email:        loginToken.Email,
+			Year:         loginToken.Year,
+			SequentialID: loginToken.SequentialID,
+			LoginTokenID: loginToken.ID,
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(sessionTTL),
+			IP:           c.RealIP(),
+			UserAgent:    c.Request().UserAgent(),
+		}); err != nil {
+			log.Printf("failed to create session (%s):%q\n", loginToken.Email, err)
+			return c.Render(http.StatusOK, "sou-candidato-success.html", map[string]interface{}{
+				"Text": "Erro inesperado. Por favor tentar novamente mais tarde.",
+			})
+		}
+		sessionToken, err := tokenService.Issue(token.SessionKind, id, loginToken.Email, loginToken.SequentialID, sessionTTL)
+		if err != nil {
+			log.Printf("failed to issue session token (%s):%q\n", loginToken.Email, err)
+			return c.Render(http.StatusOK, "sou-candidato-success.html", map[string]interface{}{
+				"Text": "Erro inesperado. Por favor tentar novamente mais tarde.",
+			})
+		}
+		encodedSessionToken := b64.StdEncoding.EncodeToString([]byte(sessionToken))
+		return c.Redirect(http.StatusSeeOther, "/atualizar-candidatura?access_token="+url.QueryEscape(encodedSessionToken))
+	}
+}
+
+// POST /sair revokes the current session. When the form field "todas" is
+// set, all sessions and unused login links of the email are revoked.
+func newSairFormHandler(sessions db.SessionStore, tokenService *token.Token) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims, err := parseToken(tokenService, c.FormValue("token"), token.SessionKind)
+		if err != nil {
+			return c.Redirect(http.StatusSeeOther, "/sou-candidato")
+		}
+		now := time.Now().UTC()
+		if c.FormValue("todas") != "" {
+			err = sessions.RevokeAllByEmail(claims["email"], now)
+		} else {
+			err = sessions.RevokeSession(claims["jti"], now)
+		}
+		if err != nil {
+			log.Printf("failed to revoke session (%s):%q\n", claims["jti"], err)
+			return c.Render(http.StatusOK, "sou-candidato-success.html", map[string]interface{}{
+				"Text": "Erro inesperado. Por favor tentar novamente mais tarde.",
+			})
+		}
+		return c.Render(http.StatusOK, "sou-candidato-success.html", map[string]interface{}{
+			"Text": "Sessão encerrada com sucesso.",
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/token"
+	"github.com/labstack/echo"
+)
+
+// Keys used to store the authenticated candidate in the echo.Context.
+const (
+	candidateContextKey   = "candidate"
+	accessTokenContextKey = "accessToken"
+	sessionContextKey     = "session"
+)
+
+// newCandidateAuthMiddleware verifies the session token sent as the
+// access_token (or token) parameter, checks the session is still active and
+// resolves the candidate it was issued to. The candidate, the encoded token
+// and the session ID are stored in the context, see authenticatedCandidate,
+// authenticatedAccessToken and authenticatedSessionID. Requests that fail any of these steps are
+// answered with the acesso-invalido.html page.
+func newCandidateAuthMiddleware(dbClient db.Store, tokenService *token.Token) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			encodedAccessToken := c.FormValue("access_token")
+			if encodedAccessToken == "" {
+				encodedAccessToken = c.FormValue("token")
+			}
+			claims, err := parseToken(tokenService, encodedAccessToken, token.SessionKind)
+			switch {
+			case err == token.ErrExpired:
+				return renderAuthError(c, http.StatusUnauthorized, "Código de acesso expirado. Por favor, solicite um novo link de acesso.")
+			case err != nil:
+				log.Printf("invalid access token, error %v\n", err)
+				return renderAuthError(c, http.StatusUnauthorized, "Código de acesso inválido. Por favor, solicite um novo link de acesso.")
+			}
+			session, err := dbClient.FindSession(claims["jti"])
+			if err != nil {
+				log.Printf("failed to find session (%s):%q\n", claims["jti"], err)
+				return renderAuthError(c, http.StatusUnauthorized, "Código de acesso inválido. Por favor, solicite um novo link de acesso.")
+			}
+			if !session.Active(time.Now()) {
+				if !session.RevokedAt.IsZero() {
+					return renderAuthError(c, http.StatusUnauthorized, "Código de acesso inválido. Por favor, solicite um novo link de acesso.")
+				}
+				return renderAuthError(c, http.StatusUnauthorized, "Código de acesso expirado. Por favor, solicite um novo link de acesso.")
+			}
+			candidate, err := findSessionCandidate(dbClient, claims)
+			switch {
+			case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+				return renderAuthError(c, http.StatusNotFound, "Não encontramos a candidatura associada a este código de acesso.")
+			case err != nil:
+				log.Printf("failed to find candidate of session (%s), error %v\n", claims["jti"], err)
+				return renderAuthError(c, http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+			}
+			disabled, err := isProfileDisabled(dbClient, candidate.Year, candidate.SequencialCandidate)
+			switch {
+			case err != nil:
+				log.Printf("failed to check if profile is disabled (%s), error %v\n", candidate.SequencialCandidate, err)
+				return renderAuthError(c, http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+			case disabled:
+				return renderAuthError(c, http.StatusForbidden, "Este perfil foi desativado pela equipe do candidatos.info. Por favor, entre em contato conosco.")
+			}
+			c.Set(candidateContextKey, candidate)
+			c.Set(accessTokenContextKey, encodedAccessToken)
+			c.Set(sessionContextKey, session.ID)
+			return next(c)
+		}
+	}
+}
+
+// findSessionCandidate resolves the candidate using the sequential ID of the
+// token claims and falls back on the email for tokens issued without it. The
+// candidate must still be registered with the email the token was sent to.
+func findSessionCandidate(dbClient db.CandidateStore, claims map[string]string) (*descritor.CandidateForDB, error) {
+	seqID, ok := claims["seqid"]
+	if !ok {
+		return dbClient.GetCandidateByEmail(strings.ToUpper(claims["email"]), globals.Year)
+	}
+	candidate, err := dbClient.FindCandidateBySequencialIDAndYear(globals.Year, seqID)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Email != strings.ToUpper(claims["email"]) {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Candidatura [%s] não pertence ao email [%s]", seqID, claims["email"]), nil)
+	}
+	return candidate, nil
+}
+
+func renderAuthError(c echo.Context, status int, msg string) error {
+	return c.Render(status, "acesso-invalido.html", map[string]interface{}{
+		"ErrorMsg": msg,
+	})
+}
+
+// authenticatedCandidate returns the candidate resolved by the auth middleware.
+func authenticatedCandidate(c echo.Context) *descritor.CandidateForDB {
+	return c.Get(candidateContextKey).(*descritor.CandidateForDB)
+}
+
+// authenticatedAccessToken returns the encoded session token verified by the
+// auth middleware, so it can be passed on to the next forms.
+func authenticatedAccessToken(c echo.Context) string {
+	return c.Get(accessTokenContextKey).(string)
+}
+
+// authenticatedSessionID returns the ID of the session verified by the auth
+// middleware.
+func authenticatedSessionID(c echo.Context) string {
+	return c.Get(sessionContextKey).(string)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/challenge"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/email"
+	"github.com/candidatos-info/site/token"
+)
+
+func postForm(t *testing.T, h http.Handler, target string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoginLinkIsSingleUseAndSessionIsRevocable(t *testing.T) {
+	e, store := newTestServer(t)
+	link, err := newLoginLink(store, tokenService, "MARIA.JOSE@exemplo.com", "20000000001", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	loginToken := u.Query().Get("token")
+
+	// Opening the link must not consume it.
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `action="/entrar"`) {
+		t.Fatalf("want confirmation page, got status %d", rec.Code)
+	}
+
+	rec = postForm(t, e, "/entrar", url.Values{"token": {loginToken}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after login, got status %d", rec.Code)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	sessionToken := location.Query().Get("access_token")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, location.RequestURI(), nil))
+	if !strings.Contains(rec.Body.String(), "Salvar perfil") {
+		t.Errorf("want profile form when using the session token")
+	}
+
+	rec = postForm(t, e, "/entrar", url.Values{"token": {loginToken}})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "já utilizado") {
+		t.Errorf("want login token to be single use, got status %d", rec.Code)
+	}
+	rec = postForm(t, e, "/entrar", url.Values{"token": {sessionToken}})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "já utilizado") {
+		t.Errorf("want session token not to be accepted as login token, got status %d", rec.Code)
+	}
+
+	rec = postForm(t, e, "/sair", url.Values{"token": {sessionToken}, "todas": {"1"}})
+	if !strings.Contains(rec.Body.String(), "Sessão encerrada") {
+		t.Errorf("want session to be revoked")
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, location.RequestURI(), nil))
+	if !strings.Contains(rec.Body.String(), "Código de acesso inválido") {
+		t.Errorf("want revoked session token to be rejected")
+	}
+}
+
+func TestCandidateAuthMiddleware(t *testing.T) {
+	e, store := newTestServer(t)
+	now := time.Now()
+	newSessionToken := func(id, email string, sessionTTL, tokenTTL time.Duration, tokenService *token.Token) string {
+		if err := store.CreateSession(&db.Session{ID: id, Email: email, CreatedAt: now, ExpiresAt: now.Add(sessionTTL)}); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		sessionToken, err := tokenService.Issue(token.SessionKind, id, email, "", tokenTTL)
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		return b64.StdEncoding.EncodeToString([]byte(sessionToken))
+	}
+	testCases := []struct {
+		name        string
+		accessToken string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"valid", newSessionToken("valid", "maria.jose@exemplo.com", time.Hour, time.Hour, tokenService), http.StatusOK, "Salvar perfil"},
+		{"missing", "", http.StatusUnauthorized, "Código de acesso inválido"},
+		{"not base64", "%%%", http.StatusUnauthorized, "Código de acesso inválido"},
+		{"forged", newSessionToken("forged", "maria.jose@exemplo.com", time.Hour, time.Hour, token.New("other secret")), http.StatusUnauthorized, "Código de acesso inválido"},
+		{"expired token", newSessionToken("expired-token", "maria.jose@exemplo.com", time.Hour, -time.Minute, tokenService), http.StatusUnauthorized, "Código de acesso expirado"},
+		{"expired session", newSessionToken("expired-session", "maria.jose@exemplo.com", -time.Minute, time.Hour, tokenService), http.StatusUnauthorized, "Código de acesso expirado"},
+		{"unknown candidate", newSessionToken("unknown", "ninguem@exemplo.com", time.Hour, time.Hour, tokenService), http.StatusNotFound, "Não encontramos a candidatura"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/atualizar-candidatura?access_token="+url.QueryEscape(tt.accessToken), nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("want status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("want body containing %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestLoginLinksAreBoundToCandidature(t *testing.T) {
+	e, store := newTestServer(t)
+	login := func(email, seqID string) *httptest.ResponseRecorder {
+		link, err := newLoginLink(store, tokenService, email, seqID, "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		rec := postForm(t, e, "/entrar", url.Values{"token": {u.Query().Get("token")}})
+		rec2 := httptest.NewRecorder()
+		e.ServeHTTP(rec2, httptest.NewRequest(http.MethodGet, rec.Header().Get("Location"), nil))
+		return rec2
+	}
+	for seqID, name := range map[string]string{
+		"20000000002": "JOÃO PEREIRA SANTOS",
+		"20000000003": "ANTÔNIO CARLOS LIMA",
+	} {
+		if body := login("comite@exemplo.com", seqID).Body.String(); !strings.Contains(body, name) {
+			t.Errorf("want page of candidature %s (%s)", seqID, name)
+		}
+	}
+	if rec := login("comite@exemplo.com", "20000000001"); rec.Code != http.StatusNotFound {
+		t.Errorf("want candidature of another email to be rejected, got status %d", rec.Code)
+	}
+}
+
+// souCandidatoForm returns the login form filled with the email and a solved
+// challenge, as submitted by browsers.
+func souCandidatoForm(t *testing.T, address string) url.Values {
+	t.Helper()
+	desafio, err := testLoginChallenges.Issue(time.Now())
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	return url.Values{
+		"email":   {address},
+		"chave":   {"form-key"},
+		"desafio": {desafio},
+		"prova":   {challenge.Solve(desafio, testLoginChallengeDifficulty)},
+	}
+}
+
+func TestSouCandidatoSendsOneLinkPerCandidature(t *testing.T) {
+	e, store := newTestServer(t)
+	mailer := emailClient.(*email.MemoryMailer)
+	form := souCandidatoForm(t, "comite@exemplo.com")
+	for i := 0; i < 2; i++ { // the form is submitted twice.
+		rec := postForm(t, e, "/sou-candidato", form)
+		if !strings.Contains(rec.Body.String(), "você receberá em instantes um link de acesso") {
+			t.Errorf("want login requested message")
+		}
+	}
+	if _, err := deliverDueEmails(store, mailer, time.Now().UTC()); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	msgs := mailer.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("want 1 email sent, got %d", len(msgs))
+	}
+	if msgs[0].From != emailFrom || msgs[0].To[0] != "COMITE@EXEMPLO.COM" {
+		t.Errorf("want email from %s to COMITE@EXEMPLO.COM, got %+v", emailFrom, msgs[0])
+	}
+	if n := strings.Count(msgs[0].HTML, `href="http://localhost/entrar?token=`); n != 2 {
+		t.Errorf("want 2 login links, got %d", n)
+	}
+	registered := postForm(t, e, "/sou-candidato", souCandidatoForm(t, "maria.jose@exemplo.com")).Body.String()
+	unknown := postForm(t, e, "/sou-candidato", souCandidatoForm(t, "ninguem@exemplo.com")).Body.String()
+	if strings.Replace(unknown, "ninguem@", "maria.jose@", 1) != registered {
+		t.Errorf("want the same response for registered and unknown emails")
+	}
+	if emails, _ := store.ListEmails("", 10); len(emails) != 2 {
+		t.Errorf("want 2 emails queued, got %d", len(emails))
+	}
+}
+
+func TestSouCandidatoAbuseProtection(t *testing.T) {
+	e, store := newTestServer(t)
+	page := httptest.NewRecorder()
+	e.ServeHTTP(page, httptest.NewRequest(http.MethodGet, "/sou-candidato", nil))
+	if !strings.Contains(page.Body.String(), `name="desafio" value="`) {
+		t.Errorf("want challenge in the login form")
+	}
+
+	honeypot := souCandidatoForm(t, "maria.jose@exemplo.com")
+	honeypot.Set(honeypotField, "http://spam.example.com")
+	if rec := postForm(t, e, "/sou-candidato", honeypot); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "você receberá em instantes") {
+		t.Errorf("want login requested message when the honeypot is filled, got status %d", rec.Code)
+	}
+
+	forged := souCandidatoForm(t, "maria.jose@exemplo.com")
+	forged.Set("desafio", "1"+forged.Get("desafio"))
+	if rec := postForm(t, e, "/sou-candidato", forged); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for forged challenge, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if emails, _ := store.ListEmails("", 10); len(emails) != 0 {
+		t.Errorf("want no emails queued, got %d", len(emails))
+	}
+
+	for i := 0; i < loginAttemptsPerEmail+1; i++ {
+		form := souCandidatoForm(t, "maria.jose@exemplo.com")
+		form.Set("chave", strconv.Itoa(i))
+		if rec := postForm(t, e, "/sou-candidato", form); !strings.Contains(rec.Body.String(), "você receberá em instantes") {
+			t.Errorf("want login requested message for attempt %d", i)
+		}
+	}
+	if emails, _ := store.ListEmails("", 10); len(emails) != loginAttemptsPerEmail {
+		t.Errorf("want %d emails queued, got %d", loginAttemptsPerEmail, len(emails))
+	}
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < loginAttemptsPerIP; i++ {
+		rec = postForm(t, e, "/sou-candidato", souCandidatoForm(t, "ninguem@exemplo.com"))
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("want status %d after too many attempts, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/search"
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultSuggestions         = 10
+	maxSuggestions             = 20
+	suggestionsRebuildInterval = time.Hour
+)
+
+type apiSuggestionsResponse struct {
+	Suggestions []*search.Suggestion `json:"suggestions"`
+}
+
+// rebuildSuggestions rebuilds the autocomplete index using the candidatures
+// and locations found in the database.
+func rebuildSuggestions(dbClient db.CandidateStore, index *search.PrefixIndex) error {
+	candidatures, err := dbClient.FindCandidaturesForIndex()
+	if err != nil {
+		return err
+	}
+	locations, err := dbClient.GetLocations()
+	if err != nil {
+		return err
+	}
+	var suggestions []*search.Suggestion
+	for _, c := range candidatures {
+		suggestions = append(suggestions,
+			&search.Suggestion{Kind: search.KindBallotName, Text: c.BallotName, State: c.State, City: c.City, Year: c.Year, SequentialID: c.SequencialCandidate},
+			&search.Suggestion{Kind: search.KindName, Text: c.Name, State: c.State, City: c.City, Year: c.Year, SequentialID: c.SequencialCandidate},
+			&search.Suggestion{Kind: search.KindParty, Text: c.Party, State: c.State, City: c.City, Year: c.Year},
+		)
+	}
+	for _, l := range locations {
+		for _, city := range l.Cities {
+			suggestions = append(suggestions, &search.Suggestion{Kind: search.KindCity, Text: city, State: l.State, City: city})
+		}
+	}
+	index.Rebuild(suggestions)
+	return nil
+}
+
+// keepSuggestionsUpdated rebuilds the autocomplete index right away and then
+// periodically. It never returns.
+func keepSuggestionsUpdated(dbClient db.CandidateStore, index *search.PrefixIndex, interval time.Duration) {
+	for {
+		start := time.Now()
+		if err := rebuildSuggestions(dbClient, index); err != nil {
+			log.Printf("failed to rebuild suggestions index, error %v\n", err)
+		} else {
+			log.Printf("suggestions index rebuilt with %d entries in %s\n", index.Len(), time.Since(start))
+		}
+		time.Sleep(interval)
+	}
+}
+
+func newAPISuggestionsHandler(index *search.PrefixIndex) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		scope := search.Scope{
+			State: strings.ToUpper(c.QueryParam("estado")),
+			City:  c.QueryParam("cidade"),
+		}
+		if year := c.QueryParam("ano"); year != "" {
+			y, err := strconv.Atoi(year)
+			if err != nil {
+				return apiError(c, exception.New(exception.InvalidParameters, "Ano fornecido é inválido.", nil))
+			}
+			scope.Year = y
+		}
+		limit := defaultSuggestions
+		if l := c.QueryParam("limite"); l != "" {
+			aux, err := strconv.Atoi(l)
+			if err != nil || aux < 1 || aux > maxSuggestions {
+				return apiError(c, exception.New(exception.InvalidParameters, "Limite fornecido é inválido.", nil))
+			}
+			limit = aux
+		}
+		suggestions := index.Lookup(c.QueryParam("q"), scope, limit)
+		if suggestions == nil {
+			suggestions = []*search.Suggestion{}
+		}
+		return c.JSON(http.StatusOK, apiSuggestionsResponse{Suggestions: suggestions})
+	}
+}
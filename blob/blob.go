@@ -0,0 +1,123 @@
+// Package blob stores files uploaded to the site, like profile photos, in a
+// pluggable store and tells the URL they are served from.
+package blob
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Store keeps files identified by keys, like "fotos/2020/123/abc.jpg".
+type Store interface {
+	// Put stores the data under the key, replacing the file if it exists.
+	Put(key, contentType string, data []byte) error
+
+	// URL returns the address the file of the key is served from.
+	URL(key string) string
+}
+
+var validKey = regexp.MustCompile(`^[a-zA-Z0-9_-]+(/[a-zA-Z0-9_.-]+)*$`)
+
+func checkKey(key string) error {
+	if !validKey.MatchString(key) || strings.Contains(key, "..") {
+		return fmt.Errorf("chave de arquivo inválida (%s)", key)
+	}
+	return nil
+}
+
+// FileStore keeps the files in a directory of the local filesystem, to be
+// served by the site itself under a base URL.
+type FileStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileStore returns a new FileStore, creating the directory if needed.
+func NewFileStore(dir, baseURL string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("falha ao criar diretório de arquivos (%s), erro %q", dir, err)
+	}
+	return &FileStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Put writes the data to a temporary file which is then renamed, so the file
+// is never served half written.
+func (s *FileStore) Put(key, contentType string, data []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	name := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("falha ao criar diretório do arquivo (%s), erro %q", key, err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), ".upload-")
+	if err != nil {
+		return fmt.Errorf("falha ao criar arquivo (%s), erro %q", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("falha ao gravar arquivo (%s), erro %q", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("falha ao gravar arquivo (%s), erro %q", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("falha ao gravar arquivo (%s), erro %q", key, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("falha ao gravar arquivo (%s), erro %q", key, err)
+	}
+	return nil
+}
+
+// URL returns the base URL followed by the key.
+func (s *FileStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// File is a file kept by the MemoryStore.
+type File struct {
+	ContentType string
+	Data        []byte
+}
+
+// MemoryStore keeps the files in memory. It is meant for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	files   map[string]*File
+	baseURL string
+}
+
+// NewMemoryStore returns a new empty MemoryStore.
+func NewMemoryStore(baseURL string) *MemoryStore {
+	return &MemoryStore{files: make(map[string]*File), baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Put keeps a copy of the data.
+func (s *MemoryStore) Put(key, contentType string, data []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = &File{ContentType: contentType, Data: append([]byte(nil), data...)}
+	return nil
+}
+
+// URL returns the base URL followed by the key.
+func (s *MemoryStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// Get returns the file of the key, nil if there is none.
+func (s *MemoryStore) Get(key string) *File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files[key]
+}
@@ -0,0 +1,59 @@
+package blob
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blob")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir, "/arquivos/")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	key := "fotos/2020/20000000001/abc-320.jpg"
+	for _, data := range []string{"primeira", "segunda"} {
+		if err := s.Put(key, "image/jpeg", []byte(data)); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "fotos", "2020", "20000000001", "abc-320.jpg"))
+	if err != nil || string(got) != "segunda" {
+		t.Errorf("want file replaced, got %q (error %v)", got, err)
+	}
+	if files, _ := ioutil.ReadDir(filepath.Join(dir, "fotos", "2020", "20000000001")); len(files) != 1 {
+		t.Errorf("want no temporary files left, got %d files", len(files))
+	}
+	if u := s.URL(key); u != "/arquivos/"+key {
+		t.Errorf("want URL under the base URL, got %s", u)
+	}
+	for _, key := range []string{"", "/etc/passwd", "fotos/../../segredo", "fotos//a.jpg", `fotos\a.jpg`} {
+		if err := s.Put(key, "image/jpeg", []byte("x")); err == nil {
+			t.Errorf("want error for invalid key %q", key)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore("http://localhost/arquivos")
+	data := []byte("foto")
+	if err := s.Put("fotos/a.jpg", "image/jpeg", data); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	data[0] = 'F'
+	if f := s.Get("fotos/a.jpg"); f == nil || string(f.Data) != "foto" || f.ContentType != "image/jpeg" {
+		t.Errorf("want a copy of the file stored, got %+v", f)
+	}
+	if f := s.Get("fotos/b.jpg"); f != nil {
+		t.Errorf("want nil for missing file, got %+v", f)
+	}
+	if u := s.URL("fotos/a.jpg"); u != "http://localhost/arquivos/fotos/a.jpg" {
+		t.Errorf("want URL under the base URL, got %s", u)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+// The campaign calendar defines, for each election year, when candidates can
+// request access links, accept the terms and edit their profiles. Operators
+// adjust it at /admin/calendario; years without a calendar have every phase
+// open, except profile editing when UPDATE_PROFILE is not 1.
+
+var (
+	phasesUI = map[string]string{
+		db.PhaseLogin: "Acesso de candidatos",
+		db.PhaseTerms: "Aceite do termo de uso",
+		db.PhaseEdit:  "Edição de perfis",
+	}
+
+	// calendarLocation is the time zone of the dates of the calendar, the
+	// official time of Brasília, which has no daylight saving time since 2019.
+	calendarLocation = time.FixedZone("BRT", -3*60*60)
+)
+
+type campaignCalendar struct {
+	store         db.CalendarStore
+	updateProfile bool
+}
+
+// newCampaignCalendar returns the calendar kept in the store. The
+// updateProfile flag sets whether profile editing is open in years without a
+// calendar.
+func newCampaignCalendar(store db.CalendarStore, updateProfile bool) *campaignCalendar {
+	return &campaignCalendar{store: store, updateProfile: updateProfile}
+}
+
+// calendar returns the calendar of the election year, or the default one if
+// operators have not defined it.
+func (cc *campaignCalendar) calendar(year int) (*db.Calendar, error) {
+	cal, err := cc.store.FindCalendar(year)
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		return &db.Calendar{
+			Year:    year,
+			Windows: map[string]*db.CalendarWindow{db.PhaseEdit: {Closed: !cc.updateProfile}},
+		}, nil
+	case err != nil:
+		return nil, err
+	}
+	return cal, nil
+}
+
+// checkPhase renders the page explaining the phase is closed and returns
+// closed true if the phase is not open for the election year. Callers must
+// return err when closed, as with the finders.
+func checkPhase(c echo.Context, cc *campaignCalendar, year int, phase string) (closed bool, err error) {
+	cal, err := cc.calendar(year)
+	if err != nil {
+		log.Printf("failed to find calendar (%d), error %v\n", year, err)
+		return true, c.Render(http.StatusOK, "atualizar-candidato-success.html", map[string]interface{}{
+			"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+			"Success":  false,
+		})
+	}
+	w := cal.Window(phase)
+	now := time.Now()
+	if w.Open(now) {
+		return false, nil
+	}
+	data := map[string]interface{}{
+		"Phase":        phase,
+		"ElectionYear": year,
+		"Closed":       w.Closed,
+		"NotYet":       !w.Closed && !w.Opens.IsZero() && now.Before(w.Opens),
+		"Opens":        w.Opens.In(calendarLocation),
+		"Closes":       w.Closes.In(calendarLocation),
+	}
+	if cand, ok := c.Get(candidateContextKey).(*descritor.CandidateForDB); ok {
+		data["Candidate"] = cand
+		data["Token"] = authenticatedAccessToken(c)
+	}
+	return true, c.Render(http.StatusForbidden, "periodo-fechado.html", data)
+}
+
+// requirePhase is a middleware answering with the page explaining the phase
+// is closed. The election year is the one of the authenticated candidate, if
+// any, or the current one.
+func requirePhase(cc *campaignCalendar, phase string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			year := globals.Year
+			if cand, ok := c.Get(candidateContextKey).(*descritor.CandidateForDB); ok {
+				year = cand.Year
+			}
+			if closed, err := checkPhase(c, cc, year, phase); closed {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+)
+
+func TestCampaignCalendarDefault(t *testing.T) {
+	_, store := newTestServer(t)
+	for _, tc := range []struct {
+		updateProfile bool
+		want          bool
+	}{{true, true}, {false, false}} {
+		cal, err := newCampaignCalendar(store, tc.updateProfile).calendar(2020)
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		if got := cal.Window(db.PhaseEdit).Open(time.Now()); got != tc.want {
+			t.Errorf("want editing open %v with UPDATE_PROFILE %v, got %v", tc.want, tc.updateProfile, got)
+		}
+		if !cal.Window(db.PhaseLogin).Open(time.Now()) || !cal.Window(db.PhaseTerms).Open(time.Now()) {
+			t.Errorf("want login and terms open by default")
+		}
+	}
+}
+
+func TestCampaignCalendar(t *testing.T) {
+	e, store := newTestServer(t)
+	calendar := newCampaignCalendar(store, true)
+	e.POST("/atualizar-candidatura", newAtualizarCandidaturaFormHandler(store, nil, false, false), newCandidateAuthMiddleware(store, tokenService), requirePhase(calendar, db.PhaseEdit))
+	accessToken := loginTestCandidate(t, e, store, "comite@exemplo.com", "20000000002")
+	profilePage := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/atualizar-candidatura?access_token="+url.QueryEscape(accessToken), nil))
+		return rec
+	}
+	inputTime := func(d time.Duration) string {
+		return time.Now().Add(d).In(calendarLocation).Format(calendarInputLayout)
+	}
+
+	createTestOperator(t, store, "leitor@exemplo.com", db.RoleViewer)
+	if rec := adminRequest(t, e, http.MethodGet, "/admin/calendario", "leitor@exemplo.com", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Edição de perfis") {
+		t.Errorf("want calendar shown to viewers, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, "/admin/calendario", "leitor@exemplo.com", url.Values{"ano": {"2020"}}); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d for viewer, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, "/admin/calendario", testAdminUser, url.Values{"ano": {"2020"}, "edicao_abre": {inputTime(time.Hour)}, "edicao_fecha": {inputTime(-time.Hour)}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d closing before opening, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// Terms open tomorrow and editing closed an hour ago.
+	rec := adminRequest(t, e, http.MethodPost, "/admin/calendario", testAdminUser, url.Values{
+		"ano":          {"2020"},
+		"termo_abre":   {inputTime(24 * time.Hour)},
+		"edicao_fecha": {inputTime(-time.Hour)},
+	})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after saving the calendar, got status %d", rec.Code)
+	}
+	if cal, err := store.FindCalendar(2020); err != nil || cal.UpdatedBy != testAdminUser || cal.Window(db.PhaseTerms).Opens.IsZero() {
+		t.Fatalf("want calendar saved, got %+v (error %v)", cal, err)
+	}
+	entries, err := store.ListAuditEntries(2020, "", 10)
+	if err != nil || len(entries) != 1 || entries[0].Action != db.AuditUpdateCalendar || entries[0].Actor != operatorActor(&db.Operator{ID: testAdminUser}) {
+		t.Fatalf("want calendar change audited, got %v (error %v)", entries, err)
+	}
+	if changes := entries[0].Changes; len(changes) != 2 || changes[0].Field != phasesUI[db.PhaseTerms] || changes[0].Before != "abre: sem limite, fecha: sem limite" || changes[1].Field != phasesUI[db.PhaseEdit] {
+		t.Errorf("want terms and edit windows changed, got %+v", changes)
+	}
+	if rec := profilePage(); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "abre em") {
+		t.Errorf("want terms not open yet, got status %d", rec.Code)
+	}
+	if rec := postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}}); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d accepting terms, got %d", http.StatusForbidden, rec.Code)
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000002"); !candidate.AcceptedTerms.IsZero() {
+		t.Errorf("want terms not accepted")
+	}
+
+	adminRequest(t, e, http.MethodPost, "/admin/calendario", testAdminUser, url.Values{"ano": {"2020"}, "edicao_fecha": {inputTime(-time.Hour)}})
+	postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}})
+	if rec := profilePage(); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "foi encerrado") {
+		t.Errorf("want editing closed, got status %d", rec.Code)
+	}
+	rec = postForm(t, e, "/atualizar-candidatura", url.Values{
+		"access_token":                 {accessToken},
+		"numTags":                      {"1"},
+		"descriptions[0][tag]":         {"Educação"},
+		"descriptions[0][description]": {"Creches em todos os bairros."},
+		"biography":                    {"Biografia depois da eleição."},
+		"provider":                     {"twitter"},
+		"contact":                      {"mariajose"},
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d editing, got %d", http.StatusForbidden, rec.Code)
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000002"); candidate.Biography == "Biografia depois da eleição." {
+		t.Errorf("want profile not changed")
+	}
+
+	adminRequest(t, e, http.MethodPost, "/admin/calendario", testAdminUser, url.Values{"ano": {"2020"}, "login_suspenso": {"1"}})
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, "/sou-candidato", nil))
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "suspenso") {
+			t.Errorf("want login suspended for %s, got status %d", method, rec.Code)
+		}
+	}
+	if rec := profilePage(); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Salvar perfil") {
+		t.Errorf("want editing open again, got status %d", rec.Code)
+	}
+}
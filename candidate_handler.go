@@ -22,7 +22,7 @@ type requestProposalEmail struct {
 	To      string
 }
 
-func newCandidateHandler(db *db.Client) echo.HandlerFunc {
+func newCandidateHandler(dbClient db.Store) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// TODO: Create error page.
 		id := c.Param("id")
@@ -31,7 +31,7 @@ func newCandidateHandler(db *db.Client) echo.HandlerFunc {
 			log.Printf("Parâmetro year inválido (%s):%q\n", c.Param("year"), err)
 			return echo.ErrBadRequest
 		}
-		candidate, err := db.FindCandidateBySequencialIDAndYear(year, id)
+		candidate, err := findPublicCandidate(dbClient, year, id)
 		switch {
 		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
 			return echo.ErrNotFound
@@ -41,45 +41,16 @@ func newCandidateHandler(db *db.Client) echo.HandlerFunc {
 		}
 		email := requestProposalEmail{}
 		if len(candidate.Proposals) == 0 {
-			email.To = strings.ToLower(candidate.Email)
-			email.Subject = "Registro na plataforma candidatos.info"
-			email.Body = fmt.Sprintf(`Olá, sr(a) %s
-
-			Sou eleitor(a) na cidade de %s/%s e percebi que seu perfil https://candidatos.info/c/%d/%s não possui propostas.
-			
-			Para atualizá-lo, basta acessar https://candidatos.info/sou-candidato, escolher as áreas de atuação e preencher as propostas referentes a cada uma das áreas
-
-			Acesse https://candidatos.info/sobre para mais informações sobre a plataforma.
-			
-			Atenciosamente,
-			Um(a) eleitor(a) tentando pautar as eleições`,
-				candidate.Name,
-				candidate.City,
-				candidate.State,
-				candidate.Year,
-				candidate.SequencialCandidate,
-			)
-		}
-		for _, sn := range candidate.Contacts {
-			addrPrefix := ""
-			switch sn.SocialNetwork {
-			case "email":
-				addrPrefix = "mailto:"
-			case "telefone":
-				addrPrefix = "tel:"
-			case "whatsapp":
-				addrPrefix = "https://wa.me/"
-			case "facebook":
-				addrPrefix = "http://facebook.com/"
-			case "instagram":
-				addrPrefix = "http://instagram.com/"
-			case "twitter":
-				addrPrefix = "http://twitter.com/"
-			case "paginaWeb":
-				addrPrefix = "http://"
+			m, err := buildRequestProposalsEmail(candidate)
+			if err != nil {
+				log.Printf("failed to build request proposals email, error %v\n", err)
+				return echo.ErrInternalServerError
 			}
-			sn.Value = addrPrefix + sn.Value
+			email.To = strings.ToLower(candidate.Email)
+			email.Subject = m.Subject
+			email.Body = m.Text
 		}
+		contacts := contactLinks(candidate.Contacts)
 		queryMap := make(map[string]interface{})
 		queryMap["city"] = candidate.City
 		queryMap["state"] = candidate.State
@@ -89,13 +60,13 @@ func newCandidateHandler(db *db.Client) echo.HandlerFunc {
 		}
 		queryMap["tags"] = candidateTags
 		queryMap["role"] = candidate.Role
-		relatedCandidatures, err := db.FindTransparentCandidatures(queryMap, relatedCandidaturesMaxCards)
+		relatedCandidatures, err := dbClient.FindTransparentCandidatures(queryMap, db.Page{Number: 1, Size: relatedCandidaturesMaxCards, Seed: searchSeed(c)})
 		if err != nil {
 			log.Printf("failed to find related candidatures, error %v\n", err)
 			return echo.ErrInternalServerError
 		}
 		var relatedCandidatesCards []*candidateCard
-		for _, rc := range relatedCandidatures {
+		for _, rc := range relatedCandidatures.Candidatures {
 			if rc.SequencialCandidate != id {
 				var tags []string
 				for _, p := range rc.Proposals {
@@ -103,7 +74,7 @@ func newCandidateHandler(db *db.Client) echo.HandlerFunc {
 				}
 				relatedCandidatesCards = append(relatedCandidatesCards, &candidateCard{
 					Transparency: rc.Transparency,
-					Picture:      rc.PhotoURL,
+					Picture:      photoRenditionURL(rc.PhotoURL, photoCardSize),
 					Name:         rc.BallotName,
 					City:         rc.City,
 					State:        rc.State,
@@ -116,11 +87,21 @@ func newCandidateHandler(db *db.Client) echo.HandlerFunc {
 				})
 			}
 		}
+		// Vice-prefeitos are not voted on their own, so they can not be added to
+		// the cola.
+		ballotRole := candidate.Role
+		_, onBallot := ballotOrder[ballotRole]
 		candidate.Role = strings.Title(candidate.Role) + "(a)"
 		r := c.Render(http.StatusOK, "candidato.html", map[string]interface{}{
 			"Candidato":         candidate,
+			"Contacts":          contacts,
 			"RelatedCandidates": relatedCandidatesCards,
 			"ReqProposalEmail":  email,
+			"ReportCategories":  reportCategoriesUI,
+			"MaxReportTextSize": maxReportTextSize,
+			"OnBallot":          onBallot,
+			"BallotRole":        ballotRole,
+			"LocationURL":       locationURL(candidate.Year, candidate.State, candidate.City),
 		})
 		fmt.Println(r)
 		return r
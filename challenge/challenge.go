@@ -0,0 +1,111 @@
+// Package challenge implements a lightweight proof-of-work used to make
+// automated form submissions expensive, without external captcha services.
+//
+// The server issues a signed challenge. Before submitting the form, the
+// browser must find a nonce such that SHA-256(challenge + ":" + nonce) starts
+// with a given number of zero bits.
+package challenge
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned when verifying a solution.
+var (
+	ErrInvalid  = errors.New("invalid challenge")
+	ErrExpired  = errors.New("expired challenge")
+	ErrUnsolved = errors.New("challenge not solved")
+)
+
+// Service issues and verifies challenges.
+type Service struct {
+	secret     []byte
+	difficulty int
+	maxAge     time.Duration
+}
+
+// New returns a new challenge service. Difficulty is the number of leading
+// zero bits required, each bit doubles the average work of the client.
+// Challenges older than maxAge are rejected.
+func New(secret string, difficulty int, maxAge time.Duration) *Service {
+	return &Service{
+		secret:     []byte(secret),
+		difficulty: difficulty,
+		maxAge:     maxAge,
+	}
+}
+
+// Difficulty returns the number of leading zero bits required.
+func (s *Service) Difficulty() int {
+	return s.difficulty
+}
+
+// Issue returns a new challenge in the format <unix time>.<random>.<signature>.
+func (s *Service) Issue(now time.Time) (string, error) {
+	var r [12]byte
+	if _, err := crand.Read(r[:]); err != nil {
+		return "", fmt.Errorf("failed to generate challenge, error %v", err)
+	}
+	payload := strconv.FormatInt(now.Unix(), 10) + "." + hex.EncodeToString(r[:])
+	return payload + "." + s.sign(payload), nil
+}
+
+// Verify checks the challenge was issued by the service, is not expired and
+// was solved by the nonce.
+func (s *Service) Verify(challenge, nonce string, now time.Time) error {
+	i := strings.LastIndex(challenge, ".")
+	if i < 0 || !hmac.Equal([]byte(challenge[i+1:]), []byte(s.sign(challenge[:i]))) {
+		return ErrInvalid
+	}
+	issuedAt, err := strconv.ParseInt(challenge[:strings.Index(challenge, ".")], 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	if age := now.Sub(time.Unix(issuedAt, 0)); age < 0 || age > s.maxAge {
+		return ErrExpired
+	}
+	if LeadingZeroBits(challenge, nonce) < s.difficulty {
+		return ErrUnsolved
+	}
+	return nil
+}
+
+func (s *Service) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("challenge:" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LeadingZeroBits returns the number of leading zero bits of
+// SHA-256(challenge + ":" + nonce).
+func LeadingZeroBits(challenge, nonce string) int {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Solve finds a nonce that solves the challenge with the difficulty. It does
+// the same work expected from browsers.
+func Solve(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		if LeadingZeroBits(challenge, nonce) >= difficulty {
+			return nonce
+		}
+	}
+}
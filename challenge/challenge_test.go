@@ -0,0 +1,66 @@
+package challenge
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const secret = "test secret"
+
+func TestVerify(t *testing.T) {
+	s := New(secret, 8, time.Minute)
+	now := time.Now()
+	c, err := s.Issue(now)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	nonce := Solve(c, 8)
+	if err := s.Verify(c, nonce, now.Add(time.Second)); err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	unsolved := nonce
+	for LeadingZeroBits(c, unsolved) >= 8 {
+		unsolved += "x"
+	}
+	parts := strings.Split(c, ".")
+	testCases := []struct {
+		name      string
+		challenge string
+		nonce     string
+		now       time.Time
+		want      error
+	}{
+		{"unsolved", c, unsolved, now, ErrUnsolved},
+		{"expired", c, nonce, now.Add(2 * time.Minute), ErrExpired},
+		{"from the future", c, nonce, now.Add(-time.Minute), ErrExpired},
+		{"tampered time", "1." + parts[1] + "." + parts[2], nonce, now, ErrInvalid},
+		{"other secret", mustIssue(t, New("other", 8, time.Minute), now), nonce, now, ErrInvalid},
+		{"malformed", "abc", nonce, now, ErrInvalid},
+		{"empty", "", "", now, ErrInvalid},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Verify(tt.challenge, tt.nonce, tt.now); err != tt.want {
+				t.Errorf("want error %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	c := "challenge"
+	for difficulty := 0; difficulty <= 12; difficulty += 4 {
+		if n := LeadingZeroBits(c, Solve(c, difficulty)); n < difficulty {
+			t.Errorf("want at least %d zero bits, got %d", difficulty, n)
+		}
+	}
+}
+
+func mustIssue(t *testing.T, s *Service, now time.Time) string {
+	c, err := s.Issue(now)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	return c
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/pdf"
+	"github.com/labstack/echo"
+)
+
+// The cola eleitoral is a printable list of the ballot numbers chosen by the
+// voter, who can not take a phone into the voting booth. Choices are kept in
+// the browser and sent as repeated id parameters; the server only renders
+// them, as HTML at /cola and as PDF at /cola.pdf.
+
+const (
+	maxColaEntries = 10
+	colaIDParam    = "id"
+)
+
+// ballotOrder is the order in which the roles are voted in the ballot box.
+// Other roles, such as vice-prefeito, are not voted on their own.
+var ballotOrder = map[string]int{"vereador": 0, "prefeito": 1}
+
+type colaEntry struct {
+	SequentialID string
+	Name         string
+	Party        string
+	Role         string
+	Number       int
+	City         string
+	State        string
+}
+
+// parseColaQuery returns the election year and the sequential IDs of the
+// cola, without repetitions.
+func parseColaQuery(c echo.Context) (int, []string, error) {
+	year := globals.Year
+	if v := c.QueryParam("ano"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, nil, exception.New(exception.InvalidParameters, "Ano fornecido é inválido.", nil)
+		}
+		year = y
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range c.QueryParams()[colaIDParam] {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) > maxColaEntries {
+		return 0, nil, exception.New(exception.InvalidParameters, fmt.Sprintf("A cola pode ter no máximo %d candidaturas.", maxColaEntries), nil)
+	}
+	return year, ids, nil
+}
+
+// findCola returns the entries of the cola in the order they are voted,
+// grouped by city. Only one prefeito and one vereador are accepted for each
+// city.
+func findCola(dbClient db.Store, year int, ids []string) ([]*colaEntry, error) {
+	var entries []*colaEntry
+	chosen := make(map[string]bool)
+	for _, id := range ids {
+		candidate, err := findPublicCandidate(dbClient, year, id)
+		if err != nil {
+			if e, ok := err.(*exception.Exception); ok && e.Code == exception.NotFound {
+				return nil, exception.New(exception.NotFound, fmt.Sprintf("Candidatura %s não encontrada.", id), nil)
+			}
+			return nil, err
+		}
+		if _, ok := ballotOrder[candidate.Role]; !ok {
+			return nil, exception.New(exception.InvalidParameters, "Apenas candidaturas a prefeito(a) e vereador(a) podem entrar na cola.", nil)
+		}
+		key := strings.Join([]string{candidate.State, candidate.City, candidate.Role}, "|")
+		if chosen[key] {
+			return nil, exception.New(exception.InvalidParameters, fmt.Sprintf("A cola pode ter apenas um(a) %s por cidade.", strings.ToLower(uiRoles[candidate.Role])), nil)
+		}
+		chosen[key] = true
+		entries = append(entries, &colaEntry{
+			SequentialID: candidate.SequencialCandidate,
+			Name:         candidate.BallotName,
+			Party:        candidate.Party,
+			Role:         candidate.Role,
+			Number:       candidate.BallotNumber,
+			City:         strings.Title(strings.ToLower(candidate.City)),
+			State:        candidate.State,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		if a.City != b.City {
+			return a.City < b.City
+		}
+		return ballotOrder[a.Role] < ballotOrder[b.Role]
+	})
+	return entries, nil
+}
+
+// queryCola returns the year and the entries of the cola in the query string.
+// Invalid parameters and missing candidatures are returned as an
+// exception.Exception.
+func queryCola(c echo.Context, dbClient db.Store) (int, []*colaEntry, error) {
+	year, ids, err := parseColaQuery(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	entries, err := findCola(dbClient, year, ids)
+	return year, entries, err
+}
+
+// colaURL returns the URL of the cola of the entries, with the given suffix
+// for the format, as in colaURL(2020, entries, ".pdf").
+func colaURL(year int, entries []*colaEntry, suffix string) string {
+	v := url.Values{}
+	v.Set("ano", strconv.Itoa(year))
+	for _, e := range entries {
+		v.Add(colaIDParam, e.SequentialID)
+	}
+	return "/cola" + suffix + "?" + v.Encode()
+}
+
+// colaError returns err as an exception.Exception, logging and hiding
+// unexpected errors behind a generic message.
+func colaError(c echo.Context, err error) *exception.Exception {
+	e, ok := err.(*exception.Exception)
+	if !ok {
+		log.Printf("failed to find candidatures of the cola (%s), error %v\n", c.Request().URL, err)
+		e = &exception.Exception{Code: exception.Unknown, Message: "Erro inesperado. Por favor, tente novamente mais tarde."}
+	}
+	return e
+}
+
+// GET /cola shows the cola ready to be printed. Without candidatures, it
+// explains how to choose them.
+func newColaHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		year, entries, err := queryCola(c, dbClient)
+		if err != nil {
+			e := colaError(c, err)
+			return c.Render(e.Code, "cola.html", map[string]interface{}{
+				"ErrorMsg": e.Message,
+			})
+		}
+		data := map[string]interface{}{
+			"ElectionYear": year,
+			"Entries":      entries,
+			"Roles":        uiRoles,
+		}
+		if len(entries) > 0 {
+			data["PDFURL"] = colaURL(year, entries, ".pdf")
+		}
+		return c.Render(http.StatusOK, "cola.html", data)
+	}
+}
+
+// GET /cola.pdf returns the cola as a PDF document.
+func newColaPDFHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		year, entries, err := queryCola(c, dbClient)
+		if err != nil {
+			e := colaError(c, err)
+			return c.String(e.Code, e.Message)
+		}
+		if len(entries) == 0 {
+			return c.String(http.StatusBadRequest, "Escolha ao menos uma candidatura para a cola.")
+		}
+		var buf bytes.Buffer
+		if _, err := writeColaPDF(&buf, year, entries); err != nil {
+			log.Printf("failed to write cola pdf (%s), error %v\n", c.Request().URL, err)
+			return c.String(http.StatusInternalServerError, "Erro inesperado. Por favor, tente novamente mais tarde.")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="cola-eleitoral.pdf"`)
+		return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+	}
+}
+
+// writeColaPDF writes the cola as a single column list, one block for each
+// city, in the order the roles are voted. Every page has the footer.
+func writeColaPDF(w io.Writer, year int, entries []*colaEntry) (int64, error) {
+	const (
+		margin     = 56.0
+		entryWidth = pdf.PageWidth - 2*margin
+	)
+	d := pdf.New()
+	newPage := func() {
+		d.AddPage()
+		d.Text(margin, pdf.PageHeight-margin/2, pdf.Regular, 8, "Gerada em candidatos.info")
+	}
+	newPage()
+	y := margin
+	d.Text(margin, y, pdf.Bold, 20, fmt.Sprintf("Cola eleitoral - Eleições %d", year))
+	y += 14
+	d.Text(margin, y, pdf.Regular, 9, "Leve esta cola impressa para votar. Celulares não são permitidos na cabine de votação.")
+	city := ""
+	for _, e := range entries {
+		if y+100 > pdf.PageHeight-margin {
+			newPage()
+			y = margin
+			city = ""
+		}
+		if c := e.City + "/" + e.State; c != city {
+			city = c
+			y += 36
+			d.Text(margin, y, pdf.Bold, 14, city)
+			y += 6
+			d.Line(margin, y, margin+entryWidth, y, 1)
+		}
+		y += 22
+		d.Text(margin, y, pdf.Regular, 10, uiRoles[e.Role])
+		y += 16
+		d.Text(margin, y, pdf.Bold, 12, fmt.Sprintf("%s (%s)", e.Name, e.Party))
+		d.Text(margin+entryWidth-110, y, pdf.Bold, 28, strconv.Itoa(e.Number))
+		y += 12
+		d.Line(margin, y, margin+entryWidth, y, 0.25)
+	}
+	return d.WriteTo(w)
+}
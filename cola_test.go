@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+func TestCola(t *testing.T) {
+	e, _ := newTestServer(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/c/2020/20000000001"); !strings.Contains(rec.Body.String(), "Adicionar à cola") {
+		t.Errorf("want button to add the candidature to the cola")
+	}
+	if rec := get("/cola"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Adicionar à cola") {
+		t.Errorf("want instructions without candidatures, got status %d", rec.Code)
+	}
+
+	rec := get("/cola?ano=2020&id=20000000005&id=20000000003&id=20000000001")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	maria, antonio, pedro := strings.Index(body, "PROFESSORA MARIA JOSÉ"), strings.Index(body, "DR. ANTÔNIO"), strings.Index(body, "PEDRO DA BICICLETA")
+	if maria < 0 || antonio < maria || pedro < antonio {
+		t.Errorf("want candidatures grouped by city in the order they are voted, got %d %d %d", maria, antonio, pedro)
+	}
+	if !strings.Contains(body, "/cola.pdf?ano=2020&amp;id=20000000001&amp;id=20000000003&amp;id=20000000005") {
+		t.Errorf("want link to the PDF, got %q", body)
+	}
+
+	testCases := []struct {
+		target string
+		code   int
+	}{
+		{"/cola?ano=2020&id=20000000001&id=20000000002", http.StatusBadRequest},
+		{"/cola?ano=dois&id=20000000001", http.StatusBadRequest},
+		{"/cola?ano=2020&id=99999999999", http.StatusNotFound},
+		{"/cola.pdf?ano=2020", http.StatusBadRequest},
+		{"/cola.pdf?ano=2020&id=20000000001&id=20000000002", http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		if rec := get(tc.target); rec.Code != tc.code {
+			t.Errorf("want status %d for %s, got %d", tc.code, tc.target, rec.Code)
+		}
+	}
+
+	rec = get("/cola.pdf?ano=2020&id=20000000001&id=20000000003")
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != "application/pdf" {
+		t.Fatalf("want PDF, got status %d and %q", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+	if out := rec.Body.Bytes(); !bytes.HasPrefix(out, []byte("%PDF-")) || !bytes.Contains(out, []byte("(PROFESSORA MARIA JOS\xc9 \\(PARTIDO A\\))")) {
+		t.Errorf("want PDF with the candidatures, got %q", out)
+	}
+}
+
+func TestWriteColaPDFFooterOnEveryPage(t *testing.T) {
+	var entries []*colaEntry
+	for i := 0; i < maxColaEntries; i++ {
+		entries = append(entries, &colaEntry{Name: "MARIA", Party: "PARTIDO A", Role: "vereador", Number: 12345 + i, City: fmt.Sprintf("Cidade %d", i), State: "AL"})
+	}
+	var buf bytes.Buffer
+	if _, err := writeColaPDF(&buf, 2020, entries); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	out := buf.String()
+	pages := regexp.MustCompile(`/Count (\d+)`).FindStringSubmatch(out)
+	if pages == nil || pages[1] == "1" {
+		t.Fatalf("want several pages, got %v", pages)
+	}
+	if n := strings.Count(out, "(Gerada em candidatos.info) Tj"); strconv.Itoa(n) != pages[1] {
+		t.Errorf("want footer on each of the %s pages, got %d", pages[1], n)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+// The comparison page shows candidatures of the same election side by side,
+// with the proposals grouped by topic so they line up. Candidatures are passed
+// as repeated id parameters, as in /comparar?ano=2020&id=...&id=..., and are
+// picked on the home page.
+
+const (
+	minComparedCandidates = 2
+	maxComparedCandidates = 4
+	compareIDParam        = "id"
+)
+
+// comparedTopic holds the proposals of each compared candidature for a topic,
+// in the order of the candidatures.
+type comparedTopic struct {
+	Topic     string     `json:"topic"`
+	Proposals [][]string `json:"proposals"`
+}
+
+type comparison struct {
+	Year       int
+	Candidates []*descritor.CandidateForDB
+	Topics     []*comparedTopic
+}
+
+// comparedCandidate is a column of the comparison page.
+type comparedCandidate struct {
+	Card      *candidateCard
+	Biography string
+	Contacts  []*contactLink
+	RemoveURL string
+}
+
+// parseComparisonQuery returns the election year and the sequential IDs to
+// be compared, without repetitions.
+func parseComparisonQuery(c echo.Context) (int, []string, error) {
+	year := globals.Year
+	if v := c.QueryParam("ano"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, nil, exception.New(exception.InvalidParameters, "Ano fornecido é inválido.", nil)
+		}
+		year = y
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range c.QueryParams()[compareIDParam] {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	switch {
+	case len(ids) < minComparedCandidates:
+		return 0, nil, exception.New(exception.InvalidParameters, fmt.Sprintf("Escolha ao menos %d candidaturas para comparar.", minComparedCandidates), nil)
+	case len(ids) > maxComparedCandidates:
+		return 0, nil, exception.New(exception.InvalidParameters, fmt.Sprintf("É possível comparar no máximo %d candidaturas.", maxComparedCandidates), nil)
+	}
+	return year, ids, nil
+}
+
+// findComparison returns the candidatures as shown to voters, with their
+// proposals grouped by topic in the order topics first appear.
+func findComparison(dbClient db.Store, year int, ids []string) (*comparison, error) {
+	cmp := &comparison{Year: year}
+	for _, id := range ids {
+		candidate, err := findPublicCandidate(dbClient, year, id)
+		if err != nil {
+			if e, ok := err.(*exception.Exception); ok && e.Code == exception.NotFound {
+				return nil, exception.New(exception.NotFound, fmt.Sprintf("Candidatura %s não encontrada.", id), nil)
+			}
+			return nil, err
+		}
+		cmp.Candidates = append(cmp.Candidates, candidate)
+	}
+	topics := make(map[string]*comparedTopic)
+	for i, candidate := range cmp.Candidates {
+		for _, p := range candidate.Proposals {
+			t, ok := topics[p.Topic]
+			if !ok {
+				t = &comparedTopic{Topic: p.Topic, Proposals: make([][]string, len(cmp.Candidates))}
+				topics[p.Topic] = t
+				cmp.Topics = append(cmp.Topics, t)
+			}
+			t.Proposals[i] = append(t.Proposals[i], p.Description)
+		}
+	}
+	return cmp, nil
+}
+
+// queryComparison returns the comparison of the candidatures in the query
+// string. Invalid parameters and missing candidatures are returned as an
+// exception.Exception.
+func queryComparison(c echo.Context, dbClient db.Store) (*comparison, error) {
+	year, ids, err := parseComparisonQuery(c)
+	if err != nil {
+		return nil, err
+	}
+	return findComparison(dbClient, year, ids)
+}
+
+// comparisonURL returns the URL of the comparison of the candidatures.
+func comparisonURL(year int, ids []string) string {
+	v := url.Values{}
+	v.Set("ano", strconv.Itoa(year))
+	v[compareIDParam] = ids
+	return "/comparar?" + v.Encode()
+}
+
+// GET /comparar shows the candidatures side by side.
+func newCompararHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cmp, err := queryComparison(c, dbClient)
+		if err != nil {
+			e, ok := err.(*exception.Exception)
+			if !ok {
+				log.Printf("failed to find candidatures to compare (%s), error %v\n", c.Request().URL, err)
+				e = &exception.Exception{Code: exception.Unknown, Message: "Erro inesperado. Por favor, tente novamente mais tarde."}
+			}
+			return c.Render(e.Code, "comparar.html", map[string]interface{}{
+				"ErrorMsg": e.Message,
+			})
+		}
+		var ids []string
+		for _, candidate := range cmp.Candidates {
+			ids = append(ids, candidate.SequencialCandidate)
+		}
+		var columns []*comparedCandidate
+		for i, candidate := range cmp.Candidates {
+			col := &comparedCandidate{
+				Card:      newCandidateCard(candidate),
+				Biography: candidate.Biography,
+				Contacts:  contactLinks(candidate.Contacts),
+			}
+			if len(ids) > minComparedCandidates {
+				others := append(append([]string{}, ids[:i]...), ids[i+1:]...)
+				col.RemoveURL = comparisonURL(cmp.Year, others)
+			}
+			columns = append(columns, col)
+		}
+		return c.Render(http.StatusOK, "comparar.html", map[string]interface{}{
+			"ElectionYear": cmp.Year,
+			"Candidates":   columns,
+			"Topics":       cmp.Topics,
+			"JSONURL":      "/api/v1" + comparisonURL(cmp.Year, ids),
+		})
+	}
+}
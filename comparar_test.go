@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComparar(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/comparar?ano=2020&id=20000000001&id=20000000003&id=20000000001", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"PROFESSORA MARIA JOSÉ", "DR. ANTÔNIO", "Direitos das Mulheres", "Saneamento Básico", "Sem proposta"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("want %q in the comparison", want)
+		}
+	}
+	if strings.Contains(body, "Remover da comparação") {
+		t.Errorf("want no remove links when comparing only 2 candidatures")
+	}
+
+	testCases := []struct {
+		target string
+		code   int
+	}{
+		{"/comparar?ano=2020&id=20000000001", http.StatusBadRequest},
+		{"/comparar?ano=2020&id=1&id=2&id=3&id=4&id=5", http.StatusBadRequest},
+		{"/comparar?ano=dois&id=20000000001&id=20000000003", http.StatusBadRequest},
+		{"/comparar?ano=2020&id=20000000001&id=99999999999", http.StatusNotFound},
+		{"/comparar?ano=2016&id=20000000001&id=20000000003", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.code {
+			t.Errorf("want status %d for %s, got %d", tc.code, tc.target, rec.Code)
+		}
+	}
+}
+
+func TestAPIComparar(t *testing.T) {
+	e, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/comparar?ano=2020&id=20000000001&id=20000000005&id=20000000002", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var resp struct {
+		Candidates []*candidateCard `json:"candidates"`
+		Topics     []*comparedTopic `json:"topics"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(resp.Candidates) != 3 || resp.Candidates[1].SequentialID != "20000000005" {
+		t.Fatalf("want candidatures in the requested order, got %+v", resp.Candidates)
+	}
+	if len(resp.Topics) != 3 || resp.Topics[0].Topic != "Educação" {
+		t.Fatalf("want 3 topics starting with Educação, got %+v", resp.Topics)
+	}
+	p := resp.Topics[0].Proposals
+	if len(p) != 3 || len(p[0]) != 1 || len(p[1]) != 1 || p[2] == nil || len(p[2]) != 0 {
+		t.Errorf("want Educação proposals aligned with the candidatures, got %v", p)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/comparar?ano=2020&id=20000000001", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":400`) {
+		t.Errorf("want JSON error with status %d, got %d %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
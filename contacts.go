@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/candidatos-info/descritor"
+)
+
+// Contacts are validated and stored in a canonical form for each network:
+// handles without @ for Instagram and Twitter, https URLs for Facebook and
+// websites, E.164 numbers (+55...) for phones and WhatsApp and lower case
+// emails. Links are built from the canonical form, contacts stored before
+// validation existed are normalized the same way when shown.
+
+// maxContacts is the maximum number of contacts of a profile.
+const maxContacts = 7
+
+var (
+	instagramHandleRegex = regexp.MustCompile(`^[A-Za-z0-9._]{1,30}$`)
+	twitterHandleRegex   = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+	facebookPathRegex    = regexp.MustCompile(`^/[A-Za-z0-9.-]+(/[A-Za-z0-9.-]+)*$`)
+	facebookPageRegex    = regexp.MustCompile(`^[A-Za-z0-9.]{5,50}$`)
+	hostnameRegex        = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+	digitsRegex          = regexp.MustCompile(`^[0-9]+$`)
+
+	// Brazilian area codes (DDD).
+	validDDDs = map[string]bool{
+		"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+		"21": true, "22": true, "24": true, "27": true, "28": true,
+		"31": true, "32": true, "33": true, "34": true, "35": true, "37": true, "38": true,
+		"41": true, "42": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+		"51": true, "53": true, "54": true, "55": true,
+		"61": true, "62": true, "63": true, "64": true, "65": true, "66": true, "67": true, "68": true, "69": true,
+		"71": true, "73": true, "74": true, "75": true, "77": true, "79": true,
+		"81": true, "82": true, "83": true, "84": true, "85": true, "86": true, "87": true, "88": true, "89": true,
+		"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true, "98": true, "99": true,
+	}
+)
+
+// normalizeContact validates the value of a contact of the network and
+// returns its canonical form. The error message can be shown to candidates.
+func normalizeContact(network, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if _, ok := socialNetworksUI[network]; !ok {
+		return "", errors.New("rede social inválida")
+	}
+	if utf8.RuneCountInString(value) > maxContactsTextSize {
+		return "", fmt.Errorf("o tamanho máximo é de %d caracteres", maxContactsTextSize)
+	}
+	switch network {
+	case "instagram":
+		if h := socialHandle(value, "instagram.com"); instagramHandleRegex.MatchString(h) {
+			return h, nil
+		}
+		return "", errors.New("informe o usuário do Instagram, por exemplo @candidato")
+	case "twitter":
+		if h := socialHandle(value, "twitter.com"); twitterHandleRegex.MatchString(h) {
+			return h, nil
+		}
+		return "", errors.New("informe o usuário do Twitter, por exemplo @candidato")
+	case "facebook":
+		if u, ok := normalizeFacebook(value); ok {
+			return u, nil
+		}
+		return "", errors.New("informe o endereço da página no Facebook, por exemplo facebook.com/candidato")
+	case "telefone", "whatsapp":
+		if p, ok := normalizePhone(value); ok {
+			return p, nil
+		}
+		return "", errors.New("informe um telefone brasileiro com DDD, por exemplo (82) 99999-9999")
+	case "email":
+		if v := strings.ToLower(value); emailRegex.MatchString(v) {
+			return v, nil
+		}
+		return "", errors.New("informe um email válido, por exemplo candidato@exemplo.com")
+	case "paginaWeb":
+		if u, ok := normalizeWebsite(value); ok {
+			return u, nil
+		}
+		return "", errors.New("informe um endereço https válido, por exemplo https://candidato.com.br")
+	}
+	return value, nil
+}
+
+// socialHandle extracts the handle from values like @candidato, candidato or
+// https://www.host/candidato/.
+func socialHandle(value, host string) string {
+	v := value
+	lower := strings.ToLower(v)
+	for _, prefix := range []string{"https://", "http://", "www.", host + "/"} {
+		if strings.HasPrefix(lower, prefix) {
+			v, lower = v[len(prefix):], lower[len(prefix):]
+		}
+	}
+	if i := strings.IndexAny(v, "?#"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(v, "/"), "@")
+}
+
+// normalizeFacebook returns the https URL of Facebook pages and profiles,
+// given either their URL or the page name.
+func normalizeFacebook(value string) (string, bool) {
+	if facebookPageRegex.MatchString(value) && !strings.Contains(strings.ToLower(value), "facebook.com") {
+		return "https://www.facebook.com/" + value, true
+	}
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil || u.Port() != "" {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "facebook.com" && !strings.HasSuffix(host, ".facebook.com") && host != "fb.com" {
+		return "", false
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "/profile.php" {
+		id := u.Query().Get("id")
+		if !digitsRegex.MatchString(id) {
+			return "", false
+		}
+		return "https://www.facebook.com/profile.php?id=" + id, true
+	}
+	if !facebookPathRegex.MatchString(path) {
+		return "", false
+	}
+	return "https://www.facebook.com" + path, true
+}
+
+// normalizePhone returns Brazilian phone numbers in the E.164 format, like
+// +5582999999999. Numbers must have the area code and may have the country
+// code, the trunk prefix 0, spaces, dots, dashes and parentheses.
+func normalizePhone(value string) (string, bool) {
+	var digits strings.Builder
+	international := false
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			international = true
+		case strings.ContainsRune(" ().-", r):
+		default:
+			return "", false
+		}
+	}
+	d := digits.String()
+	switch {
+	case international || len(d) > 11:
+		if !strings.HasPrefix(d, "55") {
+			return "", false
+		}
+		d = d[2:]
+	case strings.HasPrefix(d, "0"):
+		d = d[1:]
+	}
+	if len(d) < 10 || !validDDDs[d[:2]] {
+		return "", false
+	}
+	switch {
+	case len(d) == 11 && d[2] == '9': // mobile.
+	case len(d) == 10 && d[2] >= '2' && d[2] <= '5': // landline.
+	default:
+		return "", false
+	}
+	return "+55" + d, true
+}
+
+// normalizeWebsite returns the https URL of websites. Addresses without a
+// scheme are assumed to use https.
+func normalizeWebsite(value string) (string, bool) {
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme != "https" || u.User != nil || u.Port() != "" || u.Opaque != "" {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if !hostnameRegex.MatchString(host) {
+		return "", false
+	}
+	u.Host = host
+	return u.String(), true
+}
+
+// contactURL returns the link to the contact, empty if the value is not
+// valid for the network.
+func contactURL(c *descritor.Contact) string {
+	v, err := normalizeContact(c.SocialNetwork, c.Value)
+	if err != nil {
+		return ""
+	}
+	switch c.SocialNetwork {
+	case "instagram":
+		return "https://www.instagram.com/" + v
+	case "twitter":
+		return "https://twitter.com/" + v
+	case "telefone":
+		return "tel:" + v
+	case "whatsapp":
+		return "https://wa.me/" + strings.TrimPrefix(v, "+")
+	case "email":
+		return "mailto:" + v
+	}
+	return v
+}
+
+// contactLink is a contact shown in the candidate page.
+type contactLink struct {
+	SocialNetwork string
+	URL           string
+}
+
+// contactLinks returns the links to the contacts, skipping the invalid ones.
+func contactLinks(contacts []*descritor.Contact) []*contactLink {
+	var links []*contactLink
+	for _, c := range contacts {
+		if u := contactURL(c); u != "" {
+			links = append(links, &contactLink{SocialNetwork: c.SocialNetwork, URL: u})
+		}
+	}
+	return links
+}
+
+// parseContacts validates the contacts sent as lists of networks and values,
+// ignoring empty values. Invalid contacts are added to errs by their
+// position in the form.
+func parseContacts(networks, values []string, errs *formErrors) []*descritor.Contact {
+	var contacts []*descritor.Contact
+	seen := make(map[string]bool)
+	for i := 0; i < len(networks) && i < len(values); i++ {
+		if strings.TrimSpace(values[i]) == "" {
+			continue
+		}
+		v, err := normalizeContact(networks[i], values[i])
+		if err != nil {
+			label := socialNetworksUI[networks[i]]
+			if label == "" {
+				label = networks[i]
+			}
+			errs.add(contactField(i), fmt.Sprintf("Contato %d (%s): %s.", i+1, label, err))
+			continue
+		}
+		key := networks[i] + " " + v
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		contacts = append(contacts, &descritor.Contact{SocialNetwork: networks[i], Value: v})
+	}
+	if len(contacts) > maxContacts {
+		errs.add("contacts", fmt.Sprintf("O número máximo de contatos é %d.", maxContacts))
+	}
+	return contacts
+}
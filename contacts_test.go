@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/candidatos-info/descritor"
+)
+
+func TestNormalizeContact(t *testing.T) {
+	testCases := []struct {
+		network string
+		value   string
+		want    string // empty when invalid.
+	}{
+		{"instagram", "@professoramariajose", "professoramariajose"},
+		{"instagram", "https://www.instagram.com/maria.jose_/", "maria.jose_"},
+		{"instagram", "maria jose", ""},
+		{"twitter", "@pedrodabike", "pedrodabike"},
+		{"twitter", "twitter.com/pedrodabike?lang=pt", "pedrodabike"},
+		{"twitter", "pedro.da.bike", ""},
+		{"facebook", "mariajose.oficial", "https://www.facebook.com/mariajose.oficial"},
+		{"facebook", "http://m.facebook.com/mariajose.oficial/", "https://www.facebook.com/mariajose.oficial"},
+		{"facebook", "facebook.com/profile.php?id=1000123", "https://www.facebook.com/profile.php?id=1000123"},
+		{"facebook", "https://exemplo.com/mariajose", ""},
+		{"whatsapp", "(82) 99999-8888", "+5582999998888"},
+		{"whatsapp", "+55 82 99999 8888", "+5582999998888"},
+		{"telefone", "082 3333-4444", "+558233334444"},
+		{"telefone", "(20) 99999-8888", ""},
+		{"telefone", "99999-8888", ""},
+		{"telefone", "+1 415 555 0100", ""},
+		{"email", "Maria.Jose@Exemplo.com", "maria.jose@exemplo.com"},
+		{"email", "maria.jose", ""},
+		{"paginaWeb", "drantonio.com.br", "https://drantonio.com.br"},
+		{"paginaWeb", "https://DrAntonio.com.br/propostas", "https://drantonio.com.br/propostas"},
+		{"paginaWeb", "http://drantonio.com.br", ""},
+		{"paginaWeb", "javascript:alert(1)", ""},
+		{"orkut", "mariajose", ""},
+	}
+	for _, tc := range testCases {
+		got, err := normalizeContact(tc.network, tc.value)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("want %s %q rejected, got %q", tc.network, tc.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("want %s %q normalized to %q, got %q (error %v)", tc.network, tc.value, tc.want, got, err)
+		}
+	}
+}
+
+func TestContactURL(t *testing.T) {
+	testCases := []struct {
+		contact *descritor.Contact
+		want    string
+	}{
+		{&descritor.Contact{SocialNetwork: "instagram", Value: "professoramariajose"}, "https://www.instagram.com/professoramariajose"},
+		{&descritor.Contact{SocialNetwork: "twitter", Value: "pedrodabike"}, "https://twitter.com/pedrodabike"},
+		{&descritor.Contact{SocialNetwork: "whatsapp", Value: "+5582999998888"}, "https://wa.me/5582999998888"},
+		{&descritor.Contact{SocialNetwork: "telefone", Value: "+558233334444"}, "tel:+558233334444"},
+		{&descritor.Contact{SocialNetwork: "email", Value: "maria.jose@exemplo.com"}, "mailto:maria.jose@exemplo.com"},
+		{&descritor.Contact{SocialNetwork: "paginaWeb", Value: "drantonio.com.br"}, "https://drantonio.com.br"},
+		{&descritor.Contact{SocialNetwork: "paginaWeb", Value: "javascript:alert(1)"}, ""},
+	}
+	for _, tc := range testCases {
+		if got := contactURL(tc.contact); got != tc.want {
+			t.Errorf("want %s, got %s", tc.want, got)
+		}
+	}
+}
+
+func TestUpdateContacts(t *testing.T) {
+	e, store := newTestServer(t)
+	e.POST("/atualizar-candidatura", newAtualizarCandidaturaFormHandler(store, nil, false, false), newCandidateAuthMiddleware(store, tokenService))
+	accessToken := loginTestCandidate(t, e, store, "maria.jose@exemplo.com", "20000000001")
+	postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}})
+	submit := func(networks, values []string) *httptest.ResponseRecorder {
+		return postForm(t, e, "/atualizar-candidatura", url.Values{
+			"access_token":                 {accessToken},
+			"numTags":                      {"1"},
+			"descriptions[0][tag]":         {"Educação"},
+			"descriptions[0][description]": {"Creches em todos os bairros."},
+			"biography":                    {"Professora da rede pública há 15 anos."},
+			"provider":                     networks,
+			"contact":                      values,
+		})
+	}
+
+	rec := submit([]string{"instagram", "whatsapp", "paginaWeb"}, []string{"@mariajose", "(82) 9999-8888", "http://mariajose.com.br"})
+	body := rec.Body.String()
+	if rec.Code != http.StatusBadRequest || !strings.Contains(body, "Contato 2 (Whatsapp)") || !strings.Contains(body, "Contato 3 (Página Web)") || strings.Contains(body, "Contato 1") {
+		t.Errorf("want errors for the second and third contacts, got status %d", rec.Code)
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.Contacts[0].Value != "professoramariajose" {
+		t.Errorf("want contacts unchanged after invalid values, got %s", formatContacts(candidate.Contacts))
+	}
+	if rec := submit([]string{"email", "twitter"}, []string{"", " "}); !strings.Contains(rec.Body.String(), "Informe ao menos um contato.") || strings.Contains(rec.Body.String(), "sucesso") {
+		t.Errorf("want at least one contact required")
+	}
+
+	rec = submit([]string{"instagram", "whatsapp", "email", "twitter"}, []string{"@mariajose", "(82) 99999-8888", "Maria.Jose@Exemplo.com", ""})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "sucesso") {
+		t.Fatalf("want contacts updated, got status %d", rec.Code)
+	}
+	candidate, err := store.FindCandidateBySequencialIDAndYear(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if got := formatContacts(candidate.Contacts); got != formatContacts([]*descritor.Contact{
+		{SocialNetwork: "instagram", Value: "mariajose"},
+		{SocialNetwork: "whatsapp", Value: "+5582999998888"},
+		{SocialNetwork: "email", Value: "maria.jose@exemplo.com"},
+	}) {
+		t.Errorf("want normalized contacts, got %s", got)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/c/2020/20000000001", nil))
+	for _, link := range []string{"https://www.instagram.com/mariajose", "https://wa.me/5582999998888", "mailto:maria.jose@exemplo.com"} {
+		if !strings.Contains(rec.Body.String(), link) {
+			t.Errorf("want link %s in the candidate page", link)
+		}
+	}
+}
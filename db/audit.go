@@ -0,0 +1,55 @@
+package db
+
+import "time"
+
+// AuditCollection is the name of the collection of audit entries, which
+// record every change made to candidate profiles and to the campaign
+// calendar.
+const AuditCollection = "audit"
+
+// Actions recorded in the audit trail.
+const (
+	AuditUpdateProfile  = "perfil-atualizado"
+	AuditAcceptTerms    = "termo-aceito"
+	AuditResetTerms     = "termo-resetado"
+	AuditResendAccess   = "acesso-reenviado"
+	AuditRevokeSessions = "sessoes-revogadas"
+	AuditDisableProfile = "perfil-desativado"
+	AuditEnableProfile  = "perfil-reativado"
+	AuditRollback       = "perfil-restaurado"
+	AuditApproveProfile = "alteracao-aprovada"
+	AuditRejectProfile  = "alteracao-rejeitada"
+	AuditUpdateCalendar = "calendario-atualizado"
+)
+
+// FieldChange is a change of a single field of a profile.
+type FieldChange struct {
+	Field  string `bson:"field" json:"field"`
+	Before string `bson:"before" json:"before"`
+	After  string `bson:"after" json:"after"`
+}
+
+// AuditEntry records who did what to a candidature and when. The actor is
+// either "operador:<id>" or "candidato:<email>". Changes to the campaign
+// calendar have only the year, with an empty SequentialID.
+type AuditEntry struct {
+	ID           string         `bson:"_id" json:"id"`
+	CreatedAt    time.Time      `bson:"created_at" json:"created_at"`
+	Actor        string         `bson:"actor" json:"actor"`
+	SessionID    string         `bson:"session_id" json:"session_id"`
+	Action       string         `bson:"action" json:"action"`
+	Year         int            `bson:"year" json:"year"`
+	SequentialID string         `bson:"sequential_id" json:"sequential_id"`
+	Changes      []*FieldChange `bson:"changes" json:"changes"`
+}
+
+// AuditStore keeps the audit trail.
+type AuditStore interface {
+	// AddAuditEntry stores a new audit entry.
+	AddAuditEntry(e *AuditEntry) error
+
+	// ListAuditEntries returns up to limit entries of the candidature, newest
+	// first. A zero year and an empty sequentialID list entries of all
+	// candidatures.
+	ListAuditEntries(year int, sequentialID string, limit int) ([]*AuditEntry, error)
+}
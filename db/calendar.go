@@ -0,0 +1,65 @@
+package db
+
+import "time"
+
+// CalendarsCollection is the name of the collection of campaign calendars.
+const CalendarsCollection = "calendars"
+
+// Phases of the campaign calendar.
+const (
+	PhaseLogin = "login"  // candidates request access links.
+	PhaseTerms = "termo"  // candidates accept the terms of use.
+	PhaseEdit  = "edicao" // candidates edit their profiles.
+)
+
+// Phases lists the phases of the campaign calendar, in the order candidates
+// go through them.
+var Phases = []string{PhaseLogin, PhaseTerms, PhaseEdit}
+
+// CalendarWindow is the period in which a phase is open. A zero Opens or
+// Closes leaves the window unbounded on that side, and Closed closes it
+// regardless of the dates.
+type CalendarWindow struct {
+	Opens  time.Time `bson:"opens" json:"opens"`
+	Closes time.Time `bson:"closes" json:"closes"`
+	Closed bool      `bson:"closed" json:"closed"`
+}
+
+// Open reports whether the window is open at the time.
+func (w *CalendarWindow) Open(now time.Time) bool {
+	if w.Closed {
+		return false
+	}
+	if !w.Opens.IsZero() && now.Before(w.Opens) {
+		return false
+	}
+	return w.Closes.IsZero() || now.Before(w.Closes)
+}
+
+// Calendar defines when the candidates of an election year can sign in,
+// accept the terms and edit their profiles.
+type Calendar struct {
+	Year      int                        `bson:"_id" json:"year"`
+	Windows   map[string]*CalendarWindow `bson:"windows" json:"windows"`
+	UpdatedBy string                     `bson:"updated_by" json:"updated_by"`
+	UpdatedAt time.Time                  `bson:"updated_at" json:"updated_at"`
+}
+
+// Window returns the window of the phase. Phases without a window are always
+// open.
+func (c *Calendar) Window(phase string) *CalendarWindow {
+	if w, ok := c.Windows[phase]; ok && w != nil {
+		return w
+	}
+	return &CalendarWindow{}
+}
+
+// CalendarStore keeps the campaign calendars defined by operators.
+type CalendarStore interface {
+	// FindCalendar returns the calendar of the election year. It fails with
+	// exception.NotFound if operators have not defined it.
+	FindCalendar(year int) (*Calendar, error)
+
+	// SaveCalendar stores the calendar, replacing the one of the same year.
+	SaveCalendar(c *Calendar) error
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// DisabledProfilesCollection is the name of the collection of profiles
+// disabled by operators.
+const DisabledProfilesCollection = "disabled_profiles"
+
+// DisabledProfile marks a candidature whose profile was disabled by an
+// operator: the biography, proposals and contacts are not shown and the
+// candidate can not sign in to edit them.
+type DisabledProfile struct {
+	ID           string    `bson:"_id" json:"id"`
+	Year         int       `bson:"year" json:"year"`
+	SequentialID string    `bson:"sequential_id" json:"sequential_id"`
+	Reason       string    `bson:"reason" json:"reason"`
+	DisabledBy   string    `bson:"disabled_by" json:"disabled_by"`
+	DisabledAt   time.Time `bson:"disabled_at" json:"disabled_at"`
+}
+
+// DisabledProfileID returns the ID of the disabled profile record of a
+// candidature.
+func DisabledProfileID(year int, sequentialID string) string {
+	return fmt.Sprintf("%d/%s", year, sequentialID)
+}
+
+// DisabledProfileStore keeps the profiles disabled by operators.
+type DisabledProfileStore interface {
+	// DisableProfile stores the disabled profile record, replacing an
+	// existing one of the same candidature.
+	DisableProfile(p *DisabledProfile) error
+
+	// EnableProfile removes the disabled profile record of the candidature.
+	// It fails with exception.NotFound if the profile is not disabled.
+	EnableProfile(year int, sequentialID string) error
+
+	// FindDisabledProfile returns the disabled profile record of the
+	// candidature. It fails with exception.NotFound if the profile is not
+	// disabled.
+	FindDisabledProfile(year int, sequentialID string) (*DisabledProfile, error)
+}
@@ -0,0 +1,356 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/ratelimit"
+	"github.com/candidatos-info/site/search"
+)
+
+const (
+	locationsFixtureFile    = "locations.json"
+	candidaturesFixtureFile = "candidatures.json"
+)
+
+// MemoryClient is an in-memory implementation of Store. It is
+// loaded from fixture files and is meant to run the site offline and in tests.
+type MemoryClient struct {
+	mu               sync.RWMutex
+	locations        []*descritor.Location
+	candidatures     []*descritor.CandidateForDB
+	loginTokens      map[string]*LoginToken
+	sessions         map[string]*Session
+	outbox           map[string]*OutboxEmail
+	rateCounters     *ratelimit.MemoryStore
+	operators        map[string]*Operator
+	audit            []*AuditEntry
+	disabledProfiles map[string]*DisabledProfile
+	revisions        []*ProfileRevision
+	submissions      map[string]*ProfileSubmission
+	photos           map[string]*PhotoSubmission
+	tickets          map[string]*Ticket
+	calendars        map[int]*Calendar
+}
+
+// NewMemoryClient returns an in-memory client loaded from the fixtures
+// found at fixturesDir. The directory must contain the locations.json and
+// candidatures.json files, both being JSON arrays of descritor.Location and
+// descritor.CandidateForDB respectively.
+func NewMemoryClient(fixturesDir string) (*MemoryClient, error) {
+	var locations []*descritor.Location
+	if err := loadFixture(filepath.Join(fixturesDir, locationsFixtureFile), &locations); err != nil {
+		return nil, err
+	}
+	var candidatures []*descritor.CandidateForDB
+	if err := loadFixture(filepath.Join(fixturesDir, candidaturesFixtureFile), &candidatures); err != nil {
+		return nil, err
+	}
+	return &MemoryClient{
+		locations:        locations,
+		candidatures:     candidatures,
+		loginTokens:      make(map[string]*LoginToken),
+		sessions:         make(map[string]*Session),
+		outbox:           make(map[string]*OutboxEmail),
+		rateCounters:     ratelimit.NewMemoryStore(),
+		operators:        make(map[string]*Operator),
+		disabledProfiles: make(map[string]*DisabledProfile),
+		submissions:      make(map[string]*ProfileSubmission),
+		photos:           make(map[string]*PhotoSubmission),
+		tickets:          make(map[string]*Ticket),
+		calendars:        make(map[int]*Calendar),
+	}, nil
+}
+
+func loadFixture(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fixture file [%s], error %v", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to unmarshal fixture file [%s], error %v", path, err)
+	}
+	return nil
+}
+
+// GetStates returns a list of available states
+func (c *MemoryClient) GetStates() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var states []string
+	for _, location := range c.locations {
+		states = append(states, location.State)
+	}
+	return states, nil
+}
+
+// GetCities returns the city of a given state
+func (c *MemoryClient) GetCities(state string) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, location := range c.locations {
+		if location.State == state {
+			cities := append([]string(nil), location.Cities...)
+			sort.Strings(cities)
+			return cities, nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar cidades do estado [%s]", state), nil)
+}
+
+// GetLocations returns all states and their cities
+func (c *MemoryClient) GetLocations() ([]*descritor.Location, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	locations := make([]*descritor.Location, len(c.locations))
+	for i, l := range c.locations {
+		locations[i] = &descritor.Location{State: l.State, Cities: append([]string(nil), l.Cities...)}
+	}
+	return locations, nil
+}
+
+// GetCandidateByEmail searches for a candidate using email
+func (c *MemoryClient) GetCandidateByEmail(email string, year int) (*descritor.CandidateForDB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, candidate := range c.candidatures {
+		if candidate.Email == strings.ToUpper(email) && candidate.Year == year {
+			return copyCandidate(candidate), nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar candidato pelo ano [%d] e pelo email [%s]", year, email), nil)
+}
+
+// FindCandidatesByEmail returns every candidature registered with the email
+// in the year, ordered by sequential ID.
+func (c *MemoryClient) FindCandidatesByEmail(email string, year int) ([]*descritor.CandidateForDB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var candidates []*descritor.CandidateForDB
+	for _, candidate := range c.candidatures {
+		if candidate.Email == strings.ToUpper(email) && candidate.Year == year {
+			candidates = append(candidates, copyCandidate(candidate))
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].SequencialCandidate < candidates[j].SequencialCandidate
+	})
+	return candidates, nil
+}
+
+// FindCandidateBySequencialIDAndYear searches for a candidate using its
+// sequencial ID and returns it.
+func (c *MemoryClient) FindCandidateBySequencialIDAndYear(year int, sequencialID string) (*descritor.CandidateForDB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, candidate := range c.candidatures {
+		if candidate.SequencialCandidate == sequencialID && candidate.Year == year {
+			return copyCandidate(candidate), nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar candidato pelo ano [%d] e pelo sequencial ID [%s]", year, sequencialID), nil)
+}
+
+// UpdateCandidateProfile updates the profile of a cndidate
+func (c *MemoryClient) UpdateCandidateProfile(candidate *descritor.CandidateForDB) (*descritor.CandidateForDB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stored := range c.candidatures {
+		if stored.SequencialCandidate == candidate.SequencialCandidate && stored.Year == candidate.Year {
+			updated := copyCandidate(candidate)
+			stored.Biography = updated.Biography
+			stored.Transparency = updated.Transparency
+			stored.Proposals = updated.Proposals
+			stored.Contacts = updated.Contacts
+			stored.AcceptedTerms = updated.AcceptedTerms
+			stored.PhotoURL = updated.PhotoURL
+			return candidate, nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao atualizar perfil de candidato, candidato [%s] não encontrado", candidate.SequencialCandidate), nil)
+}
+
+// FindTransparentCandidatures searches for a page of candidatures with proposals defined
+func (c *MemoryClient) FindTransparentCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error) {
+	return c.findCandidatures(queryMap, page, func(candidate *descritor.CandidateForDB) bool {
+		return candidate.Proposals != nil
+	})
+}
+
+// FindNonTransparentCandidatures searches for a page of non transparent candidatures
+func (c *MemoryClient) FindNonTransparentCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error) {
+	return c.findCandidatures(queryMap, page, func(candidate *descritor.CandidateForDB) bool {
+		return candidate.Proposals == nil
+	})
+}
+
+func (c *MemoryClient) findCandidatures(queryMap map[string]interface{}, page Page, transparency func(*descritor.CandidateForDB) bool) (*CandidaturesPage, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	bySequencialID := make(map[string]*descritor.CandidateForDB)
+	var matches []*descritor.CandidateForDB
+	for _, candidate := range c.candidatures {
+		if !transparency(candidate) || c.isDisabled(candidate) {
+			continue
+		}
+		ok, err := matchCandidate(candidate, queryMap)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			bySequencialID[candidate.SequencialCandidate] = candidate
+			matches = append(matches, candidate)
+		}
+	}
+	ids := order(matches, queryMap, page.Seed)
+	result := &CandidaturesPage{Total: len(ids)}
+	for _, id := range page.slice(ids) {
+		result.Candidatures = append(result.Candidatures, copyCandidate(bySequencialID[id]))
+	}
+	return result, nil
+}
+
+// FindCandidaturesForIndex returns all candidatures filled only with the
+// fields needed to build search indexes.
+func (c *MemoryClient) FindCandidaturesForIndex() ([]*descritor.CandidateForDB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var candidatures []*descritor.CandidateForDB
+	for _, candidate := range c.candidatures {
+		if c.isDisabled(candidate) {
+			continue
+		}
+		candidatures = append(candidatures, &descritor.CandidateForDB{
+			SequencialCandidate: candidate.SequencialCandidate,
+			BallotName:          candidate.BallotName,
+			BallotNumber:        candidate.BallotNumber,
+			Name:                candidate.Name,
+			Party:               candidate.Party,
+			Role:                candidate.Role,
+			State:               candidate.State,
+			City:                candidate.City,
+			Year:                candidate.Year,
+		})
+	}
+	return candidatures, nil
+}
+
+// FindCandidaturesForRanking returns every transparent candidature matching
+// the query filled only with the fields needed to rank them.
+func (c *MemoryClient) FindCandidaturesForRanking(queryMap map[string]interface{}) ([]*descritor.CandidateForDB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var candidatures []*descritor.CandidateForDB
+	for _, candidate := range c.candidatures {
+		if candidate.Proposals == nil || c.isDisabled(candidate) {
+			continue
+		}
+		ok, err := matchCandidate(candidate, queryMap)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		proposals := make([]*descritor.Proposal, len(candidate.Proposals))
+		for i, p := range candidate.Proposals {
+			proposals[i] = &descritor.Proposal{Topic: p.Topic}
+		}
+		candidatures = append(candidatures, &descritor.CandidateForDB{
+			SequencialCandidate: candidate.SequencialCandidate,
+			Year:                candidate.Year,
+			BallotName:          candidate.BallotName,
+			Gender:              candidate.Gender,
+			Party:               candidate.Party,
+			Proposals:           proposals,
+		})
+	}
+	return candidatures, nil
+}
+
+// matchCandidate mimics the $match stage built by Client.findCandidatures,
+// except for the disabled profiles, which are left out by the callers.
+func matchCandidate(candidate *descritor.CandidateForDB, queryMap map[string]interface{}) (bool, error) {
+	for k, v := range queryMap {
+		switch k {
+		case "name":
+			name, _ := v.(string)
+			if !search.Match(name, searchDocument(candidate)) {
+				return false, nil
+			}
+		case "tags":
+			tags, _ := v.([]string)
+			if len(tags) > 0 && !hasAnyTopic(candidate, tags) {
+				return false, nil
+			}
+		case "year":
+			if year, ok := v.(int); !ok || candidate.Year != year {
+				return false, nil
+			}
+		case "state":
+			if candidate.State != v {
+				return false, nil
+			}
+		case "city":
+			if candidate.City != v {
+				return false, nil
+			}
+		case "gender":
+			if candidate.Gender != v {
+				return false, nil
+			}
+		case "role":
+			if candidate.Role != v {
+				return false, nil
+			}
+		case "party":
+			if candidate.Party != v {
+				return false, nil
+			}
+		case "proposals":
+			// Handled by the transparency predicate.
+		default:
+			return false, exception.New(exception.InvalidParameters, fmt.Sprintf("Filtro [%s] não suportado", k), nil)
+		}
+	}
+	return true, nil
+}
+
+func hasAnyTopic(candidate *descritor.CandidateForDB, tags []string) bool {
+	for _, p := range candidate.Proposals {
+		for _, t := range tags {
+			if p.Topic == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyCandidate returns a deep copy of the candidate, so callers can freely
+// change the returned value without changing the stored data.
+func copyCandidate(candidate *descritor.CandidateForDB) *descritor.CandidateForDB {
+	cp := *candidate
+	if candidate.Proposals != nil {
+		cp.Proposals = make([]*descritor.Proposal, len(candidate.Proposals))
+		for i, p := range candidate.Proposals {
+			aux := *p
+			cp.Proposals[i] = &aux
+		}
+	}
+	if candidate.Contacts != nil {
+		cp.Contacts = make([]*descritor.Contact, len(candidate.Contacts))
+		for i, contact := range candidate.Contacts {
+			aux := *contact
+			cp.Contacts[i] = &aux
+		}
+	}
+	return &cp
+}
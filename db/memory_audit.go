@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// AddAuditEntry stores a new audit entry.
+func (c *MemoryClient) AddAuditEntry(e *AuditEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stored := range c.audit {
+		if stored.ID == e.ID {
+			return exception.New(exception.Conflict, fmt.Sprintf("Registro de auditoria [%s] já existe", e.ID), nil)
+		}
+	}
+	c.audit = append(c.audit, copyAuditEntry(e))
+	return nil
+}
+
+// ListAuditEntries returns up to limit entries of the candidature, newest first.
+func (c *MemoryClient) ListAuditEntries(year int, sequentialID string, limit int) ([]*AuditEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var entries []*AuditEntry
+	for _, e := range c.audit {
+		if (year == 0 || e.Year == year) && (sequentialID == "" || e.SequentialID == sequentialID) {
+			entries = append(entries, copyAuditEntry(e))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func copyAuditEntry(e *AuditEntry) *AuditEntry {
+	cp := *e
+	if e.Changes != nil {
+		cp.Changes = make([]*FieldChange, len(e.Changes))
+		for i, change := range e.Changes {
+			aux := *change
+			cp.Changes[i] = &aux
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestOperatorCan(t *testing.T) {
+	testCases := []struct {
+		operator Operator
+		role     string
+		want     bool
+	}{
+		{Operator{Role: RoleViewer}, RoleViewer, true},
+		{Operator{Role: RoleViewer}, RoleEditor, false},
+		{Operator{Role: RoleEditor}, RoleViewer, true},
+		{Operator{Role: RoleEditor}, RoleAdmin, false},
+		{Operator{Role: RoleAdmin}, RoleAdmin, true},
+		{Operator{Role: RoleAdmin, DisabledAt: time.Now()}, RoleViewer, false},
+		{Operator{Role: "unknown"}, RoleViewer, false},
+		{Operator{Role: RoleAdmin}, "unknown", false},
+	}
+	for _, tt := range testCases {
+		if got := tt.operator.Can(tt.role); got != tt.want {
+			t.Errorf("want %s can %s %v, got %v", tt.operator.Role, tt.role, tt.want, got)
+		}
+	}
+}
+
+func TestMemoryClientOperators(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for _, id := range []string{"b", "a"} {
+		if err := c.CreateOperator(&Operator{ID: id, Role: RoleViewer}); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	if err := c.CreateOperator(&Operator{ID: "a"}); err == nil || err.(*exception.Exception).Code != exception.Conflict {
+		t.Errorf("want conflict error, got %v", err)
+	}
+	operators, err := c.ListOperators()
+	if err != nil || len(operators) != 2 || operators[0].ID != "a" {
+		t.Errorf("want operators a and b, got %v (error %v)", operators, err)
+	}
+	if err := c.DisableOperator("a", time.Now()); err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if o, err := c.FindOperator("a"); err != nil || o.DisabledAt.IsZero() {
+		t.Errorf("want operator disabled, got %+v (error %v)", o, err)
+	}
+	if err := c.DisableOperator("c", time.Now()); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+}
+
+func TestMemoryClientAudit(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for i, e := range []*AuditEntry{
+		{ID: "1", Year: 2020, SequentialID: "1", CreatedAt: now, Changes: []*FieldChange{{Field: "biografia", After: "a"}}},
+		{ID: "2", Year: 2020, SequentialID: "2", CreatedAt: now.Add(time.Second)},
+		{ID: "3", Year: 2020, SequentialID: "1", CreatedAt: now.Add(2 * time.Second)},
+	} {
+		if err := c.AddAuditEntry(e); err != nil {
+			t.Fatalf("want entry %d stored, got error %q", i, err)
+		}
+	}
+	entries, err := c.ListAuditEntries(2020, "1", 10)
+	if err != nil || len(entries) != 2 || entries[0].ID != "3" || entries[1].Changes[0].After != "a" {
+		t.Errorf("want entries 3 and 1, got %v (error %v)", entries, err)
+	}
+	if entries, _ := c.ListAuditEntries(0, "", 2); len(entries) != 2 || entries[0].ID != "3" || entries[1].ID != "2" {
+		t.Errorf("want the 2 newest entries, got %v", entries)
+	}
+}
+
+func TestMemoryClientDisabledProfiles(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if _, err := c.FindDisabledProfile(2020, "1"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	if err := c.DisableProfile(&DisabledProfile{Year: 2020, SequentialID: "1", Reason: "spam"}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if p, err := c.FindDisabledProfile(2020, "1"); err != nil || p.Reason != "spam" || p.ID != "2020/1" {
+		t.Errorf("want disabled profile, got %+v (error %v)", p, err)
+	}
+	if err := c.EnableProfile(2020, "1"); err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if err := c.EnableProfile(2020, "1"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+}
+
+func TestMemoryClientLeavesOutDisabledProfiles(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := c.DisableProfile(&DisabledProfile{Year: 2020, SequentialID: "20000000003", Reason: "spam"}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	// Only the disabled profile has proposals about Saúde.
+	page, err := c.FindTransparentCandidatures(map[string]interface{}{"year": 2020, "tags": []string{"Saúde"}}, Page{Number: 1, Size: 10})
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if page.Total != 0 || len(page.Candidatures) != 0 {
+		t.Errorf("want disabled profile left out of the search, got %+v", page)
+	}
+	page, err = c.FindNonTransparentCandidatures(map[string]interface{}{"year": 2020}, Page{Number: 1, Size: 10})
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for _, candidate := range page.Candidatures {
+		if candidate.SequencialCandidate == "20000000003" {
+			t.Errorf("want disabled profile left out of the search, got %+v", candidate)
+		}
+	}
+	candidatures, err := c.FindCandidaturesForIndex()
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for _, candidate := range candidatures {
+		if candidate.SequencialCandidate == "20000000003" {
+			t.Errorf("want disabled profile left out of the index, got %+v", candidate)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// FindCalendar returns the calendar of the election year.
+func (c *MemoryClient) FindCalendar(year int) (*Calendar, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cal, ok := c.calendars[year]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Calendário de %d não encontrado", year), nil)
+	}
+	return copyCalendar(cal), nil
+}
+
+// SaveCalendar stores the calendar, replacing the one of the same year.
+func (c *MemoryClient) SaveCalendar(cal *Calendar) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calendars[cal.Year] = copyCalendar(cal)
+	return nil
+}
+
+func copyCalendar(cal *Calendar) *Calendar {
+	aux := *cal
+	aux.Windows = make(map[string]*CalendarWindow, len(cal.Windows))
+	for phase, w := range cal.Windows {
+		window := *w
+		aux.Windows[phase] = &window
+	}
+	return &aux
+}
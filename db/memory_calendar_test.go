@@ -0,0 +1,58 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestCalendarWindowOpen(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		window CalendarWindow
+		want   bool
+	}{
+		{CalendarWindow{}, true},
+		{CalendarWindow{Closed: true}, false},
+		{CalendarWindow{Opens: now.Add(-time.Hour)}, true},
+		{CalendarWindow{Opens: now.Add(time.Hour)}, false},
+		{CalendarWindow{Closes: now.Add(time.Hour)}, true},
+		{CalendarWindow{Closes: now}, false},
+		{CalendarWindow{Opens: now.Add(-time.Hour), Closes: now.Add(time.Hour), Closed: true}, false},
+	}
+	for _, tc := range testCases {
+		if got := tc.window.Open(now); got != tc.want {
+			t.Errorf("want window %+v open %v, got %v", tc.window, tc.want, got)
+		}
+	}
+	if w := (&Calendar{}).Window(PhaseEdit); !w.Open(now) {
+		t.Errorf("want phase without window open")
+	}
+}
+
+func TestMemoryClientCalendars(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if _, err := c.FindCalendar(2020); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	cal := &Calendar{Year: 2020, Windows: map[string]*CalendarWindow{PhaseEdit: {Closed: true}}, UpdatedBy: "admin"}
+	if err := c.SaveCalendar(cal); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	cal.Windows[PhaseEdit].Closed = false
+	found, err := c.FindCalendar(2020)
+	if err != nil || !found.Window(PhaseEdit).Closed || found.UpdatedBy != "admin" {
+		t.Errorf("want stored calendar unchanged, got %+v (error %v)", found, err)
+	}
+	found.Windows[PhaseEdit].Closed = false
+	if err := c.SaveCalendar(found); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if found, _ := c.FindCalendar(2020); found.Window(PhaseEdit).Closed {
+		t.Errorf("want calendar replaced")
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+)
+
+// DisableProfile stores the disabled profile record.
+func (c *MemoryClient) DisableProfile(p *DisabledProfile) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	aux := *p
+	aux.ID = DisabledProfileID(p.Year, p.SequentialID)
+	c.disabledProfiles[aux.ID] = &aux
+	return nil
+}
+
+// EnableProfile removes the disabled profile record of the candidature.
+func (c *MemoryClient) EnableProfile(year int, sequentialID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := DisabledProfileID(year, sequentialID)
+	if _, ok := c.disabledProfiles[id]; !ok {
+		return exception.New(exception.NotFound, fmt.Sprintf("Perfil [%s] não está desativado", id), nil)
+	}
+	delete(c.disabledProfiles, id)
+	return nil
+}
+
+// FindDisabledProfile returns the disabled profile record of the candidature.
+func (c *MemoryClient) FindDisabledProfile(year int, sequentialID string) (*DisabledProfile, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id := DisabledProfileID(year, sequentialID)
+	p, ok := c.disabledProfiles[id]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Perfil [%s] não está desativado", id), nil)
+	}
+	aux := *p
+	return &aux, nil
+}
+
+// isDisabled reports whether the profile of the candidature was disabled. The
+// caller must hold the lock.
+func (c *MemoryClient) isDisabled(candidate *descritor.CandidateForDB) bool {
+	_, ok := c.disabledProfiles[DisabledProfileID(candidate.Year, candidate.SequencialCandidate)]
+	return ok
+}
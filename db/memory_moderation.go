@@ -0,0 +1,117 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+)
+
+// CreateSubmission stores a new pending submission.
+func (c *MemoryClient) CreateSubmission(s *ProfileSubmission) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.submissions[s.ID]; ok {
+		return exception.New(exception.Conflict, fmt.Sprintf("Submissão [%s] já existe", s.ID), nil)
+	}
+	c.supersedeSubmissions(s.Year, s.SequentialID)
+	c.submissions[s.ID] = copySubmission(s)
+	return nil
+}
+
+// SupersedeSubmissions marks the pending submissions of the candidature as
+// superseded.
+func (c *MemoryClient) SupersedeSubmissions(year int, sequentialID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.supersedeSubmissions(year, sequentialID)
+	return nil
+}
+
+// supersedeSubmissions must be called with the lock held.
+func (c *MemoryClient) supersedeSubmissions(year int, sequentialID string) {
+	for _, s := range c.submissions {
+		if s.Year == year && s.SequentialID == sequentialID && s.Status == SubmissionPending {
+			s.Status = SubmissionSuperseded
+		}
+	}
+}
+
+// FindSubmission returns the submission with the given ID.
+func (c *MemoryClient) FindSubmission(id string) (*ProfileSubmission, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.submissions[id]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Submissão [%s] não encontrada", id), nil)
+	}
+	return copySubmission(s), nil
+}
+
+// FindPendingSubmission returns the pending submission of the candidature.
+func (c *MemoryClient) FindPendingSubmission(year int, sequentialID string) (*ProfileSubmission, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, s := range c.submissions {
+		if s.Year == year && s.SequentialID == sequentialID && s.Status == SubmissionPending {
+			return copySubmission(s), nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Não há submissão pendente da candidatura [%s]", sequentialID), nil)
+}
+
+// ListSubmissions returns up to limit submissions with the status, oldest first.
+func (c *MemoryClient) ListSubmissions(status string, limit int) ([]*ProfileSubmission, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var submissions []*ProfileSubmission
+	for _, s := range c.submissions {
+		if s.Status == status {
+			submissions = append(submissions, copySubmission(s))
+		}
+	}
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].CreatedAt.Before(submissions[j].CreatedAt)
+	})
+	if len(submissions) > limit {
+		submissions = submissions[:limit]
+	}
+	return submissions, nil
+}
+
+// ReviewSubmission approves or rejects a pending submission.
+func (c *MemoryClient) ReviewSubmission(id, status, reviewedBy, reason string, reviewedAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.submissions[id]
+	if !ok || s.Status != SubmissionPending {
+		return exception.New(exception.NotFound, fmt.Sprintf("Submissão [%s] não encontrada ou já moderada", id), nil)
+	}
+	s.Status = status
+	s.ReviewedBy = reviewedBy
+	s.Reason = reason
+	s.ReviewedAt = reviewedAt
+	return nil
+}
+
+// ReopenSubmission returns an approved submission to the queue.
+func (c *MemoryClient) ReopenSubmission(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.submissions[id]
+	if !ok || s.Status != SubmissionApproved {
+		return exception.New(exception.NotFound, fmt.Sprintf("Submissão [%s] não encontrada ou não aprovada", id), nil)
+	}
+	s.Status = SubmissionPending
+	s.ReviewedBy = ""
+	s.ReviewedAt = time.Time{}
+	return nil
+}
+
+func copySubmission(s *ProfileSubmission) *ProfileSubmission {
+	cp := *s
+	cp.Proposals = copyCandidate(&descritor.CandidateForDB{Proposals: s.Proposals}).Proposals
+	return &cp
+}
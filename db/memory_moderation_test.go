@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestMemoryClientSubmissions(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for i, id := range []string{"a", "b", "c"} {
+		seqID := "20000000001"
+		if id == "c" {
+			seqID = "20000000002"
+		}
+		s := &ProfileSubmission{ID: id, Year: 2020, SequentialID: seqID, Status: SubmissionPending, CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		if err := c.CreateSubmission(s); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	if s, err := c.FindSubmission("a"); err != nil || s.Status != SubmissionSuperseded {
+		t.Errorf("want submission a superseded by b, got %+v (error %v)", s, err)
+	}
+	if s, err := c.FindPendingSubmission(2020, "20000000001"); err != nil || s.ID != "b" {
+		t.Errorf("want pending submission b, got %+v (error %v)", s, err)
+	}
+	pending, err := c.ListSubmissions(SubmissionPending, 10)
+	if err != nil || len(pending) != 2 || pending[0].ID != "b" || pending[1].ID != "c" {
+		t.Errorf("want pending submissions b and c, got %v (error %v)", pending, err)
+	}
+	if err := c.ReviewSubmission("b", SubmissionRejected, "operador", "linguagem ofensiva", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s, err := c.FindSubmission("b"); err != nil || s.Status != SubmissionRejected || s.Reason != "linguagem ofensiva" {
+		t.Errorf("want submission b rejected, got %+v (error %v)", s, err)
+	}
+	if err := c.ReviewSubmission("b", SubmissionApproved, "operador", "", now); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error reviewing twice, got %v", err)
+	}
+	if _, err := c.FindPendingSubmission(2020, "20000000001"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	if err := c.ReopenSubmission("b"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error reopening a rejected submission, got %v", err)
+	}
+	if err := c.ReviewSubmission("c", SubmissionApproved, "operador", "", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := c.ReopenSubmission("c"); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s, err := c.FindPendingSubmission(2020, "20000000002"); err != nil || s.ID != "c" || s.ReviewedBy != "" {
+		t.Errorf("want submission c pending again, got %+v (error %v)", s, err)
+	}
+	if err := c.SupersedeSubmissions(2020, "20000000002"); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s, err := c.FindSubmission("c"); err != nil || s.Status != SubmissionSuperseded {
+		t.Errorf("want submission c superseded, got %+v (error %v)", s, err)
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// CreateOperator stores a new operator.
+func (c *MemoryClient) CreateOperator(o *Operator) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.operators[o.ID]; ok {
+		return exception.New(exception.Conflict, fmt.Sprintf("Operador [%s] já existe", o.ID), nil)
+	}
+	aux := *o
+	c.operators[o.ID] = &aux
+	return nil
+}
+
+// FindOperator returns the operator with the given ID.
+func (c *MemoryClient) FindOperator(id string) (*Operator, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	o, ok := c.operators[id]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Operador [%s] não encontrado", id), nil)
+	}
+	aux := *o
+	return &aux, nil
+}
+
+// ListOperators returns all operators ordered by ID.
+func (c *MemoryClient) ListOperators() ([]*Operator, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var operators []*Operator
+	for _, o := range c.operators {
+		aux := *o
+		operators = append(operators, &aux)
+	}
+	sort.Slice(operators, func(i, j int) bool {
+		return operators[i].ID < operators[j].ID
+	})
+	return operators, nil
+}
+
+// DisableOperator disables an operator.
+func (c *MemoryClient) DisableOperator(id string, disabledAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.operators[id]
+	if !ok {
+		return exception.New(exception.NotFound, fmt.Sprintf("Operador [%s] não encontrado", id), nil)
+	}
+	if o.DisabledAt.IsZero() {
+		o.DisabledAt = disabledAt
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// EnqueueEmail stores a new queued email.
+func (c *MemoryClient) EnqueueEmail(e *OutboxEmail) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.outbox[e.ID]; ok {
+		return false, exception.New(exception.Conflict, fmt.Sprintf("Email [%s] já existe", e.ID), nil)
+	}
+	if e.IdempotencyKey != "" {
+		for _, aux := range c.outbox {
+			if aux.IdempotencyKey == e.IdempotencyKey {
+				return false, nil
+			}
+		}
+	}
+	c.outbox[e.ID] = copyOutboxEmail(e)
+	return true, nil
+}
+
+// ClaimDueEmails returns up to limit queued emails whose next attempt is due.
+func (c *MemoryClient) ClaimDueEmails(now time.Time, lease time.Duration, limit int) ([]*OutboxEmail, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var due []*OutboxEmail
+	for _, e := range c.outbox {
+		if e.Status == EmailQueued && !e.NextAttemptAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	var claimed []*OutboxEmail
+	for _, e := range due {
+		e.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, copyOutboxEmail(e))
+	}
+	return claimed, nil
+}
+
+// MarkEmailSent records the email was sent.
+func (c *MemoryClient) MarkEmailSent(id string, sentAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.outbox[id]
+	if !ok {
+		return exception.New(exception.NotFound, fmt.Sprintf("Email [%s] não encontrado", id), nil)
+	}
+	e.Status = EmailSent
+	e.SentAt = sentAt
+	e.Attempts++
+	return nil
+}
+
+// MarkEmailFailed records a failed attempt.
+func (c *MemoryClient) MarkEmailFailed(id string, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.outbox[id]
+	if !ok {
+		return exception.New(exception.NotFound, fmt.Sprintf("Email [%s] não encontrado", id), nil)
+	}
+	e.Attempts = attempts
+	e.LastError = lastError
+	e.NextAttemptAt = nextAttemptAt
+	if dead {
+		e.Status = EmailDead
+	}
+	return nil
+}
+
+// ListEmails returns up to limit emails with the status, newest first.
+func (c *MemoryClient) ListEmails(status string, limit int) ([]*OutboxEmail, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var emails []*OutboxEmail
+	for _, e := range c.outbox {
+		if status == "" || e.Status == status {
+			emails = append(emails, copyOutboxEmail(e))
+		}
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].CreatedAt.After(emails[j].CreatedAt)
+	})
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return emails, nil
+}
+
+// RequeueEmail puts a dead email back in the queue.
+func (c *MemoryClient) RequeueEmail(id string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.outbox[id]
+	if !ok || e.Status != EmailDead {
+		return exception.New(exception.NotFound, fmt.Sprintf("Email [%s] não encontrado ou não está com falha", id), nil)
+	}
+	e.Status = EmailQueued
+	e.Attempts = 0
+	e.NextAttemptAt = now
+	return nil
+}
+
+func copyOutboxEmail(e *OutboxEmail) *OutboxEmail {
+	aux := *e
+	aux.To = append([]string(nil), e.To...)
+	return &aux
+}
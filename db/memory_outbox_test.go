@@ -0,0 +1,64 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryClientOutbox(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for i, e := range []*OutboxEmail{
+		{ID: "1", IdempotencyKey: "login/a@b.com/x", To: []string{"a@b.com"}, Status: EmailQueued, CreatedAt: now, NextAttemptAt: now},
+		{ID: "2", To: []string{"c@d.com"}, Status: EmailQueued, CreatedAt: now.Add(time.Second), NextAttemptAt: now.Add(-time.Minute)},
+		{ID: "3", To: []string{"e@f.com"}, Status: EmailQueued, CreatedAt: now.Add(2 * time.Second), NextAttemptAt: now.Add(time.Hour)},
+	} {
+		queued, err := c.EnqueueEmail(e)
+		if err != nil || !queued {
+			t.Fatalf("want email %d queued, got %v (error %v)", i, queued, err)
+		}
+	}
+	queued, err := c.EnqueueEmail(&OutboxEmail{ID: "4", IdempotencyKey: "login/a@b.com/x", Status: EmailQueued})
+	if err != nil || queued {
+		t.Errorf("want email with repeated idempotency key not queued, got %v (error %v)", queued, err)
+	}
+
+	claimed, err := c.ClaimDueEmails(now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != "2" || claimed[1].ID != "1" {
+		t.Fatalf("want emails 2 and 1 claimed, got %v", claimed)
+	}
+	if claimed, _ := c.ClaimDueEmails(now, time.Minute, 10); len(claimed) != 0 {
+		t.Errorf("want claimed emails leased, got %v", claimed)
+	}
+
+	if err := c.MarkEmailSent("1", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := c.MarkEmailFailed("2", 8, "timeout", now, true); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for status, want := range map[string]int{"": 3, EmailQueued: 1, EmailSent: 1, EmailDead: 1} {
+		if emails, _ := c.ListEmails(status, 10); len(emails) != want {
+			t.Errorf("want %d emails with status %q, got %d", want, status, len(emails))
+		}
+	}
+	if emails, _ := c.ListEmails("", 1); len(emails) != 1 || emails[0].ID != "3" {
+		t.Errorf("want newest email first, got %v", emails)
+	}
+
+	if err := c.RequeueEmail("1", now); err == nil {
+		t.Errorf("want error requeueing email already sent")
+	}
+	if err := c.RequeueEmail("2", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if claimed, _ := c.ClaimDueEmails(now, time.Minute, 10); len(claimed) != 1 || claimed[0].ID != "2" || claimed[0].Attempts != 0 {
+		t.Errorf("want requeued email claimed again, got %v", claimed)
+	}
+}
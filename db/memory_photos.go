@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// CreatePhotoSubmission stores a new pending photo.
+func (c *MemoryClient) CreatePhotoSubmission(s *PhotoSubmission) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.photos[s.ID]; ok {
+		return exception.New(exception.Conflict, fmt.Sprintf("Foto [%s] já existe", s.ID), nil)
+	}
+	for _, stored := range c.photos {
+		if stored.Year == s.Year && stored.SequentialID == s.SequentialID && stored.Status == SubmissionPending {
+			stored.Status = SubmissionSuperseded
+		}
+	}
+	c.photos[s.ID] = copyPhotoSubmission(s)
+	return nil
+}
+
+// FindPhotoSubmission returns the photo submission with the given ID.
+func (c *MemoryClient) FindPhotoSubmission(id string) (*PhotoSubmission, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.photos[id]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Foto [%s] não encontrada", id), nil)
+	}
+	return copyPhotoSubmission(s), nil
+}
+
+// FindPendingPhotoSubmission returns the pending photo of the candidature.
+func (c *MemoryClient) FindPendingPhotoSubmission(year int, sequentialID string) (*PhotoSubmission, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, s := range c.photos {
+		if s.Year == year && s.SequentialID == sequentialID && s.Status == SubmissionPending {
+			return copyPhotoSubmission(s), nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Não há foto pendente da candidatura [%s]", sequentialID), nil)
+}
+
+// ListPhotoSubmissions returns up to limit photo submissions with the status,
+// oldest first.
+func (c *MemoryClient) ListPhotoSubmissions(status string, limit int) ([]*PhotoSubmission, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var photos []*PhotoSubmission
+	for _, s := range c.photos {
+		if s.Status == status {
+			photos = append(photos, copyPhotoSubmission(s))
+		}
+	}
+	sort.Slice(photos, func(i, j int) bool {
+		return photos[i].CreatedAt.Before(photos[j].CreatedAt)
+	})
+	if len(photos) > limit {
+		photos = photos[:limit]
+	}
+	return photos, nil
+}
+
+// ReviewPhotoSubmission approves or rejects a pending photo.
+func (c *MemoryClient) ReviewPhotoSubmission(id, status, reviewedBy, reason string, reviewedAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.photos[id]
+	if !ok || s.Status != SubmissionPending {
+		return exception.New(exception.NotFound, fmt.Sprintf("Foto [%s] não encontrada ou já moderada", id), nil)
+	}
+	s.Status = status
+	s.ReviewedBy = reviewedBy
+	s.Reason = reason
+	s.ReviewedAt = reviewedAt
+	return nil
+}
+
+// ReopenPhotoSubmission returns an approved photo to the queue.
+func (c *MemoryClient) ReopenPhotoSubmission(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.photos[id]
+	if !ok || s.Status != SubmissionApproved {
+		return exception.New(exception.NotFound, fmt.Sprintf("Foto [%s] não encontrada ou não aprovada", id), nil)
+	}
+	s.Status = SubmissionPending
+	s.ReviewedBy = ""
+	s.ReviewedAt = time.Time{}
+	return nil
+}
+
+func copyPhotoSubmission(s *PhotoSubmission) *PhotoSubmission {
+	cp := *s
+	cp.Renditions = make([]*PhotoRendition, len(s.Renditions))
+	for i, r := range s.Renditions {
+		aux := *r
+		cp.Renditions[i] = &aux
+	}
+	return &cp
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestMemoryClientPhotoSubmissions(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for i, id := range []string{"a", "b"} {
+		s := &PhotoSubmission{
+			ID:           id,
+			Year:         2020,
+			SequentialID: "20000000001",
+			Status:       SubmissionPending,
+			CreatedAt:    now.Add(time.Duration(i) * time.Minute),
+			Renditions:   []*PhotoRendition{{Name: "pequena", Width: 120, Height: 160, URL: "/arquivos/" + id + ".jpg"}},
+		}
+		if err := c.CreatePhotoSubmission(s); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		s.Renditions[0].URL = "alterada"
+	}
+	if s, err := c.FindPhotoSubmission("a"); err != nil || s.Status != SubmissionSuperseded {
+		t.Errorf("want photo a superseded by b, got %+v (error %v)", s, err)
+	}
+	s, err := c.FindPendingPhotoSubmission(2020, "20000000001")
+	if err != nil || s.ID != "b" || s.Rendition("pequena").URL != "/arquivos/b.jpg" || s.Rendition("grande") != nil {
+		t.Errorf("want pending photo b, got %+v (error %v)", s, err)
+	}
+	if pending, err := c.ListPhotoSubmissions(SubmissionPending, 10); err != nil || len(pending) != 1 || pending[0].ID != "b" {
+		t.Errorf("want pending photo b, got %v (error %v)", pending, err)
+	}
+	if err := c.ReviewPhotoSubmission("b", SubmissionApproved, "operador", "", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := c.ReviewPhotoSubmission("b", SubmissionRejected, "operador", "foto de outra pessoa", now); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error reviewing twice, got %v", err)
+	}
+	if _, err := c.FindPendingPhotoSubmission(2020, "20000000001"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	if err := c.ReopenPhotoSubmission("b"); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s, err := c.FindPendingPhotoSubmission(2020, "20000000001"); err != nil || s.ID != "b" || s.ReviewedBy != "" {
+		t.Errorf("want photo b pending again, got %+v (error %v)", s, err)
+	}
+	if err := c.ReopenPhotoSubmission("a"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error reopening a superseded photo, got %v", err)
+	}
+}
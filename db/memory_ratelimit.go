@@ -0,0 +1,9 @@
+package db
+
+import "time"
+
+// IncrementRateCounter increments the counter identified by key and returns
+// its new value.
+func (c *MemoryClient) IncrementRateCounter(key string, expiresAt time.Time) (int, error) {
+	return c.rateCounters.IncrementRateCounter(key, expiresAt)
+}
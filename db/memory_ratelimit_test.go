@@ -0,0 +1,28 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryClientIncrementRateCounter(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for want := 1; want <= 3; want++ {
+		if n, err := c.IncrementRateCounter("a", now.Add(time.Hour)); err != nil || n != want {
+			t.Errorf("want counter %d, got %d (error %v)", want, n, err)
+		}
+	}
+	if n, err := c.IncrementRateCounter("b", now.Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("want counters to be independent, got %d (error %v)", n, err)
+	}
+	if n, err := c.IncrementRateCounter("expired", now.Add(-time.Second)); err != nil || n != 1 {
+		t.Errorf("want counter 1, got %d (error %v)", n, err)
+	}
+	if n, err := c.IncrementRateCounter("expired", now.Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("want expired counter to restart, got %d (error %v)", n, err)
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+)
+
+// AddProfileRevision stores a new revision as the latest one of the candidature.
+func (c *MemoryClient) AddProfileRevision(r *ProfileRevision) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	number := 1
+	for _, stored := range c.revisions {
+		if stored.Year == r.Year && stored.SequentialID == r.SequentialID && stored.Number >= number {
+			number = stored.Number + 1
+		}
+	}
+	r.Number = number
+	r.ID = ProfileRevisionID(r.Year, r.SequentialID, number)
+	c.revisions = append(c.revisions, copyProfileRevision(r))
+	return nil
+}
+
+// ListProfileRevisions returns the revisions of the candidature, newest first.
+func (c *MemoryClient) ListProfileRevisions(year int, sequentialID string) ([]*ProfileRevision, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var revisions []*ProfileRevision
+	for _, r := range c.revisions {
+		if r.Year == year && r.SequentialID == sequentialID {
+			revisions = append(revisions, copyProfileRevision(r))
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number > revisions[j].Number
+	})
+	return revisions, nil
+}
+
+// FindProfileRevision returns a revision of the candidature.
+func (c *MemoryClient) FindProfileRevision(year int, sequentialID string, number int) (*ProfileRevision, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, r := range c.revisions {
+		if r.Year == year && r.SequentialID == sequentialID && r.Number == number {
+			return copyProfileRevision(r), nil
+		}
+	}
+	return nil, exception.New(exception.NotFound, fmt.Sprintf("Revisão [%s] não encontrada", ProfileRevisionID(year, sequentialID, number)), nil)
+}
+
+func copyProfileRevision(r *ProfileRevision) *ProfileRevision {
+	cp := *r
+	profile := copyCandidate(&descritor.CandidateForDB{Proposals: r.Proposals, Contacts: r.Contacts})
+	cp.Proposals = profile.Proposals
+	cp.Contacts = profile.Contacts
+	cp.Changes = copyAuditEntry(&AuditEntry{Changes: r.Changes}).Changes
+	return &cp
+}
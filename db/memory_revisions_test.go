@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestMemoryClientRevisions(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for i, bio := range []string{"a", "b"} {
+		r := &ProfileRevision{Year: 2020, SequentialID: "1", Biography: bio, Proposals: []*descritor.Proposal{{Topic: "Educação"}}}
+		if err := c.AddProfileRevision(r); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		if r.Number != i+1 || r.ID != ProfileRevisionID(2020, "1", i+1) {
+			t.Errorf("want revision number %d, got %d (%s)", i+1, r.Number, r.ID)
+		}
+		r.Proposals[0].Topic = "changed after stored"
+	}
+	if err := c.AddProfileRevision(&ProfileRevision{Year: 2020, SequentialID: "2"}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	revisions, err := c.ListProfileRevisions(2020, "1")
+	if err != nil || len(revisions) != 2 || revisions[0].Biography != "b" || revisions[1].Number != 1 {
+		t.Errorf("want revisions 2 and 1, got %v (error %v)", revisions, err)
+	}
+	r, err := c.FindProfileRevision(2020, "1", 1)
+	if err != nil || r.Biography != "a" || r.Proposals[0].Topic != "Educação" {
+		t.Errorf("want revision 1 isolated from caller, got %+v (error %v)", r, err)
+	}
+	if _, err := c.FindProfileRevision(2020, "1", 3); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// CreateLoginToken stores a new login token.
+func (c *MemoryClient) CreateLoginToken(t *LoginToken) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.loginTokens[t.ID]; ok {
+		return exception.New(exception.Conflict, fmt.Sprintf("Token de acesso [%s] já existe", t.ID), nil)
+	}
+	aux := *t
+	c.loginTokens[t.ID] = &aux
+	return nil
+}
+
+// UseLoginToken marks the login token as used and returns it.
+func (c *MemoryClient) UseLoginToken(id string, usedAt time.Time, ip, userAgent string) (*LoginToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.loginTokens[id]
+	if !ok || !t.UsedAt.IsZero() || !t.RevokedAt.IsZero() || !usedAt.Before(t.ExpiresAt) {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Token de acesso [%s] inexistente, expirado ou já utilizado", id), nil)
+	}
+	t.UsedAt = usedAt
+	t.UsedIP = ip
+	t.UsedUserAgent = userAgent
+	aux := *t
+	return &aux, nil
+}
+
+// CreateSession stores a new session.
+func (c *MemoryClient) CreateSession(s *Session) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.sessions[s.ID]; ok {
+		return exception.New(exception.Conflict, fmt.Sprintf("Sessão [%s] já existe", s.ID), nil)
+	}
+	aux := *s
+	c.sessions[s.ID] = &aux
+	return nil
+}
+
+// FindSession returns the session with the given ID.
+func (c *MemoryClient) FindSession(id string) (*Session, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.sessions[id]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar sessão [%s]", id), nil)
+	}
+	aux := *s
+	return &aux, nil
+}
+
+// RevokeSession revokes a single session.
+func (c *MemoryClient) RevokeSession(id string, revokedAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.sessions[id]; ok && s.RevokedAt.IsZero() {
+		s.RevokedAt = revokedAt
+	}
+	return nil
+}
+
+// RevokeAllByEmail revokes every session and unused login token issued to the email.
+func (c *MemoryClient) RevokeAllByEmail(email string, revokedAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	email = strings.ToLower(email)
+	for _, s := range c.sessions {
+		if s.Email == email && s.RevokedAt.IsZero() {
+			s.RevokedAt = revokedAt
+		}
+	}
+	for _, t := range c.loginTokens {
+		if t.Email == email && t.RevokedAt.IsZero() && t.UsedAt.IsZero() {
+			t.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
+
+// RevokeAllByCandidature revokes every session and unused login token giving
+// access to the candidature.
+func (c *MemoryClient) RevokeAllByCandidature(year int, sequentialID string, revokedAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.sessions {
+		if s.Year == year && s.SequentialID == sequentialID && s.RevokedAt.IsZero() {
+			s.RevokedAt = revokedAt
+		}
+	}
+	for _, t := range c.loginTokens {
+		if t.Year == year && t.SequentialID == sequentialID && t.RevokedAt.IsZero() && t.UsedAt.IsZero() {
+			t.RevokedAt = revokedAt
+		}
+	}
+	return nil
+}
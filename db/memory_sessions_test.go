@@ -0,0 +1,119 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryClientLoginTokens(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for _, lt := range []*LoginToken{
+		{ID: "valid", Email: "a@b.com", IssuedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "expired", Email: "a@b.com", IssuedAt: now, ExpiresAt: now.Add(-time.Minute)},
+		{ID: "revoked", Email: "c@d.com", IssuedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := c.CreateLoginToken(lt); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	if err := c.CreateLoginToken(&LoginToken{ID: "valid"}); err == nil {
+		t.Errorf("want error when creating a repeated login token")
+	}
+	if err := c.RevokeAllByEmail("C@D.COM", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	lt, err := c.UseLoginToken("valid", now, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if lt.UsedAt != now || lt.UsedIP != "127.0.0.1" || lt.UsedUserAgent != "test" {
+		t.Errorf("want usage to be recorded, got %+v", lt)
+	}
+	for _, id := range []string{"valid", "expired", "revoked", "unknown"} {
+		if _, err := c.UseLoginToken(id, now, "", ""); err == nil {
+			t.Errorf("want error when using login token %s", id)
+		}
+	}
+}
+
+func TestMemoryClientSessions(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for _, id := range []string{"s1", "s2", "s3"} {
+		if err := c.CreateSession(&Session{ID: id, Email: "a@b.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	if err := c.RevokeSession("s1", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	s1, err := c.FindSession("s1")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s1.Active(now) {
+		t.Errorf("want revoked session to be inactive")
+	}
+	s2, err := c.FindSession("s2")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if !s2.Active(now) || s2.Active(now.Add(2*time.Hour)) {
+		t.Errorf("want session active only before it expires")
+	}
+	if err := c.RevokeAllByEmail("a@b.com", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s3, _ := c.FindSession("s3"); s3.Active(now) {
+		t.Errorf("want all sessions of the email revoked")
+	}
+}
+
+func TestMemoryClientRevokeAllByCandidature(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	for _, s := range []*Session{
+		{ID: "s1", Email: "a@b.com", Year: 2020, SequentialID: "1", ExpiresAt: now.Add(time.Hour)},
+		{ID: "s2", Email: "a@b.com", Year: 2020, SequentialID: "2", ExpiresAt: now.Add(time.Hour)},
+		{ID: "s3", Email: "a@b.com", Year: 2016, SequentialID: "1", ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := c.CreateSession(s); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	for _, lt := range []*LoginToken{
+		{ID: "t1", Email: "a@b.com", Year: 2020, SequentialID: "1", ExpiresAt: now.Add(time.Hour)},
+		{ID: "t2", Email: "a@b.com", Year: 2020, SequentialID: "2", ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := c.CreateLoginToken(lt); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	if err := c.RevokeAllByCandidature(2020, "1", now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if s1, _ := c.FindSession("s1"); s1.Active(now) {
+		t.Errorf("want session of the candidature revoked")
+	}
+	for _, id := range []string{"s2", "s3"} {
+		if s, _ := c.FindSession(id); !s.Active(now) {
+			t.Errorf("want session %s of other candidatures active", id)
+		}
+	}
+	if _, err := c.UseLoginToken("t1", now, "", ""); err == nil {
+		t.Errorf("want login token of the candidature revoked")
+	}
+	if _, err := c.UseLoginToken("t2", now, "", ""); err != nil {
+		t.Errorf("want login token of other candidatures valid, got %q", err)
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import "sort"
+
+// LocationStats returns the statistics of the candidatures of the state in
+// the year or, if city is not empty, of the city.
+func (c *MemoryClient) LocationStats(year int, state, city string) (*LocationStats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stats := &LocationStats{}
+	roles, genders, parties, topics := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	for _, candidate := range c.candidatures {
+		if candidate.Year != year || candidate.State != state || (city != "" && candidate.City != city) {
+			continue
+		}
+		stats.Total++
+		if candidate.Proposals != nil {
+			stats.Transparent++
+		}
+		roles[candidate.Role]++
+		genders[candidate.Gender]++
+		parties[candidate.Party]++
+		seen := make(map[string]bool)
+		for _, p := range candidate.Proposals {
+			if !seen[p.Topic] {
+				seen[p.Topic] = true
+				topics[p.Topic]++
+			}
+		}
+	}
+	stats.Roles, stats.Genders, stats.Parties, stats.Topics = counts(roles), counts(genders), counts(parties), counts(topics)
+	return stats, nil
+}
+
+func counts(m map[string]int) []*Count {
+	var c []*Count
+	for value, total := range m {
+		c = append(c, &Count{Value: value, Total: total})
+	}
+	sortCounts(c)
+	return c
+}
+
+// ElectionYears returns the years of the elections with candidatures.
+func (c *MemoryClient) ElectionYears() ([]int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[int]bool)
+	var years []int
+	for _, candidate := range c.candidatures {
+		if !seen[candidate.Year] {
+			seen[candidate.Year] = true
+			years = append(years, candidate.Year)
+		}
+	}
+	sort.Ints(years)
+	return years, nil
+}
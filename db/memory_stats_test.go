@@ -0,0 +1,52 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemoryClientLocationStats(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	stats, err := c.LocationStats(2020, "AL", "MACEIÓ")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if stats.Total != 3 || stats.Transparent != 2 {
+		t.Errorf("want 3 candidatures, 2 with proposals, got %d and %d", stats.Total, stats.Transparent)
+	}
+	want := &LocationStats{
+		Total:       3,
+		Transparent: 2,
+		Roles:       []*Count{{"vereador", 2}, {"prefeito", 1}},
+		Genders:     []*Count{{"MASCULINO", 2}, {"FEMININO", 1}},
+		Parties:     []*Count{{"PARTIDO B", 2}, {"PARTIDO A", 1}},
+		Topics:      []*Count{{"Direitos das Mulheres", 1}, {"Educação", 1}, {"Saneamento Básico", 1}, {"Saúde", 1}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("want %+v, got %+v", want, stats)
+	}
+
+	stats, err = c.LocationStats(2020, "AL", "")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if stats.Total != 4 || len(stats.Parties) != 3 {
+		t.Errorf("want 4 candidatures of 3 parties in the state, got %d of %d", stats.Total, len(stats.Parties))
+	}
+	if stats, _ := c.LocationStats(2012, "AL", ""); stats.Total != 0 || stats.Roles != nil {
+		t.Errorf("want no candidatures in 2012, got %+v", stats)
+	}
+}
+
+func TestMemoryClientElectionYears(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if years, err := c.ElectionYears(); err != nil || len(years) != 2 || years[0] != 2016 || years[1] != 2020 {
+		t.Errorf("want years 2016 and 2020, got %v (error %v)", years, err)
+	}
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+)
+
+const fixturesDir = "fixtures"
+
+func TestNewMemoryClient(t *testing.T) {
+	if _, err := NewMemoryClient("does-not-exist"); err == nil {
+		t.Errorf("want error when fixtures dir does not exist")
+	}
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	states, err := c.GetStates()
+	if err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if len(states) != 2 {
+		t.Errorf("want 2 states, got %d", len(states))
+	}
+	cities, err := c.GetCities("AL")
+	if err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if len(cities) != 2 || cities[0] != "ARAPIRACA" {
+		t.Errorf("want sorted cities [ARAPIRACA MACEIÓ], got %v", cities)
+	}
+	if _, err := c.GetCities("XX"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+}
+
+func TestMemoryClientGetCandidate(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	candidate, err := c.GetCandidateByEmail("maria.jose@exemplo.com", 2020)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if candidate.SequencialCandidate != "20000000001" {
+		t.Errorf("want candidate 20000000001, got %s", candidate.SequencialCandidate)
+	}
+	if _, err := c.GetCandidateByEmail("maria.jose@exemplo.com", 2016); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	if _, err := c.FindCandidateBySequencialIDAndYear(2020, "20000000003"); err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if _, err := c.FindCandidateBySequencialIDAndYear(2020, "1"); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	candidates, err := c.FindCandidatesByEmail("comite@exemplo.com", 2020)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(candidates) != 2 || candidates[0].SequencialCandidate != "20000000002" || candidates[1].SequencialCandidate != "20000000003" {
+		t.Errorf("want candidates 20000000002 and 20000000003, got %v", candidates)
+	}
+	if candidates, err := c.FindCandidatesByEmail("comite@exemplo.com", 2016); err != nil || len(candidates) != 0 {
+		t.Errorf("want no candidates, got %v (error %v)", candidates, err)
+	}
+}
+
+func TestMemoryClientUpdateCandidateProfile(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	candidate, err := c.FindCandidateBySequencialIDAndYear(2020, "20000000002")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	candidate.Biography = "Frentista"
+	candidate.Proposals = []*descritor.Proposal{{Topic: "Emprego", Description: "Mais empregos."}}
+	if _, err := c.UpdateCandidateProfile(candidate); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	candidate.Proposals[0].Description = "changed after update"
+	stored, err := c.FindCandidateBySequencialIDAndYear(2020, "20000000002")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if stored.Biography != "Frentista" {
+		t.Errorf("want biography Frentista, got %s", stored.Biography)
+	}
+	if stored.Proposals[0].Description != "Mais empregos." {
+		t.Errorf("want stored proposal to be isolated from caller, got %s", stored.Proposals[0].Description)
+	}
+}
+
+func TestMemoryClientFindCandidatures(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	testCases := []struct {
+		name        string
+		query       map[string]interface{}
+		transparent int
+		opaque      int
+	}{
+		{"state", map[string]interface{}{"state": "AL", "year": 2020}, 2, 2},
+		{"city", map[string]interface{}{"state": "AL", "city": "ARAPIRACA", "year": 2020}, 0, 1},
+		{"role", map[string]interface{}{"state": "AL", "role": "prefeito", "year": 2020}, 1, 0},
+		{"tags", map[string]interface{}{"tags": []string{"Educação"}, "year": 2020}, 2, 0},
+		{"name", map[string]interface{}{"name": "maria", "year": 2020}, 1, 0},
+		{"name without accents", map[string]interface{}{"name": "joao", "year": 2020}, 0, 1},
+		{"ballot number", map[string]interface{}{"name": "45", "year": 2020}, 1, 0},
+		{"party name", map[string]interface{}{"name": "partido", "state": "PE", "year": 2020}, 1, 0},
+		{"party", map[string]interface{}{"party": "PARTIDO A", "year": 2020}, 2, 0},
+		{"year", map[string]interface{}{"state": "AL", "year": 2016}, 0, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			transparent, err := c.FindTransparentCandidatures(tc.query, Page{Number: 1, Size: 10})
+			if err != nil {
+				t.Fatalf("want error nil, got %q", err)
+			}
+			if len(transparent.Candidatures) != tc.transparent || transparent.Total != tc.transparent {
+				t.Errorf("want %d transparent candidatures, got %d (total %d)", tc.transparent, len(transparent.Candidatures), transparent.Total)
+			}
+			opaque, err := c.FindNonTransparentCandidatures(tc.query, Page{Number: 1, Size: 10})
+			if err != nil {
+				t.Fatalf("want error nil, got %q", err)
+			}
+			if len(opaque.Candidatures) != tc.opaque || opaque.Total != tc.opaque {
+				t.Errorf("want %d non transparent candidatures, got %d (total %d)", tc.opaque, len(opaque.Candidatures), opaque.Total)
+			}
+		})
+	}
+}
+
+func TestMemoryClientFindCandidaturesPagination(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	query := map[string]interface{}{"year": 2020}
+	fetchAll := func(seed int64) []string {
+		var ids []string
+		for n := 1; n <= 3; n++ {
+			page, err := c.FindTransparentCandidatures(query, Page{Number: n, Size: 1, Seed: seed})
+			if err != nil {
+				t.Fatalf("want error nil, got %q", err)
+			}
+			if page.Total != 3 || page.TotalPages(1) != 3 {
+				t.Fatalf("want 3 candidatures in 3 pages, got %d", page.Total)
+			}
+			if len(page.Candidatures) != 1 {
+				t.Fatalf("want page size to be respected, got %d results", len(page.Candidatures))
+			}
+			ids = append(ids, page.Candidatures[0].SequencialCandidate)
+		}
+		return ids
+	}
+	first := fetchAll(42)
+	if again := fetchAll(42); !reflect.DeepEqual(first, again) {
+		t.Errorf("want the same order for the same seed, got %v and %v", first, again)
+	}
+	seen := make(map[string]bool)
+	for _, id := range first {
+		seen[id] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("want pages to cover all candidatures without repetition, got %v", first)
+	}
+	page, err := c.FindTransparentCandidatures(query, Page{Number: 4, Size: 1, Seed: 42})
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(page.Candidatures) != 0 || page.Total != 3 {
+		t.Errorf("want empty page after the last one, got %d results", len(page.Candidatures))
+	}
+}
+
+func TestMemoryClientFindCandidaturesRanking(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	for seed := int64(0); seed < 5; seed++ {
+		page, err := c.FindTransparentCandidatures(map[string]interface{}{"name": "educacao pedro", "year": 2020}, Page{Number: 1, Size: 10, Seed: seed})
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		if page.Total != 0 {
+			t.Errorf("want proposals not to be searched, got %d results", page.Total)
+		}
+		page, err = c.FindTransparentCandidatures(map[string]interface{}{"name": "antonio", "year": 2020}, Page{Number: 1, Size: 10, Seed: seed})
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		if page.Total != 1 || page.Candidatures[0].SequencialCandidate != "20000000003" {
+			t.Errorf("want DR. ANTÔNIO first, got %+v", page.Candidatures)
+		}
+	}
+}
+
+func TestMemoryClientFindCandidaturesForRanking(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	candidatures, err := c.FindCandidaturesForRanking(map[string]interface{}{"year": 2020, "state": "AL", "city": "MACEIÓ", "tags": []string{"Educação", "Saúde"}})
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(candidatures) != 2 {
+		t.Fatalf("want the 2 transparent candidatures with the topics, got %d", len(candidatures))
+	}
+	for _, candidate := range candidatures {
+		if candidate.Biography != "" || candidate.Proposals[0].Topic == "" || candidate.Proposals[0].Description != "" {
+			t.Errorf("want only the fields needed to rank, got %+v", candidate)
+		}
+	}
+}
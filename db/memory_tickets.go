@@ -0,0 +1,150 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+// AddTicketReport adds the report to the open ticket like t, storing t when there is none.
+func (c *MemoryClient) AddTicketReport(t *Ticket, r *TicketReport) (*Ticket, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ticket *Ticket
+	for _, stored := range c.tickets {
+		if stored.Kind == t.Kind && stored.Category == t.Category && stored.Year == t.Year && stored.SequentialID == t.SequentialID && TicketIsOpen(stored.Status) {
+			ticket = stored
+			break
+		}
+	}
+	if ticket == nil {
+		ticket = copyTicket(t)
+		c.tickets[ticket.ID] = ticket
+	}
+	for _, stored := range ticket.Reports {
+		if stored.Reporter == r.Reporter {
+			return nil, exception.New(exception.Conflict, fmt.Sprintf("Denúncia já registrada no ticket [%s]", ticket.ID), nil)
+		}
+	}
+	aux := *r
+	ticket.Reports = append(ticket.Reports, &aux)
+	ticket.UpdatedAt = r.CreatedAt
+	return copyTicket(ticket), nil
+}
+
+// CreateTicket stores a new ticket.
+func (c *MemoryClient) CreateTicket(t *Ticket) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.tickets[t.ID]; ok {
+		return exception.New(exception.Conflict, fmt.Sprintf("Ticket [%s] já existe", t.ID), nil)
+	}
+	c.tickets[t.ID] = copyTicket(t)
+	return nil
+}
+
+// AddTicketMessage adds the message to the conversation of the ticket.
+func (c *MemoryClient) AddTicketMessage(id string, m *TicketMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tickets[id]
+	if !ok {
+		return exception.New(exception.NotFound, fmt.Sprintf("Ticket [%s] não encontrado", id), nil)
+	}
+	aux := *m
+	t.Messages = append(t.Messages, &aux)
+	t.UpdatedAt = m.CreatedAt
+	return nil
+}
+
+// FindTicket returns the ticket with the given ID.
+func (c *MemoryClient) FindTicket(id string) (*Ticket, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.tickets[id]
+	if !ok {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Ticket [%s] não encontrado", id), nil)
+	}
+	return copyTicket(t), nil
+}
+
+// ListTickets returns up to limit tickets of the kind with the status, the
+// most recently updated first.
+func (c *MemoryClient) ListTickets(kind, status string, limit int) ([]*Ticket, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var tickets []*Ticket
+	for _, t := range c.tickets {
+		if t.Kind == kind && t.Status == status {
+			tickets = append(tickets, copyTicket(t))
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].UpdatedAt.After(tickets[j].UpdatedAt)
+	})
+	if len(tickets) > limit {
+		tickets = tickets[:limit]
+	}
+	return tickets, nil
+}
+
+// ListCandidatureTickets returns the tickets of the kind about the
+// candidature, the most recently updated first.
+func (c *MemoryClient) ListCandidatureTickets(kind string, year int, sequentialID string) ([]*Ticket, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var tickets []*Ticket
+	for _, t := range c.tickets {
+		if t.Kind == kind && t.Year == year && t.SequentialID == sequentialID {
+			tickets = append(tickets, copyTicket(t))
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].UpdatedAt.After(tickets[j].UpdatedAt)
+	})
+	return tickets, nil
+}
+
+// UpdateTicketStatus changes the status and the assignee of the ticket.
+func (c *MemoryClient) UpdateTicketStatus(id, assignee string, e *TicketEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tickets[id]
+	if !ok {
+		return exception.New(exception.NotFound, fmt.Sprintf("Ticket [%s] não encontrado", id), nil)
+	}
+	if t.Kind == TicketProfileReport && !TicketIsOpen(t.Status) && TicketIsOpen(e.Status) {
+		for _, stored := range c.tickets {
+			if stored.Kind == t.Kind && stored.Category == t.Category && stored.Year == t.Year && stored.SequentialID == t.SequentialID && TicketIsOpen(stored.Status) {
+				return exception.New(exception.Conflict, fmt.Sprintf("Já existe outro ticket aberto como o ticket [%s]", id), nil)
+			}
+		}
+	}
+	aux := *e
+	t.Status = e.Status
+	t.Assignee = assignee
+	t.Events = append(t.Events, &aux)
+	t.UpdatedAt = e.CreatedAt
+	return nil
+}
+
+func copyTicket(t *Ticket) *Ticket {
+	cp := *t
+	cp.Reports = make([]*TicketReport, len(t.Reports))
+	for i, r := range t.Reports {
+		aux := *r
+		cp.Reports[i] = &aux
+	}
+	cp.Messages = make([]*TicketMessage, len(t.Messages))
+	for i, m := range t.Messages {
+		aux := *m
+		cp.Messages[i] = &aux
+	}
+	cp.Events = make([]*TicketEvent, len(t.Events))
+	for i, e := range t.Events {
+		aux := *e
+		cp.Events[i] = &aux
+	}
+	return &cp
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestMemoryClientTickets(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	report := func(id, category, reporter string) (*Ticket, error) {
+		ticket := &Ticket{ID: id, Kind: TicketProfileReport, Status: TicketOpen, Category: category, Year: 2020, SequentialID: "20000000001", CreatedAt: now}
+		return c.AddTicketReport(ticket, &TicketReport{CreatedAt: now, Reporter: reporter, Message: "Mentira."})
+	}
+	if ticket, err := report("a", "informacao-falsa", "r1"); err != nil || ticket.ID != "a" {
+		t.Fatalf("want ticket a created, got %+v (error %v)", ticket, err)
+	}
+	if ticket, err := report("b", "informacao-falsa", "r2"); err != nil || ticket.ID != "a" || len(ticket.Reports) != 2 {
+		t.Errorf("want report added to ticket a, got %+v (error %v)", ticket, err)
+	}
+	if _, err := report("c", "informacao-falsa", "r1"); err == nil || err.(*exception.Exception).Code != exception.Conflict {
+		t.Errorf("want conflict error for the same reporter, got %v", err)
+	}
+	if ticket, err := report("d", "conteudo-ofensivo", "r1"); err != nil || ticket.ID != "d" {
+		t.Errorf("want ticket d created for another category, got %+v (error %v)", ticket, err)
+	}
+
+	if err := c.UpdateTicketStatus("a", "operador", &TicketEvent{CreatedAt: now.Add(time.Minute), Actor: "operador", Status: TicketResolved, Note: "Corrigido."}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if ticket, err := c.FindTicket("a"); err != nil || ticket.Status != TicketResolved || ticket.Assignee != "operador" || len(ticket.Events) != 1 {
+		t.Errorf("want ticket a resolved, got %+v (error %v)", ticket, err)
+	}
+	if ticket, err := report("e", "informacao-falsa", "r1"); err != nil || ticket.ID != "e" {
+		t.Errorf("want new ticket once the previous one is resolved, got %+v (error %v)", ticket, err)
+	}
+	open, err := c.ListTickets(TicketProfileReport, TicketOpen, 10)
+	if err != nil || len(open) != 2 {
+		t.Errorf("want 2 open tickets, got %v (error %v)", open, err)
+	}
+	if err := c.UpdateTicketStatus("a", "operador", &TicketEvent{CreatedAt: now, Actor: "operador", Status: TicketInReview}); err == nil || err.(*exception.Exception).Code != exception.Conflict {
+		t.Errorf("want conflict error reopening ticket a while ticket e is open, got %v", err)
+	}
+	if err := c.UpdateTicketStatus("z", "operador", &TicketEvent{Status: TicketResolved}); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+}
+
+func TestMemoryClientTicketMessages(t *testing.T) {
+	c, err := NewMemoryClient(fixturesDir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	now := time.Now()
+	ticket := &Ticket{ID: "a", Kind: TicketContact, Status: TicketOpen, Year: 2020, SequentialID: "20000000001", CreatedAt: now, UpdatedAt: now}
+	if err := c.CreateTicket(ticket); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := c.CreateTicket(ticket); err == nil || err.(*exception.Exception).Code != exception.Conflict {
+		t.Errorf("want conflict error, got %v", err)
+	}
+	other := &Ticket{ID: "b", Kind: TicketContact, Status: TicketOpen, Year: 2020, SequentialID: "20000000001", CreatedAt: now, UpdatedAt: now.Add(time.Minute)}
+	if err := c.CreateTicket(other); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := c.AddTicketMessage("a", &TicketMessage{CreatedAt: now.Add(time.Hour), Author: "operador", FromTeam: true, Text: "Olá!"}); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	tickets, err := c.ListCandidatureTickets(TicketContact, 2020, "20000000001")
+	if err != nil || len(tickets) != 2 || tickets[0].ID != "a" || len(tickets[0].Messages) != 1 {
+		t.Errorf("want ticket a updated by the reply first, got %v (error %v)", tickets, err)
+	}
+	if tickets, _ := c.ListCandidatureTickets(TicketContact, 2020, "20000000002"); len(tickets) != 0 {
+		t.Errorf("want no tickets of another candidature, got %v", tickets)
+	}
+	if err := c.AddTicketMessage("z", &TicketMessage{}); err == nil || err.(*exception.Exception).Code != exception.NotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+}
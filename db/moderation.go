@@ -0,0 +1,69 @@
+package db
+
+import (
+	"time"
+
+	"github.com/candidatos-info/descritor"
+)
+
+// ProfileSubmissionsCollection is the name of the collection of profile
+// changes waiting for moderation.
+const ProfileSubmissionsCollection = "profile_submissions"
+
+// Status of profile submissions.
+const (
+	SubmissionPending    = "pendente"
+	SubmissionApproved   = "aprovada"
+	SubmissionRejected   = "rejeitada"
+	SubmissionSuperseded = "substituida" // a newer submission of the same candidature was sent.
+)
+
+// ProfileSubmission is a change to the biography and proposals of a profile
+// sent by a candidate while pre-moderation is enabled. It is only published
+// after approved.
+type ProfileSubmission struct {
+	ID           string                `bson:"_id" json:"id"`
+	Year         int                   `bson:"year" json:"year"`
+	SequentialID string                `bson:"sequential_id" json:"sequential_id"`
+	Email        string                `bson:"email" json:"email"`
+	SessionID    string                `bson:"session_id" json:"session_id"`
+	CreatedAt    time.Time             `bson:"created_at" json:"created_at"`
+	Status       string                `bson:"status" json:"status"`
+	Biography    string                `bson:"biography" json:"biography"`
+	Proposals    []*descritor.Proposal `bson:"proposals" json:"proposals"`
+	ReviewedBy   string                `bson:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt   time.Time             `bson:"reviewed_at" json:"reviewed_at"`
+	Reason       string                `bson:"reason" json:"reason"` // why the submission was rejected.
+}
+
+// ModerationStore keeps the profile submissions.
+type ModerationStore interface {
+	// CreateSubmission stores a new pending submission. Pending submissions
+	// of the same candidature are superseded by it.
+	CreateSubmission(s *ProfileSubmission) error
+
+	// SupersedeSubmissions marks the pending submissions of the candidature
+	// as superseded, when the profile is saved without moderation.
+	SupersedeSubmissions(year int, sequentialID string) error
+
+	// FindSubmission returns the submission with the given ID.
+	FindSubmission(id string) (*ProfileSubmission, error)
+
+	// FindPendingSubmission returns the pending submission of the
+	// candidature. It fails with exception.NotFound if there is none.
+	FindPendingSubmission(year int, sequentialID string) (*ProfileSubmission, error)
+
+	// ListSubmissions returns up to limit submissions with the status,
+	// oldest first, so the queue is handled in order.
+	ListSubmissions(status string, limit int) ([]*ProfileSubmission, error)
+
+	// ReviewSubmission approves or rejects a pending submission. It fails
+	// with exception.NotFound if the submission does not exist or is not
+	// pending.
+	ReviewSubmission(id, status, reviewedBy, reason string, reviewedAt time.Time) error
+
+	// ReopenSubmission returns an approved submission to the queue, when
+	// publishing it fails. It fails with exception.NotFound if the
+	// submission does not exist or is not approved.
+	ReopenSubmission(id string) error
+}
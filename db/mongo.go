@@ -3,13 +3,13 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/candidatos-info/descritor"
 	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/search"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -73,6 +73,21 @@ func (c *Client) GetCities(state string) ([]string, error) {
 	return location.Cities, nil
 }
 
+// GetLocations returns all states and their cities
+func (c *Client) GetLocations() ([]*descritor.Location, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var locations []*descritor.Location
+	cursor, err := c.client.Database(c.dbName).Collection(descritor.LocationsCollection).Find(ctx, bson.M{}, nil)
+	if err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar localidades do banco na collection [%s], erro %v", descritor.LocationsCollection, err), nil)
+	}
+	if err = cursor.All(ctx, &locations); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar localidades do banco na collection [%s], erro %v", descritor.LocationsCollection, err), nil)
+	}
+	return locations, nil
+}
+
 // GetCandidateByEmail searches for a candidate using email
 func (c *Client) GetCandidateByEmail(email string, year int) (*descritor.CandidateForDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
@@ -85,6 +100,25 @@ func (c *Client) GetCandidateByEmail(email string, year int) (*descritor.Candida
 	return &candidate, nil
 }
 
+// FindCandidatesByEmail returns every candidature registered with the email
+// in the year, ordered by sequential ID.
+func (c *Client) FindCandidatesByEmail(email string, year int) ([]*descritor.CandidateForDB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"email": strings.ToUpper(email), "year": year}
+	opts := options.Find().SetSort(bson.M{"sequencial_candidate": 1})
+	cursor, err := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar candidatos pelo ano [%d] e pelo email [%s] no banco na collection [%s], erro %v", year, email, descritor.CandidaturesCollection, err), nil)
+	}
+	defer cursor.Close(ctx)
+	var candidates []*descritor.CandidateForDB
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar candidatos pelo ano [%d] e pelo email [%s] no banco na collection [%s], erro %v", year, email, descritor.CandidaturesCollection, err), nil)
+	}
+	return candidates, nil
+}
+
 // FindCandidateBySequencialIDAndYear searches for a candidate using its
 // sequencial ID and returns it.
 func (c *Client) FindCandidateBySequencialIDAndYear(year int, sequencialID string) (*descritor.CandidateForDB, error) {
@@ -113,6 +147,7 @@ func (c *Client) UpdateCandidateProfile(candidate *descritor.CandidateForDB) (*d
 			"proposals":      candidate.Proposals,
 			"contacts":       candidate.Contacts,
 			"accepted_terms": candidate.AcceptedTerms,
+			"photo_url":      candidate.PhotoURL,
 		},
 	}
 	if _, err := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection).UpdateOne(ctx, filter, update); err != nil {
@@ -121,26 +156,129 @@ func (c *Client) UpdateCandidateProfile(candidate *descritor.CandidateForDB) (*d
 	return candidate, nil
 }
 
-// FindTransparentCandidatures searches for a list of candidatures with proposals defined
-func (c *Client) FindTransparentCandidatures(queryMap map[string]interface{}, pageSize int) ([]*descritor.CandidateForDB, error) {
+// FindTransparentCandidatures searches for a page of candidatures with proposals defined
+func (c *Client) FindTransparentCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error) {
 	queryMap["proposals"] = bson.M{"$ne": nil} // candidatures without proposals does not count!
-	return c.findCandidatures(queryMap, pageSize)
+	return c.findCandidatures(queryMap, page)
 }
 
-// FindNonTransparentCandidatures searches for non transparent candidatures
-func (c *Client) FindNonTransparentCandidatures(queryMap map[string]interface{}, pageSize int) ([]*descritor.CandidateForDB, error) {
+// FindNonTransparentCandidatures searches for a page of non transparent candidatures
+func (c *Client) FindNonTransparentCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error) {
 	queryMap["proposals"] = bson.M{"$eq": nil} // candidatures without proposals does not count!
-	return c.findCandidatures(queryMap, pageSize)
+	return c.findCandidatures(queryMap, page)
 }
 
-func (c *Client) findCandidatures(queryMap map[string]interface{}, pageSize int) ([]*descritor.CandidateForDB, error) {
+// FindCandidaturesForIndex returns all candidatures filled only with the
+// fields needed to build search indexes.
+func (c *Client) FindCandidaturesForIndex() ([]*descritor.CandidateForDB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4*timeout*time.Second) // it is a full scan.
+	defer cancel()
+	filter, err := c.notDisabledQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+	projection := bson.M{"_id": 0, "sequencial_candidate": 1, "ballot_name": 1, "ballot_number": 1, "name": 1, "party": 1, "role": 1, "state": 1, "city": 1, "year": 1}
+	cursor, err := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection).Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar candidaturas para indexação na collection [%s], erro %v", descritor.CandidaturesCollection, err), nil)
+	}
+	var candidatures []*descritor.CandidateForDB
+	if err := cursor.All(ctx, &candidatures); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar candidaturas para indexação, erro %v", err), nil)
+	}
+	return candidatures, nil
+}
+
+// findCandidatures first fetches the sequencial ID of all candidatures
+// matching the query, shuffles them using the page seed and then fetches only
+// the candidatures of the requested page. Candidatures whose profile was
+// disabled never match.
+func (c *Client) findCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	bsonQuery, err := c.matchConditions(ctx, queryMap)
+	if err != nil {
+		return nil, err
+	}
+	collection := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection)
+	cur, err := collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"$and": bsonQuery}},
+		{"$project": bson.M{"_id": 0, "sequencial_candidate": 1, "ballot_name": 1, "name": 1, "party": 1, "ballot_number": 1}},
+	})
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar candidaturas, erro %v", err), nil)
+	}
+	var matches []*descritor.CandidateForDB
+	if err := cur.All(ctx, &matches); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar IDs de candidaturas a partir da resposta do banco, erro %v", err), nil)
+	}
+	ids := order(matches, queryMap, page.Seed)
+	pageIDs := page.slice(ids)
+	result := &CandidaturesPage{Total: len(ids)}
+	if len(pageIDs) == 0 {
+		return result, nil
+	}
+	cur, err = collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"$and": append(bsonQuery, bson.M{"sequencial_candidate": bson.M{"$in": pageIDs}})}},
+	})
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar candidaturas, erro %v", err), nil)
+	}
+	var candidatures []*descritor.CandidateForDB
+	if err := cur.All(ctx, &candidatures); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar candidaturas a partir da resposta do banco, erro %v", err), nil)
+	}
+	// Restoring the shuffled order, which is lost when querying with $in.
+	bySequencialID := make(map[string]*descritor.CandidateForDB, len(candidatures))
+	for _, candidate := range candidatures {
+		bySequencialID[candidate.SequencialCandidate] = candidate
+	}
+	for _, id := range pageIDs {
+		if candidate, ok := bySequencialID[id]; ok {
+			result.Candidatures = append(result.Candidatures, candidate)
+		}
+	}
+	return result, nil
+}
+
+// FindCandidaturesForRanking returns every transparent candidature matching
+// the query filled only with the fields needed to rank them.
+func (c *Client) FindCandidaturesForRanking(queryMap map[string]interface{}) ([]*descritor.CandidateForDB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	queryMap["proposals"] = bson.M{"$ne": nil}
+	bsonQuery, err := c.matchConditions(ctx, queryMap)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection).Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"$and": bsonQuery}},
+		{"$project": bson.M{"_id": 0, "sequencial_candidate": 1, "year": 1, "ballot_name": 1, "gender": 1, "party": 1, "proposals.topic": 1}},
+	})
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar candidaturas para ranqueamento, erro %v", err), nil)
+	}
+	var candidatures []*descritor.CandidateForDB
+	if err := cur.All(ctx, &candidatures); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar candidaturas para ranqueamento, erro %v", err), nil)
+	}
+	return candidatures, nil
+}
+
+// matchConditions converts the query to the conditions of a $match stage,
+// adding the one that leaves out the candidatures whose profile was disabled.
+func (c *Client) matchConditions(ctx context.Context, queryMap map[string]interface{}) ([]bson.M, error) {
 	// Convert query in bson slice to be used in the match primitive.
 	// IMPORTANT: we are using match because the atlas free tier does not support filter.
 	var bsonQuery []bson.M
 	for k, v := range queryMap {
 		switch k {
 		case "name":
-			bsonQuery = append(bsonQuery, bson.M{"ballot_name": bson.M{"$regex": primitive.Regex{Pattern: fmt.Sprintf(".*%s.*", queryMap["name"]), Options: "i"}}})
+			name, _ := v.(string)
+			bsonQuery = append(bsonQuery, nameQuery(name)...)
 		case "tags":
 			if tags, ok := queryMap["tags"].([]string); ok && len(tags) > 0 {
 				bsonQuery = append(bsonQuery, bson.M{"proposals.topic": bson.M{"$in": tags}})
@@ -149,26 +287,36 @@ func (c *Client) findCandidatures(queryMap map[string]interface{}, pageSize int)
 			bsonQuery = append(bsonQuery, bson.M{k: v})
 		}
 	}
-	db := c.client.Database(c.dbName)
-	cur, err := db.Collection(descritor.CandidaturesCollection).Aggregate(context.Background(), []bson.M{
-		bson.M{"$match": bson.M{"$and": bsonQuery}},
-		bson.M{"$sample": bson.M{"size": pageSize}},
-	})
+	notDisabled, err := c.notDisabledQuery(ctx)
 	if err != nil {
-		log.Fatal(err)
-	}
-	var results []*descritor.CandidateForDB
-	for cur.Next(context.TODO()) {
-		var c *descritor.CandidateForDB
-		err := cur.Decode(&c)
-		if err != nil {
-			return nil, fmt.Errorf(fmt.Sprintf("Falha ao deserializar struct de candidatura não transparente a partir da resposta do banco, erro %v", err))
-		}
-		results = append(results, c)
+		return nil, err
 	}
+	if notDisabled != nil {
+		bsonQuery = append(bsonQuery, notDisabled)
+	}
+	return bsonQuery, nil
+}
 
-	if err := cur.Err(); err != nil {
-		return nil, fmt.Errorf(fmt.Sprintf("Erro no cursor de candidatura não transparente, erro %v", err))
+// nameQuery returns the conditions to find candidatures matching every token
+// of the name query in its ballot name, name, party or ballot number. The
+// final ranking is done by the search package.
+func nameQuery(name string) []bson.M {
+	var conditions []bson.M
+	for _, token := range search.QueryTokens(name) {
+		regex := primitive.Regex{Pattern: search.Pattern(token), Options: "i"}
+		or := []bson.M{
+			{"ballot_name": bson.M{"$regex": regex}},
+			{"name": bson.M{"$regex": regex}},
+			{"party": bson.M{"$regex": regex}},
+		}
+		if n, ok := search.Number(token); ok {
+			or = append(or, bson.M{"ballot_number": n})
+		}
+		conditions = append(conditions, bson.M{"$or": or})
+	}
+	if len(conditions) == 0 {
+		// Nothing searchable in the query (e.g. only punctuation): no results.
+		conditions = append(conditions, bson.M{"sequencial_candidate": bson.M{"$exists": false}})
 	}
-	return results, nil
+	return conditions
 }
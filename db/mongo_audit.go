@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AddAuditEntry stores a new audit entry.
+func (c *Client) AddAuditEntry(e *AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	if _, err := c.client.Database(c.dbName).Collection(AuditCollection).InsertOne(ctx, e); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar registro de auditoria, erro %v", err), nil)
+	}
+	return nil
+}
+
+// ListAuditEntries returns up to limit entries of the candidature, newest first.
+func (c *Client) ListAuditEntries(year int, sequentialID string, limit int) ([]*AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{}
+	if year != 0 {
+		filter["year"] = year
+	}
+	if sequentialID != "" {
+		filter["sequential_id"] = sequentialID
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(limit))
+	cursor, err := c.client.Database(c.dbName).Collection(AuditCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar registros de auditoria, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var entries []*AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar registros de auditoria, erro %v", err), nil)
+	}
+	return entries, nil
+}
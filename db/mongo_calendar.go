@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindCalendar returns the calendar of the election year.
+func (c *Client) FindCalendar(year int) (*Calendar, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var cal Calendar
+	err := c.client.Database(c.dbName).Collection(CalendarsCollection).FindOne(ctx, bson.M{"_id": year}).Decode(&cal)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Calendário de %d não encontrado", year), nil)
+	case err != nil:
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar calendário de %d, erro %v", year, err), nil)
+	}
+	return &cal, nil
+}
+
+// SaveCalendar stores the calendar, replacing the one of the same year.
+func (c *Client) SaveCalendar(cal *Calendar) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	opts := options.Replace().SetUpsert(true)
+	if _, err := c.client.Database(c.dbName).Collection(CalendarsCollection).ReplaceOne(ctx, bson.M{"_id": cal.Year}, cal, opts); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar calendário de %d, erro %v", cal.Year, err), nil)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DisableProfile stores the disabled profile record.
+func (c *Client) DisableProfile(p *DisabledProfile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	aux := *p
+	aux.ID = DisabledProfileID(p.Year, p.SequentialID)
+	opts := options.Replace().SetUpsert(true)
+	if _, err := c.client.Database(c.dbName).Collection(DisabledProfilesCollection).ReplaceOne(ctx, bson.M{"_id": aux.ID}, &aux, opts); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao desativar perfil [%s], erro %v", aux.ID, err), nil)
+	}
+	return nil
+}
+
+// EnableProfile removes the disabled profile record of the candidature.
+func (c *Client) EnableProfile(year int, sequentialID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	id := DisabledProfileID(year, sequentialID)
+	res, err := c.client.Database(c.dbName).Collection(DisabledProfilesCollection).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao reativar perfil [%s], erro %v", id, err), nil)
+	}
+	if res.DeletedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Perfil [%s] não está desativado", id), nil)
+	}
+	return nil
+}
+
+// FindDisabledProfile returns the disabled profile record of the candidature.
+func (c *Client) FindDisabledProfile(year int, sequentialID string) (*DisabledProfile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	id := DisabledProfileID(year, sequentialID)
+	var p DisabledProfile
+	err := c.client.Database(c.dbName).Collection(DisabledProfilesCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&p)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Perfil [%s] não está desativado", id), nil)
+	case err != nil:
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar perfil desativado [%s], erro %v", id, err), nil)
+	}
+	return &p, nil
+}
+
+// notDisabledQuery returns the condition that leaves out the candidatures
+// whose profile was disabled, nil when no profile is disabled. Disabled
+// profiles are few, so they are all read at once.
+func (c *Client) notDisabledQuery(ctx context.Context) (bson.M, error) {
+	cursor, err := c.client.Database(c.dbName).Collection(DisabledProfilesCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar perfis desativados, erro %v", err), nil)
+	}
+	var profiles []*DisabledProfile
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar perfis desativados, erro %v", err), nil)
+	}
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	byYear := make(map[int][]string)
+	for _, p := range profiles {
+		byYear[p.Year] = append(byYear[p.Year], p.SequentialID)
+	}
+	var nor []bson.M
+	for year, sequentialIDs := range byYear {
+		nor = append(nor, bson.M{"year": year, "sequencial_candidate": bson.M{"$in": sequentialIDs}})
+	}
+	return bson.M{"$nor": nor}, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type collectionIndex struct {
+	collection string
+	model      mongo.IndexModel
+}
+
+// Indexes the stores rely on for correctness, not only for speed.
+var indexes = []collectionIndex{
+	// Concurrent submissions with the same key can not queue the email twice.
+	{OutboxCollection, mongo.IndexModel{
+		Keys:    bson.M{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	}},
+	// Concurrent reports can not open two tickets of the same category about
+	// the same candidature.
+	{TicketsCollection, mongo.IndexModel{
+		Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "category", Value: 1}, {Key: "year", Value: 1}, {Key: "sequential_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"kind": TicketProfileReport, "open": true}),
+	}},
+	// Rate limit counters are removed once expired.
+	{RateLimitsCollection, mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}},
+}
+
+// EnsureIndexes creates the indexes of the collections. Creating an index
+// that already exists is a no-op, so it is safe to call on every start.
+func (c *Client) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	db := c.client.Database(c.dbName)
+	for _, i := range indexes {
+		if _, err := db.Collection(i.collection).Indexes().CreateOne(ctx, i.model); err != nil {
+			return fmt.Errorf("failed to create index on collection [%s], error %v", i.collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateSubmission stores a new pending submission.
+func (c *Client) CreateSubmission(s *ProfileSubmission) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	if err := c.SupersedeSubmissions(s.Year, s.SequentialID); err != nil {
+		return err
+	}
+	if _, err := c.client.Database(c.dbName).Collection(ProfileSubmissionsCollection).InsertOne(ctx, s); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar submissão, erro %v", err), nil)
+	}
+	return nil
+}
+
+// SupersedeSubmissions marks the pending submissions of the candidature as
+// superseded.
+func (c *Client) SupersedeSubmissions(year int, sequentialID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"year": year, "sequential_id": sequentialID, "status": SubmissionPending}
+	if _, err := c.client.Database(c.dbName).Collection(ProfileSubmissionsCollection).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"status": SubmissionSuperseded}}); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao substituir submissões pendentes, erro %v", err), nil)
+	}
+	return nil
+}
+
+// FindSubmission returns the submission with the given ID.
+func (c *Client) FindSubmission(id string) (*ProfileSubmission, error) {
+	return c.findSubmission(bson.M{"_id": id})
+}
+
+// FindPendingSubmission returns the pending submission of the candidature.
+func (c *Client) FindPendingSubmission(year int, sequentialID string) (*ProfileSubmission, error) {
+	return c.findSubmission(bson.M{"year": year, "sequential_id": sequentialID, "status": SubmissionPending})
+}
+
+func (c *Client) findSubmission(filter bson.M) (*ProfileSubmission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var s ProfileSubmission
+	if err := c.client.Database(c.dbName).Collection(ProfileSubmissionsCollection).FindOne(ctx, filter).Decode(&s); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar submissão %v, erro %v", filter, err), nil)
+	}
+	return &s, nil
+}
+
+// ListSubmissions returns up to limit submissions with the status, oldest first.
+func (c *Client) ListSubmissions(status string, limit int) ([]*ProfileSubmission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(int64(limit))
+	cursor, err := c.client.Database(c.dbName).Collection(ProfileSubmissionsCollection).Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar submissões, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var submissions []*ProfileSubmission
+	if err := cursor.All(ctx, &submissions); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar submissões, erro %v", err), nil)
+	}
+	return submissions, nil
+}
+
+// ReviewSubmission approves or rejects a pending submission.
+func (c *Client) ReviewSubmission(id, status, reviewedBy, reason string, reviewedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	// The filter guarantees the submission is reviewed only once.
+	filter := bson.M{"_id": id, "status": SubmissionPending}
+	update := bson.M{"$set": bson.M{"status": status, "reviewed_by": reviewedBy, "reason": reason, "reviewed_at": reviewedAt}}
+	res, err := c.client.Database(c.dbName).Collection(ProfileSubmissionsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao moderar submissão [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Submissão [%s] não encontrada ou já moderada", id), nil)
+	}
+	return nil
+}
+
+// ReopenSubmission returns an approved submission to the queue.
+func (c *Client) ReopenSubmission(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "status": SubmissionApproved}
+	update := bson.M{"$set": bson.M{"status": SubmissionPending, "reviewed_by": "", "reviewed_at": time.Time{}}}
+	res, err := c.client.Database(c.dbName).Collection(ProfileSubmissionsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao reabrir submissão [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Submissão [%s] não encontrada ou não aprovada", id), nil)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateOperator stores a new operator.
+func (c *Client) CreateOperator(o *Operator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	// Upserting on the ID makes an existing operator a no-op.
+	res, err := c.client.Database(c.dbName).Collection(OperatorsCollection).UpdateOne(ctx, bson.M{"_id": o.ID}, bson.M{"$setOnInsert": o}, options.Update().SetUpsert(true))
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar operador [%s], erro %v", o.ID, err), nil)
+	}
+	if res.UpsertedCount == 0 {
+		return exception.New(exception.Conflict, fmt.Sprintf("Operador [%s] já existe", o.ID), nil)
+	}
+	return nil
+}
+
+// FindOperator returns the operator with the given ID.
+func (c *Client) FindOperator(id string) (*Operator, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var o Operator
+	if err := c.client.Database(c.dbName).Collection(OperatorsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&o); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar operador [%s], erro %v", id, err), nil)
+	}
+	return &o, nil
+}
+
+// ListOperators returns all operators ordered by ID.
+func (c *Client) ListOperators() ([]*Operator, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	cursor, err := c.client.Database(c.dbName).Collection(OperatorsCollection).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar operadores, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var operators []*Operator
+	if err := cursor.All(ctx, &operators); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar operadores, erro %v", err), nil)
+	}
+	return operators, nil
+}
+
+// DisableOperator disables an operator.
+func (c *Client) DisableOperator(id string, disabledAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	collection := c.client.Database(c.dbName).Collection(OperatorsCollection)
+	res, err := collection.UpdateOne(ctx, bson.M{"_id": id, "disabled_at": time.Time{}}, bson.M{"$set": bson.M{"disabled_at": disabledAt}})
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao desativar operador [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		if _, err := c.FindOperator(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnqueueEmail stores a new queued email.
+func (c *Client) EnqueueEmail(e *OutboxEmail) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	collection := c.client.Database(c.dbName).Collection(OutboxCollection)
+	if e.IdempotencyKey == "" {
+		if _, err := collection.InsertOne(ctx, e); err != nil {
+			return false, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao enfileirar email, erro %v", err), nil)
+		}
+		return true, nil
+	}
+	// Upserting on the key makes repeated keys a no-op. Concurrent upserts
+	// of the same key are rejected by its unique index, see EnsureIndexes.
+	filter := bson.M{"idempotency_key": e.IdempotencyKey}
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$setOnInsert": e}, options.Update().SetUpsert(true))
+	if err != nil && isDuplicateKey(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao enfileirar email (%s), erro %v", e.IdempotencyKey, err), nil)
+	}
+	return res.UpsertedCount == 1, nil
+}
+
+// ClaimDueEmails returns up to limit queued emails whose next attempt is due.
+func (c *Client) ClaimDueEmails(now time.Time, lease time.Duration, limit int) ([]*OutboxEmail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	collection := c.client.Database(c.dbName).Collection(OutboxCollection)
+	filter := bson.M{"status": EmailQueued, "next_attempt_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After)
+	var claimed []*OutboxEmail
+	for len(claimed) < limit {
+		var e OutboxEmail
+		err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&e)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return claimed, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar emails a enviar, erro %v", err), nil)
+		}
+		claimed = append(claimed, &e)
+	}
+	return claimed, nil
+}
+
+// MarkEmailSent records the email was sent.
+func (c *Client) MarkEmailSent(id string, sentAt time.Time) error {
+	return c.updateOutboxEmail(id, bson.M{
+		"$set": bson.M{"status": EmailSent, "sent_at": sentAt},
+		"$inc": bson.M{"attempts": 1},
+	})
+}
+
+// MarkEmailFailed records a failed attempt.
+func (c *Client) MarkEmailFailed(id string, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	set := bson.M{"attempts": attempts, "last_error": lastError, "next_attempt_at": nextAttemptAt}
+	if dead {
+		set["status"] = EmailDead
+	}
+	return c.updateOutboxEmail(id, bson.M{"$set": set})
+}
+
+// ListEmails returns up to limit emails with the status, newest first.
+func (c *Client) ListEmails(status string, limit int) ([]*OutboxEmail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(limit))
+	cursor, err := c.client.Database(c.dbName).Collection(OutboxCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar emails, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var emails []*OutboxEmail
+	if err := cursor.All(ctx, &emails); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar emails, erro %v", err), nil)
+	}
+	return emails, nil
+}
+
+// RequeueEmail puts a dead email back in the queue.
+func (c *Client) RequeueEmail(id string, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "status": EmailDead}
+	update := bson.M{"$set": bson.M{"status": EmailQueued, "attempts": 0, "next_attempt_at": now}}
+	res, err := c.client.Database(c.dbName).Collection(OutboxCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao reenfileirar email [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Email [%s] não encontrado ou não está com falha", id), nil)
+	}
+	return nil
+}
+
+func (c *Client) updateOutboxEmail(id string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	res, err := c.client.Database(c.dbName).Collection(OutboxCollection).UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao atualizar email [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Email [%s] não encontrado", id), nil)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreatePhotoSubmission stores a new pending photo.
+func (c *Client) CreatePhotoSubmission(s *PhotoSubmission) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	collection := c.client.Database(c.dbName).Collection(PhotoSubmissionsCollection)
+	filter := bson.M{"year": s.Year, "sequential_id": s.SequentialID, "status": SubmissionPending}
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"status": SubmissionSuperseded}}); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao substituir fotos pendentes, erro %v", err), nil)
+	}
+	if _, err := collection.InsertOne(ctx, s); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar foto, erro %v", err), nil)
+	}
+	return nil
+}
+
+// FindPhotoSubmission returns the photo submission with the given ID.
+func (c *Client) FindPhotoSubmission(id string) (*PhotoSubmission, error) {
+	return c.findPhotoSubmission(bson.M{"_id": id})
+}
+
+// FindPendingPhotoSubmission returns the pending photo of the candidature.
+func (c *Client) FindPendingPhotoSubmission(year int, sequentialID string) (*PhotoSubmission, error) {
+	return c.findPhotoSubmission(bson.M{"year": year, "sequential_id": sequentialID, "status": SubmissionPending})
+}
+
+func (c *Client) findPhotoSubmission(filter bson.M) (*PhotoSubmission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var s PhotoSubmission
+	if err := c.client.Database(c.dbName).Collection(PhotoSubmissionsCollection).FindOne(ctx, filter).Decode(&s); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar foto %v, erro %v", filter, err), nil)
+	}
+	return &s, nil
+}
+
+// ListPhotoSubmissions returns up to limit photo submissions with the status,
+// oldest first.
+func (c *Client) ListPhotoSubmissions(status string, limit int) ([]*PhotoSubmission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(int64(limit))
+	cursor, err := c.client.Database(c.dbName).Collection(PhotoSubmissionsCollection).Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar fotos, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var photos []*PhotoSubmission
+	if err := cursor.All(ctx, &photos); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar fotos, erro %v", err), nil)
+	}
+	return photos, nil
+}
+
+// ReviewPhotoSubmission approves or rejects a pending photo.
+func (c *Client) ReviewPhotoSubmission(id, status, reviewedBy, reason string, reviewedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	// The filter guarantees the photo is reviewed only once.
+	filter := bson.M{"_id": id, "status": SubmissionPending}
+	update := bson.M{"$set": bson.M{"status": status, "reviewed_by": reviewedBy, "reason": reason, "reviewed_at": reviewedAt}}
+	res, err := c.client.Database(c.dbName).Collection(PhotoSubmissionsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao moderar foto [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Foto [%s] não encontrada ou já moderada", id), nil)
+	}
+	return nil
+}
+
+// ReopenPhotoSubmission returns an approved photo to the queue.
+func (c *Client) ReopenPhotoSubmission(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "status": SubmissionApproved}
+	update := bson.M{"$set": bson.M{"status": SubmissionPending, "reviewed_by": "", "reviewed_at": time.Time{}}}
+	res, err := c.client.Database(c.dbName).Collection(PhotoSubmissionsCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao reabrir foto [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Foto [%s] não encontrada ou não aprovada", id), nil)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IncrementRateCounter increments the counter identified by key and returns
+// its new value.
+func (c *Client) IncrementRateCounter(key string, expiresAt time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expires_at": expiresAt},
+	}
+	var counter RateCounter
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := c.client.Database(c.dbName).Collection(RateLimitsCollection).FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&counter); err != nil {
+		return 0, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao incrementar contador [%s], erro %v", key, err), nil)
+	}
+	return counter.Count, nil
+}
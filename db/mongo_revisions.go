@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Concurrent updates of the same profile may try to store revisions with the
+// same number, the later ones are retried with the next number.
+const maxRevisionAttempts = 3
+
+// AddProfileRevision stores a new revision as the latest one of the candidature.
+func (c *Client) AddProfileRevision(r *ProfileRevision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	collection := c.client.Database(c.dbName).Collection(ProfileRevisionsCollection)
+	filter := bson.M{"year": r.Year, "sequential_id": r.SequentialID}
+	for attempt := 1; ; attempt++ {
+		var latest ProfileRevision
+		err := collection.FindOne(ctx, filter, options.FindOne().SetSort(bson.M{"number": -1})).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar última revisão, erro %v", err), nil)
+		}
+		r.Number = latest.Number + 1
+		r.ID = ProfileRevisionID(r.Year, r.SequentialID, r.Number)
+		_, err = collection.InsertOne(ctx, r)
+		if err == nil {
+			return nil
+		}
+		if !isDuplicateKey(err) || attempt == maxRevisionAttempts {
+			return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar revisão [%s], erro %v", r.ID, err), nil)
+		}
+	}
+}
+
+func isDuplicateKey(err error) bool {
+	if ce, ok := err.(mongo.CommandError); ok { // as returned by FindOneAndUpdate.
+		return ce.Code == 11000
+	}
+	we, ok := err.(mongo.WriteException)
+	if !ok {
+		return false
+	}
+	for _, e := range we.WriteErrors {
+		if e.Code == 11000 {
+			return true
+		}
+	}
+	return false
+}
+
+// ListProfileRevisions returns the revisions of the candidature, newest first.
+func (c *Client) ListProfileRevisions(year int, sequentialID string) ([]*ProfileRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"year": year, "sequential_id": sequentialID}
+	cursor, err := c.client.Database(c.dbName).Collection(ProfileRevisionsCollection).Find(ctx, filter, options.Find().SetSort(bson.M{"number": -1}))
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar revisões, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var revisions []*ProfileRevision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar revisões, erro %v", err), nil)
+	}
+	return revisions, nil
+}
+
+// FindProfileRevision returns a revision of the candidature.
+func (c *Client) FindProfileRevision(year int, sequentialID string, number int) (*ProfileRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	id := ProfileRevisionID(year, sequentialID, number)
+	var r ProfileRevision
+	if err := c.client.Database(c.dbName).Collection(ProfileRevisionsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&r); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar revisão [%s], erro %v", id, err), nil)
+	}
+	return &r, nil
+}
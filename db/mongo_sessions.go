@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateLoginToken stores a new login token.
+func (c *Client) CreateLoginToken(t *LoginToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	if _, err := c.client.Database(c.dbName).Collection(LoginTokensCollection).InsertOne(ctx, t); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar token de acesso, erro %v", err), nil)
+	}
+	return nil
+}
+
+// UseLoginToken marks the login token as used and returns it.
+func (c *Client) UseLoginToken(id string, usedAt time.Time, ip, userAgent string) (*LoginToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	// The filter guarantees the token is used only once, even with concurrent requests.
+	filter := bson.M{
+		"_id":        id,
+		"used_at":    time.Time{},
+		"revoked_at": time.Time{},
+		"expires_at": bson.M{"$gt": usedAt},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"used_at":         usedAt,
+			"used_ip":         ip,
+			"used_user_agent": userAgent,
+		},
+	}
+	var t LoginToken
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := c.client.Database(c.dbName).Collection(LoginTokensCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&t); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Token de acesso [%s] inexistente, expirado ou já utilizado, erro %v", id, err), nil)
+	}
+	return &t, nil
+}
+
+// CreateSession stores a new session.
+func (c *Client) CreateSession(s *Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	if _, err := c.client.Database(c.dbName).Collection(SessionsCollection).InsertOne(ctx, s); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar sessão, erro %v", err), nil)
+	}
+	return nil
+}
+
+// FindSession returns the session with the given ID.
+func (c *Client) FindSession(id string) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var s Session
+	if err := c.client.Database(c.dbName).Collection(SessionsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&s); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar sessão [%s], erro %v", id, err), nil)
+	}
+	return &s, nil
+}
+
+// RevokeSession revokes a single session.
+func (c *Client) RevokeSession(id string, revokedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "revoked_at": time.Time{}}
+	if _, err := c.client.Database(c.dbName).Collection(SessionsCollection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": revokedAt}}); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao revogar sessão [%s], erro %v", id, err), nil)
+	}
+	return nil
+}
+
+// RevokeAllByEmail revokes every session and unused login token issued to the email.
+func (c *Client) RevokeAllByEmail(email string, revokedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	db := c.client.Database(c.dbName)
+	filter := bson.M{"email": strings.ToLower(email), "revoked_at": time.Time{}}
+	update := bson.M{"$set": bson.M{"revoked_at": revokedAt}}
+	if _, err := db.Collection(SessionsCollection).UpdateMany(ctx, filter, update); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao revogar sessões do email [%s], erro %v", email, err), nil)
+	}
+	filter["used_at"] = time.Time{}
+	if _, err := db.Collection(LoginTokensCollection).UpdateMany(ctx, filter, update); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao revogar tokens de acesso do email [%s], erro %v", email, err), nil)
+	}
+	return nil
+}
+
+// RevokeAllByCandidature revokes every session and unused login token giving
+// access to the candidature.
+func (c *Client) RevokeAllByCandidature(year int, sequentialID string, revokedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	db := c.client.Database(c.dbName)
+	filter := bson.M{"year": year, "sequential_id": sequentialID, "revoked_at": time.Time{}}
+	update := bson.M{"$set": bson.M{"revoked_at": revokedAt}}
+	if _, err := db.Collection(SessionsCollection).UpdateMany(ctx, filter, update); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao revogar sessões da candidatura [%s/%d], erro %v", sequentialID, year, err), nil)
+	}
+	filter["used_at"] = time.Time{}
+	if _, err := db.Collection(LoginTokensCollection).UpdateMany(ctx, filter, update); err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao revogar tokens de acesso da candidatura [%s/%d], erro %v", sequentialID, year, err), nil)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// LocationStats returns the statistics of the candidatures of the state in
+// the year or, if city is not empty, of the city. All counts are computed by
+// a single aggregation, with one $facet for each of them.
+func (c *Client) LocationStats(year int, state, city string) (*LocationStats, error) {
+	match := bson.M{"year": year, "state": state}
+	if city != "" {
+		match["city"] = city
+	}
+	countBy := func(field string) []bson.M {
+		return []bson.M{{"$group": bson.M{"_id": "$" + field, "total": bson.M{"$sum": 1}}}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*timeout*time.Second) // a state may have many candidatures.
+	defer cancel()
+	cur, err := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection).Aggregate(ctx, []bson.M{
+		{"$match": match},
+		{"$facet": bson.M{
+			"total":       []bson.M{{"$count": "total"}},
+			"transparent": []bson.M{{"$match": bson.M{"proposals": bson.M{"$ne": nil}}}, {"$count": "total"}},
+			"roles":       countBy("role"),
+			"genders":     countBy("gender"),
+			"parties":     countBy("party"),
+			// Candidatures are counted once for each topic, even with many
+			// proposals in it.
+			"topics": []bson.M{
+				{"$unwind": "$proposals"},
+				{"$group": bson.M{"_id": bson.M{"candidate": "$sequencial_candidate", "topic": "$proposals.topic"}}},
+				{"$group": bson.M{"_id": "$_id.topic", "total": bson.M{"$sum": 1}}},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao calcular estatísticas de %s/%s em %d, erro %v", city, state, year, err), nil)
+	}
+	var facets []struct {
+		Total       []*Count `bson:"total"`
+		Transparent []*Count `bson:"transparent"`
+		Roles       []*Count `bson:"roles"`
+		Genders     []*Count `bson:"genders"`
+		Parties     []*Count `bson:"parties"`
+		Topics      []*Count `bson:"topics"`
+	}
+	if err := cur.All(ctx, &facets); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar estatísticas de %s/%s em %d, erro %v", city, state, year, err), nil)
+	}
+	stats := &LocationStats{}
+	if len(facets) == 0 {
+		return stats, nil
+	}
+	f := facets[0]
+	if len(f.Total) > 0 {
+		stats.Total = f.Total[0].Total
+	}
+	if len(f.Transparent) > 0 {
+		stats.Transparent = f.Transparent[0].Total
+	}
+	stats.Roles, stats.Genders, stats.Parties, stats.Topics = f.Roles, f.Genders, f.Parties, f.Topics
+	for _, counts := range [][]*Count{stats.Roles, stats.Genders, stats.Parties, stats.Topics} {
+		sortCounts(counts)
+	}
+	return stats, nil
+}
+
+// ElectionYears returns the years of the elections with candidatures.
+func (c *Client) ElectionYears() ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	values, err := c.client.Database(c.dbName).Collection(descritor.CandidaturesCollection).Distinct(ctx, "year", bson.M{})
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao buscar anos das eleições, erro %v", err), nil)
+	}
+	var years []int
+	for _, v := range values {
+		switch year := v.(type) {
+		case int32:
+			years = append(years, int(year))
+		case int64:
+			years = append(years, int(year))
+		case float64:
+			years = append(years, int(year))
+		}
+	}
+	sort.Ints(years)
+	return years, nil
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/site/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Open tickets of reports are flagged with the open field, kept only by this
+// client, so the unique index that keeps them from being opened twice can be a
+// partial index.
+
+// AddTicketReport adds the report to the open ticket like t, storing t when there is none.
+func (c *Client) AddTicketReport(t *Ticket, r *TicketReport) (*Ticket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	collection := c.client.Database(c.dbName).Collection(TicketsCollection)
+	// The fields of the filter compared by equality are copied to the new
+	// ticket on insert.
+	filter := bson.M{
+		"kind":             t.Kind,
+		"category":         t.Category,
+		"year":             t.Year,
+		"sequential_id":    t.SequentialID,
+		"open":             true,
+		"reports.reporter": bson.M{"$ne": r.Reporter},
+	}
+	update := bson.M{
+		"$push": bson.M{"reports": r},
+		"$set":  bson.M{"updated_at": r.CreatedAt},
+		"$setOnInsert": bson.M{
+			"_id":        t.ID,
+			"status":     t.Status,
+			"messages":   []*TicketMessage{},
+			"events":     []*TicketEvent{},
+			"assignee":   t.Assignee,
+			"created_at": t.CreatedAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	// A duplicate key means the open ticket exists but was not matched:
+	// either the reporter is already in it or it was just stored by a
+	// concurrent report, which is matched when trying again.
+	for attempt := 0; ; attempt++ {
+		var ticket Ticket
+		err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&ticket)
+		switch {
+		case err == nil:
+			return &ticket, nil
+		case isDuplicateKey(err) && attempt == 0:
+			continue
+		case isDuplicateKey(err):
+			return nil, exception.New(exception.Conflict, "Denúncia já registrada", nil)
+		default:
+			return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar denúncia, erro %v", err), nil)
+		}
+	}
+}
+
+// CreateTicket stores a new ticket.
+func (c *Client) CreateTicket(t *Ticket) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	_, err := c.client.Database(c.dbName).Collection(TicketsCollection).InsertOne(ctx, t)
+	switch {
+	case err != nil && isDuplicateKey(err):
+		return exception.New(exception.Conflict, fmt.Sprintf("Ticket [%s] já existe", t.ID), nil)
+	case err != nil:
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao salvar ticket [%s], erro %v", t.ID, err), nil)
+	}
+	return nil
+}
+
+// AddTicketMessage adds the message to the conversation of the ticket.
+func (c *Client) AddTicketMessage(id string, m *TicketMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	update := bson.M{
+		"$set":  bson.M{"updated_at": m.CreatedAt},
+		"$push": bson.M{"messages": m},
+	}
+	res, err := c.client.Database(c.dbName).Collection(TicketsCollection).UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao adicionar mensagem ao ticket [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Ticket [%s] não encontrado", id), nil)
+	}
+	return nil
+}
+
+// FindTicket returns the ticket with the given ID.
+func (c *Client) FindTicket(id string) (*Ticket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var t Ticket
+	if err := c.client.Database(c.dbName).Collection(TicketsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&t); err != nil {
+		return nil, exception.New(exception.NotFound, fmt.Sprintf("Falha ao buscar ticket [%s], erro %v", id, err), nil)
+	}
+	return &t, nil
+}
+
+// ListTickets returns up to limit tickets of the kind with the status, the
+// most recently updated first.
+func (c *Client) ListTickets(kind, status string, limit int) ([]*Ticket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.M{"updated_at": -1}).SetLimit(int64(limit))
+	cursor, err := c.client.Database(c.dbName).Collection(TicketsCollection).Find(ctx, bson.M{"kind": kind, "status": status}, opts)
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar tickets, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var tickets []*Ticket
+	if err := cursor.All(ctx, &tickets); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar tickets, erro %v", err), nil)
+	}
+	return tickets, nil
+}
+
+// ListCandidatureTickets returns the tickets of the kind about the
+// candidature, the most recently updated first.
+func (c *Client) ListCandidatureTickets(kind string, year int, sequentialID string) ([]*Ticket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := bson.M{"kind": kind, "year": year, "sequential_id": sequentialID}
+	cursor, err := c.client.Database(c.dbName).Collection(TicketsCollection).Find(ctx, filter, options.Find().SetSort(bson.M{"updated_at": -1}))
+	if err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao listar tickets da candidatura, erro %v", err), nil)
+	}
+	defer cursor.Close(ctx)
+	var tickets []*Ticket
+	if err := cursor.All(ctx, &tickets); err != nil {
+		return nil, exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao deserializar tickets, erro %v", err), nil)
+	}
+	return tickets, nil
+}
+
+// UpdateTicketStatus changes the status and the assignee of the ticket.
+func (c *Client) UpdateTicketStatus(id, assignee string, e *TicketEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	update := bson.M{
+		"$set":  bson.M{"status": e.Status, "open": TicketIsOpen(e.Status), "assignee": assignee, "updated_at": e.CreatedAt},
+		"$push": bson.M{"events": e},
+	}
+	res, err := c.client.Database(c.dbName).Collection(TicketsCollection).UpdateOne(ctx, bson.M{"_id": id}, update)
+	switch {
+	case err != nil && isDuplicateKey(err):
+		return exception.New(exception.Conflict, fmt.Sprintf("Já existe outro ticket aberto como o ticket [%s]", id), nil)
+	case err != nil:
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Falha ao atualizar ticket [%s], erro %v", id, err), nil)
+	}
+	if res.MatchedCount == 0 {
+		return exception.New(exception.NotFound, fmt.Sprintf("Ticket [%s] não encontrado", id), nil)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import "time"
+
+// OperatorsCollection is the name of the collection of back office operators.
+const OperatorsCollection = "operators"
+
+// Operator roles, from the least to the most privileged. Each role can do
+// everything the previous ones can.
+const (
+	// RoleViewer can search and view candidatures, emails and the audit trail.
+	RoleViewer = "leitor"
+
+	// RoleEditor can also change profiles and resend access links.
+	RoleEditor = "editor"
+
+	// RoleAdmin can also manage operators.
+	RoleAdmin = "admin"
+)
+
+// Roles lists the operator roles, from the least to the most privileged.
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// Operator is a person allowed to use the back office.
+type Operator struct {
+	ID           string    `bson:"_id" json:"id"` // login of the operator.
+	Name         string    `bson:"name" json:"name"`
+	Role         string    `bson:"role" json:"role"`
+	PasswordHash string    `bson:"password_hash" json:"-"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	CreatedBy    string    `bson:"created_by" json:"created_by"`
+	DisabledAt   time.Time `bson:"disabled_at" json:"disabled_at"`
+}
+
+// Can reports whether the operator is active and its role is at least role.
+func (o *Operator) Can(role string) bool {
+	return o.DisabledAt.IsZero() && roleLevel(o.Role) >= roleLevel(role) && roleLevel(role) >= 0
+}
+
+// ValidRole reports whether role is one of the operator roles.
+func ValidRole(role string) bool {
+	return roleLevel(role) >= 0
+}
+
+func roleLevel(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// OperatorStore keeps the back office operators.
+type OperatorStore interface {
+	// CreateOperator stores a new operator. It fails with exception.Conflict
+	// if an operator with the same ID already exists.
+	CreateOperator(o *Operator) error
+
+	// FindOperator returns the operator with the given ID.
+	FindOperator(id string) (*Operator, error)
+
+	// ListOperators returns all operators ordered by ID.
+	ListOperators() ([]*Operator, error)
+
+	// DisableOperator disables an operator, who can not use the back office
+	// anymore.
+	DisableOperator(id string, disabledAt time.Time) error
+}
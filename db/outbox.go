@@ -0,0 +1,69 @@
+package db
+
+import "time"
+
+// OutboxCollection is the name of the collection of emails waiting to be
+// sent, already sent or given up on.
+const OutboxCollection = "outbox"
+
+// Status of the emails in the outbox.
+const (
+	// EmailQueued emails are waiting to be sent, possibly after failed
+	// attempts.
+	EmailQueued = "queued"
+
+	// EmailSent emails were delivered to the mail server.
+	EmailSent = "sent"
+
+	// EmailDead emails failed too many times and will not be retried unless
+	// requeued by an operator.
+	EmailDead = "dead"
+)
+
+// OutboxEmail is an email persisted before being sent, so it is not lost on
+// transient failures of the mail server.
+type OutboxEmail struct {
+	ID string `bson:"_id" json:"id"`
+
+	// IdempotencyKey identifies the action that originated the email (e.g. a
+	// form submission). Emails with a repeated non empty key are not queued.
+	IdempotencyKey string `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+
+	From          string    `bson:"from" json:"from"`
+	To            []string  `bson:"to" json:"to"`
+	Subject       string    `bson:"subject" json:"subject"`
+	HTML          string    `bson:"html" json:"html"`
+	Text          string    `bson:"text" json:"text"`
+	Status        string    `bson:"status" json:"status"`
+	Attempts      int       `bson:"attempts" json:"attempts"`
+	LastError     string    `bson:"last_error" json:"last_error"`
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+	NextAttemptAt time.Time `bson:"next_attempt_at" json:"next_attempt_at"`
+	SentAt        time.Time `bson:"sent_at" json:"sent_at"`
+}
+
+// OutboxStore persists outgoing emails.
+type OutboxStore interface {
+	// EnqueueEmail stores a new queued email. It returns false, without
+	// storing it, when an email with the same idempotency key already exists.
+	EnqueueEmail(e *OutboxEmail) (bool, error)
+
+	// ClaimDueEmails returns up to limit queued emails whose next attempt is
+	// due at now. Claimed emails are not returned again until the lease
+	// expires, so concurrent workers do not send the same email.
+	ClaimDueEmails(now time.Time, lease time.Duration, limit int) ([]*OutboxEmail, error)
+
+	// MarkEmailSent records the email was sent.
+	MarkEmailSent(id string, sentAt time.Time) error
+
+	// MarkEmailFailed records a failed attempt. The email is retried at
+	// nextAttemptAt, unless dead is true.
+	MarkEmailFailed(id string, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error
+
+	// ListEmails returns up to limit emails with the status, newest first. An
+	// empty status lists emails of any status.
+	ListEmails(status string, limit int) ([]*OutboxEmail, error)
+
+	// RequeueEmail puts a dead email back in the queue, to be sent at now.
+	RequeueEmail(id string, now time.Time) error
+}
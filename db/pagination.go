@@ -0,0 +1,102 @@
+package db
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/search"
+)
+
+// Page selects which slice of a search result should be returned.
+//
+// Results are ordered by a shuffle seeded by Seed, so every candidature has
+// the same chance of being shown first while the order stays stable for
+// anyone using the same seed (e.g. the same session or a shared link).
+type Page struct {
+	Number int   // Page number, starting at 1.
+	Size   int   // Maximum number of candidatures per page.
+	Seed   int64 // Seed used to shuffle the results.
+}
+
+// CandidaturesPage is a page of a search result.
+type CandidaturesPage struct {
+	Candidatures []*descritor.CandidateForDB
+	Total        int // Total of candidatures matching the search, considering all pages.
+}
+
+// TotalPages returns the number of pages of size pageSize needed to show all
+// candidatures matching the search.
+func (p *CandidaturesPage) TotalPages(pageSize int) int {
+	if pageSize <= 0 {
+		return 0
+	}
+	return (p.Total + pageSize - 1) / pageSize
+}
+
+// shuffle sorts ids and then shuffles them using seed, so the resulting
+// order depends only on the set of ids and on the seed.
+func shuffle(ids []string, seed int64) {
+	sort.Strings(ids)
+	rand.New(rand.NewSource(seed)).Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+}
+
+// order returns the sequencial IDs of the candidatures in the order they must
+// be paginated: shuffled using seed and, when the query has a name, ranked by
+// relevance. Candidatures with the same relevance keep the shuffled order and
+// the ones not matching the name are left out.
+func order(candidatures []*descritor.CandidateForDB, queryMap map[string]interface{}, seed int64) []string {
+	ids := make([]string, len(candidatures))
+	bySequencialID := make(map[string]*descritor.CandidateForDB, len(candidatures))
+	for i, c := range candidatures {
+		ids[i] = c.SequencialCandidate
+		bySequencialID[c.SequencialCandidate] = c
+	}
+	shuffle(ids, seed)
+	name, _ := queryMap["name"].(string)
+	if name == "" {
+		return ids
+	}
+	// The database pre-filter is broader than the ranking, so candidatures
+	// without score are discarded.
+	scores := make(map[string]float64, len(ids))
+	var ranked []string
+	for _, id := range ids {
+		if score := search.Score(name, searchDocument(bySequencialID[id])); score > 0 {
+			scores[id] = score
+			ranked = append(ranked, id)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked
+}
+
+func searchDocument(c *descritor.CandidateForDB) search.Document {
+	return search.Document{
+		BallotName:   c.BallotName,
+		Name:         c.Name,
+		Party:        c.Party,
+		BallotNumber: c.BallotNumber,
+	}
+}
+
+// slice returns the ids belonging to the page.
+func (p Page) slice(ids []string) []string {
+	number := p.Number
+	if number < 1 {
+		number = 1
+	}
+	start := (number - 1) * p.Size
+	if p.Size <= 0 || start >= len(ids) {
+		return nil
+	}
+	end := start + p.Size
+	if end > len(ids) {
+		end = len(ids)
+	}
+	return ids[start:end]
+}
@@ -0,0 +1,69 @@
+package db
+
+import "time"
+
+// PhotoSubmissionsCollection is the name of the collection of profile photos
+// uploaded by candidates.
+const PhotoSubmissionsCollection = "photo_submissions"
+
+// PhotoRendition is one of the sizes a photo is resized to.
+type PhotoRendition struct {
+	Name   string `bson:"name" json:"name"`
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	URL    string `bson:"url" json:"url"`
+}
+
+// PhotoSubmission is a profile photo uploaded by a candidate. It only
+// replaces the photo of the candidature after approved. Submissions share
+// the status of profile submissions.
+type PhotoSubmission struct {
+	ID           string            `bson:"_id" json:"id"`
+	Year         int               `bson:"year" json:"year"`
+	SequentialID string            `bson:"sequential_id" json:"sequential_id"`
+	Email        string            `bson:"email" json:"email"`
+	SessionID    string            `bson:"session_id" json:"session_id"`
+	CreatedAt    time.Time         `bson:"created_at" json:"created_at"`
+	Status       string            `bson:"status" json:"status"`
+	Renditions   []*PhotoRendition `bson:"renditions" json:"renditions"` // smallest first.
+	ReviewedBy   string            `bson:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt   time.Time         `bson:"reviewed_at" json:"reviewed_at"`
+	Reason       string            `bson:"reason" json:"reason"` // why the photo was rejected.
+}
+
+// Rendition returns the rendition with the name, nil if there is none.
+func (s *PhotoSubmission) Rendition(name string) *PhotoRendition {
+	for _, r := range s.Renditions {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// PhotoStore keeps the photo submissions.
+type PhotoStore interface {
+	// CreatePhotoSubmission stores a new pending photo. Pending photos of
+	// the same candidature are superseded by it.
+	CreatePhotoSubmission(s *PhotoSubmission) error
+
+	// FindPhotoSubmission returns the photo submission with the given ID.
+	FindPhotoSubmission(id string) (*PhotoSubmission, error)
+
+	// FindPendingPhotoSubmission returns the pending photo of the
+	// candidature. It fails with exception.NotFound if there is none.
+	FindPendingPhotoSubmission(year int, sequentialID string) (*PhotoSubmission, error)
+
+	// ListPhotoSubmissions returns up to limit photo submissions with the
+	// status, oldest first.
+	ListPhotoSubmissions(status string, limit int) ([]*PhotoSubmission, error)
+
+	// ReviewPhotoSubmission approves or rejects a pending photo. It fails
+	// with exception.NotFound if the photo does not exist or is not pending.
+	ReviewPhotoSubmission(id, status, reviewedBy, reason string, reviewedAt time.Time) error
+
+	// ReopenPhotoSubmission returns an approved photo to the queue, when
+	// publishing it fails. It fails with exception.NotFound if the photo
+	// does not exist or is not approved.
+	ReopenPhotoSubmission(id string) error
+}
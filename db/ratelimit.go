@@ -0,0 +1,15 @@
+package db
+
+import "time"
+
+// RateLimitsCollection is the name of the collection of rate limit counters,
+// see ratelimit.Store. Counters are removed once expired by a TTL index on
+// expires_at.
+const RateLimitsCollection = "rate_limits"
+
+// RateCounter is a counter of events identified by a key.
+type RateCounter struct {
+	Key       string    `bson:"_id" json:"key"`
+	Count     int       `bson:"count" json:"count"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
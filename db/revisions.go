@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+)
+
+// ProfileRevisionsCollection is the name of the collection of profile
+// revisions.
+const ProfileRevisionsCollection = "profile_revisions"
+
+// ProfileRevision is an immutable snapshot of a candidate profile, stored
+// each time it changes. Revisions of a candidature are numbered from 1.
+type ProfileRevision struct {
+	ID            string                `bson:"_id" json:"id"`
+	Year          int                   `bson:"year" json:"year"`
+	SequentialID  string                `bson:"sequential_id" json:"sequential_id"`
+	Number        int                   `bson:"number" json:"number"`
+	CreatedAt     time.Time             `bson:"created_at" json:"created_at"`
+	Actor         string                `bson:"actor" json:"actor"`           // see AuditEntry.
+	SessionID     string                `bson:"session_id" json:"session_id"` // session of the candidate who made the change, if any.
+	Biography     string                `bson:"biography" json:"biography"`
+	Proposals     []*descritor.Proposal `bson:"proposals" json:"proposals"`
+	Contacts      []*descritor.Contact  `bson:"contacts" json:"contacts"`
+	AcceptedTerms time.Time             `bson:"accepted_terms" json:"accepted_terms"`
+	Transparency  float64               `bson:"transparency" json:"transparency"`
+	PhotoURL      string                `bson:"photo_url" json:"photo_url"`
+	Changes       []*FieldChange        `bson:"changes" json:"changes"`             // compared to the previous revision.
+	RestoredFrom  int                   `bson:"restored_from" json:"restored_from"` // number of the revision restored by a rollback.
+}
+
+// ProfileRevisionID returns the ID of a revision of a candidature.
+func ProfileRevisionID(year int, sequentialID string, number int) string {
+	return fmt.Sprintf("%d/%s/%d", year, sequentialID, number)
+}
+
+// RevisionStore keeps the revisions of candidate profiles.
+type RevisionStore interface {
+	// AddProfileRevision stores a new revision as the latest one of the
+	// candidature, setting its number and ID.
+	AddProfileRevision(r *ProfileRevision) error
+
+	// ListProfileRevisions returns the revisions of the candidature, newest
+	// first.
+	ListProfileRevisions(year int, sequentialID string) ([]*ProfileRevision, error)
+
+	// FindProfileRevision returns a revision of the candidature.
+	FindProfileRevision(year int, sequentialID string, number int) (*ProfileRevision, error)
+}
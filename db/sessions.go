@@ -0,0 +1,80 @@
+package db
+
+import "time"
+
+const (
+	// LoginTokensCollection is the name of the collection which keeps track
+	// of the login tokens (magic links) sent by email.
+	LoginTokensCollection = "login_tokens"
+
+	// SessionsCollection is the name of the collection of sessions started
+	// through login tokens.
+	SessionsCollection = "sessions"
+)
+
+// LoginToken is the server side record of a magic link sent by email.
+// A login token can be used only once and gives access to the candidature
+// identified by Year and SequentialID.
+type LoginToken struct {
+	ID              string    `bson:"_id" json:"id"`
+	Email           string    `bson:"email" json:"email"`
+	Year            int       `bson:"year" json:"year"`
+	SequentialID    string    `bson:"sequential_id" json:"sequential_id"`
+	IssuedAt        time.Time `bson:"issued_at" json:"issued_at"`
+	ExpiresAt       time.Time `bson:"expires_at" json:"expires_at"`
+	IssuedIP        string    `bson:"issued_ip" json:"issued_ip"`
+	IssuedUserAgent string    `bson:"issued_user_agent" json:"issued_user_agent"`
+	UsedAt          time.Time `bson:"used_at" json:"used_at"`
+	UsedIP          string    `bson:"used_ip" json:"used_ip"`
+	UsedUserAgent   string    `bson:"used_user_agent" json:"used_user_agent"`
+	RevokedAt       time.Time `bson:"revoked_at" json:"revoked_at"`
+}
+
+// Session is the server side record of a session started through a login
+// token. A session is active until it expires or is revoked.
+type Session struct {
+	ID           string    `bson:"_id" json:"id"`
+	Email        string    `bson:"email" json:"email"`
+	Year         int       `bson:"year" json:"year"`
+	SequentialID string    `bson:"sequential_id" json:"sequential_id"`
+	LoginTokenID string    `bson:"login_token_id" json:"login_token_id"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt    time.Time `bson:"expires_at" json:"expires_at"`
+	IP           string    `bson:"ip" json:"ip"`
+	UserAgent    string    `bson:"user_agent" json:"user_agent"`
+	RevokedAt    time.Time `bson:"revoked_at" json:"revoked_at"`
+}
+
+// Active reports whether the session can still be used at the given time.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+// SessionStore keeps track of login tokens and sessions.
+type SessionStore interface {
+	// CreateLoginToken stores a new login token.
+	CreateLoginToken(t *LoginToken) error
+
+	// UseLoginToken marks the login token as used and returns it. It fails
+	// with exception.NotFound if the token does not exist, was already used,
+	// was revoked or is expired.
+	UseLoginToken(id string, usedAt time.Time, ip, userAgent string) (*LoginToken, error)
+
+	// CreateSession stores a new session.
+	CreateSession(s *Session) error
+
+	// FindSession returns the session with the given ID.
+	FindSession(id string) (*Session, error)
+
+	// RevokeSession revokes a single session.
+	RevokeSession(id string, revokedAt time.Time) error
+
+	// RevokeAllByEmail revokes every session and unused login token issued
+	// to the email.
+	RevokeAllByEmail(email string, revokedAt time.Time) error
+
+	// RevokeAllByCandidature revokes every session and unused login token
+	// giving access to the candidature, leaving the other candidatures of
+	// the same email signed in.
+	RevokeAllByCandidature(year int, sequentialID string, revokedAt time.Time) error
+}
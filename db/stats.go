@@ -0,0 +1,43 @@
+package db
+
+import "sort"
+
+// Count is the number of candidatures with a value of an attribute, such as
+// a party or a topic of their proposals.
+type Count struct {
+	Value string `bson:"_id" json:"value"`
+	Total int    `bson:"total" json:"total"`
+}
+
+// LocationStats are aggregate statistics of the candidatures of a state or
+// city in an election year. Counts are sorted by total, largest first, and
+// then by value.
+type LocationStats struct {
+	Total       int      `json:"total"`
+	Transparent int      `json:"transparent"` // candidatures with proposals.
+	Roles       []*Count `json:"roles"`
+	Genders     []*Count `json:"genders"`
+	Parties     []*Count `json:"parties"`
+	Topics      []*Count `json:"topics"` // candidatures with proposals in each topic.
+}
+
+// StatsStore computes statistics of the candidatures.
+type StatsStore interface {
+	// LocationStats returns the statistics of the candidatures of the state
+	// in the year or, if city is not empty, of the city.
+	LocationStats(year int, state, city string) (*LocationStats, error)
+
+	// ElectionYears returns the years of the elections with candidatures, in
+	// ascending order.
+	ElectionYears() ([]int, error)
+}
+
+// sortCounts sorts the counts by total, largest first, and then by value.
+func sortCounts(counts []*Count) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Total != counts[j].Total {
+			return counts[i].Total > counts[j].Total
+		}
+		return counts[i].Value < counts[j].Value
+	})
+}
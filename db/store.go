@@ -0,0 +1,75 @@
+package db
+
+import (
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/ratelimit"
+)
+
+// CandidateStore defines the operations over candidatures and locations
+// needed by the site. It is implemented by the MongoDB client and by the
+// in-memory client.
+type CandidateStore interface {
+	// GetStates returns a list of available states
+	GetStates() ([]string, error)
+
+	// GetCities returns the cities of a given state
+	GetCities(state string) ([]string, error)
+
+	// GetLocations returns all states and their cities
+	GetLocations() ([]*descritor.Location, error)
+
+	// GetCandidateByEmail searches for a candidate using email
+	GetCandidateByEmail(email string, year int) (*descritor.CandidateForDB, error)
+
+	// FindCandidatesByEmail returns every candidature registered with the
+	// email in the year, ordered by sequential ID.
+	FindCandidatesByEmail(email string, year int) ([]*descritor.CandidateForDB, error)
+
+	// FindCandidateBySequencialIDAndYear searches for a candidate using its
+	// sequencial ID and returns it.
+	FindCandidateBySequencialIDAndYear(year int, sequencialID string) (*descritor.CandidateForDB, error)
+
+	// UpdateCandidateProfile updates the profile of a candidate
+	UpdateCandidateProfile(candidate *descritor.CandidateForDB) (*descritor.CandidateForDB, error)
+
+	// FindTransparentCandidatures searches for a page of candidatures with proposals defined.
+	// Candidatures whose profile was disabled are left out of both searches.
+	FindTransparentCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error)
+
+	// FindNonTransparentCandidatures searches for a page of non transparent candidatures
+	FindNonTransparentCandidatures(queryMap map[string]interface{}, page Page) (*CandidaturesPage, error)
+
+	// FindCandidaturesForIndex returns all candidatures filled only with the
+	// fields needed to build search indexes: sequencial ID, ballot name,
+	// ballot number, name, party, role, state, city and year. Candidatures
+	// whose profile was disabled are left out.
+	FindCandidaturesForIndex() ([]*descritor.CandidateForDB, error)
+
+	// FindCandidaturesForRanking returns every transparent candidature
+	// matching the query filled only with the fields needed to rank them:
+	// sequencial ID, year, ballot name, gender, party and the topics of the
+	// proposals. Candidatures whose profile was disabled are left out.
+	FindCandidaturesForRanking(queryMap map[string]interface{}) ([]*descritor.CandidateForDB, error)
+}
+
+// Store groups all the operations provided by the database clients.
+type Store interface {
+	CandidateStore
+	SessionStore
+	OutboxStore
+	ratelimit.Store
+	OperatorStore
+	AuditStore
+	DisabledProfileStore
+	RevisionStore
+	ModerationStore
+	PhotoStore
+	TicketStore
+	CalendarStore
+	StatsStore
+}
+
+var (
+	_ Store = (*Client)(nil)
+	_ Store = (*MemoryClient)(nil)
+)
@@ -0,0 +1,122 @@
+package db
+
+import "time"
+
+// TicketsCollection is the name of the collection of tickets handled by
+// operators.
+const TicketsCollection = "tickets"
+
+// Kinds of tickets.
+const (
+	TicketProfileReport = "denuncia-perfil" // voter reporting the content of a profile.
+	TicketContact       = "fale-conosco"    // message of a candidate to the team.
+)
+
+// Status of tickets.
+const (
+	TicketOpen      = "aberto"
+	TicketInReview  = "em-analise"
+	TicketResolved  = "resolvido"
+	TicketDismissed = "descartado"
+)
+
+// TicketStatuses lists the status of tickets in the order they are handled.
+var TicketStatuses = []string{TicketOpen, TicketInReview, TicketResolved, TicketDismissed}
+
+// ValidTicketStatus reports whether status is a known ticket status.
+func ValidTicketStatus(status string) bool {
+	for _, s := range TicketStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TicketIsOpen reports whether tickets with the status still need to be
+// handled by operators.
+func TicketIsOpen(status string) bool {
+	return status == TicketOpen || status == TicketInReview
+}
+
+// Ticket is a request handled by operators: the reports of voters about a
+// profile or a conversation between a candidate and the team. Reports of the
+// same category about the same candidature are grouped in one ticket while
+// it is open.
+type Ticket struct {
+	ID           string           `bson:"_id" json:"id"`
+	Kind         string           `bson:"kind" json:"kind"`
+	Status       string           `bson:"status" json:"status"`
+	Category     string           `bson:"category" json:"category"` // of the reports or type of the message of the candidate.
+	Year         int              `bson:"year" json:"year"`
+	SequentialID string           `bson:"sequential_id" json:"sequential_id"`
+	Email        string           `bson:"email" json:"email"` // of the candidate who opened the ticket.
+	Subject      string           `bson:"subject" json:"subject"`
+	Reports      []*TicketReport  `bson:"reports" json:"reports"`
+	Messages     []*TicketMessage `bson:"messages" json:"messages"`
+	Events       []*TicketEvent   `bson:"events" json:"events"`
+	Assignee     string           `bson:"assignee" json:"assignee"` // operator handling the ticket.
+	CreatedAt    time.Time        `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time        `bson:"updated_at" json:"updated_at"`
+}
+
+// TicketReport is a report added to a ticket.
+type TicketReport struct {
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	// Reporter identifies who sent the report without storing personal
+	// data, e.g. a keyed hash of the IP address.
+	Reporter string `bson:"reporter" json:"-"`
+	// RevisionNumber is the revision of the profile the report is about,
+	// zero when the profile was never changed.
+	RevisionNumber int    `bson:"revision_number" json:"revision_number"`
+	Message        string `bson:"message" json:"message"`
+}
+
+// TicketMessage is a message of the conversation of a ticket.
+type TicketMessage struct {
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	Author    string    `bson:"author" json:"author"`
+	FromTeam  bool      `bson:"from_team" json:"from_team"` // sent by an operator.
+	Text      string    `bson:"text" json:"text"`
+}
+
+// TicketEvent records a change of status of a ticket.
+type TicketEvent struct {
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	Actor     string    `bson:"actor" json:"actor"`
+	Status    string    `bson:"status" json:"status"`
+	Note      string    `bson:"note" json:"note"`
+}
+
+// TicketStore keeps the tickets.
+type TicketStore interface {
+	// AddTicketReport adds the report to the open ticket with the same kind,
+	// category and candidature of t, storing t when there is none. It
+	// returns the ticket the report was added to and fails with
+	// exception.Conflict if the reporter is already in it.
+	AddTicketReport(t *Ticket, r *TicketReport) (*Ticket, error)
+
+	// CreateTicket stores a new ticket. It fails with exception.Conflict if
+	// there is a ticket with the same ID.
+	CreateTicket(t *Ticket) error
+
+	// AddTicketMessage adds the message to the conversation of the ticket.
+	AddTicketMessage(id string, m *TicketMessage) error
+
+	// FindTicket returns the ticket with the given ID.
+	FindTicket(id string) (*Ticket, error)
+
+	// ListTickets returns up to limit tickets of the kind with the status,
+	// the most recently updated first.
+	ListTickets(kind, status string, limit int) ([]*Ticket, error)
+
+	// ListCandidatureTickets returns the tickets of the kind about the
+	// candidature, the most recently updated first.
+	ListCandidatureTickets(kind string, year int, sequentialID string) ([]*Ticket, error)
+
+	// UpdateTicketStatus changes the status and the assignee of the ticket
+	// and records the event. It fails with exception.Conflict when reopening
+	// a ticket of reports while another one of the same category about the
+	// same candidature is open.
+	UpdateTicketStatus(id, assignee string, e *TicketEvent) error
+}
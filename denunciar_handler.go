@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/ratelimit"
+	"github.com/labstack/echo"
+)
+
+const (
+	reportRateWindow  = time.Hour
+	reportsPerIP      = 5
+	maxReportTextSize = 1000
+
+	reportReceivedMessage = "Obrigado! Sua denúncia foi recebida e será analisada pela equipe do candidatos.info."
+)
+
+var (
+	reportCategoriesUI = map[string]string{
+		"informacao-falsa":     "Informação falsa ou enganosa",
+		"conteudo-ofensivo":    "Conteúdo ofensivo ou discurso de ódio",
+		"perfil-falso":         "Perfil não é mantido pela candidatura",
+		"propaganda-irregular": "Propaganda eleitoral irregular",
+		"outro":                "Outro motivo",
+	}
+)
+
+// POST /c/:year/:id/denunciar receives the anonymous report of a voter about
+// the profile. Reports are stored as tickets, grouped by candidature and
+// category, and the team is emailed when a new ticket is opened. Reports
+// repeated by the same voter are ignored, with the same response.
+func newDenunciarHandler(dbClient db.Store, counters ratelimit.Store, secret, contactEmail string) echo.HandlerFunc {
+	perIP := ratelimit.New(counters, "report-ip", reportsPerIP, reportRateWindow)
+	return func(c echo.Context) error {
+		year, err := strconv.Atoi(c.Param("year"))
+		if err != nil {
+			log.Printf("Parâmetro year inválido (%s):%q\n", c.Param("year"), err)
+			return echo.ErrBadRequest
+		}
+		candidate, err := dbClient.FindCandidateBySequencialIDAndYear(year, c.Param("id"))
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			log.Printf("failed to find candidate (%s/%d), error %v\n", c.Param("id"), year, err)
+			return echo.ErrInternalServerError
+		}
+		render := func(status int, message string) error {
+			return c.Render(status, "denunciar-perfil.html", map[string]interface{}{
+				"Candidato": candidate,
+				"Success":   status == http.StatusOK,
+				"Message":   message,
+			})
+		}
+		if c.FormValue(honeypotField) != "" {
+			log.Printf("ignoring report with honeypot filled from %s\n", c.RealIP())
+			return render(http.StatusOK, reportReceivedMessage)
+		}
+		now := time.Now().UTC()
+		ok, err := perIP.Allow(c.RealIP(), now)
+		if err != nil {
+			log.Printf("failed to check report rate limit for ip (%s):%q\n", c.RealIP(), err)
+			return render(http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde.")
+		}
+		if !ok {
+			return render(http.StatusTooManyRequests, "Muitas denúncias a partir da sua rede. Por favor tentar novamente mais tarde.")
+		}
+		category := c.FormValue("categoria")
+		if _, ok := reportCategoriesUI[category]; !ok {
+			return render(http.StatusBadRequest, "Por favor escolha o motivo da denúncia.")
+		}
+		message := strings.TrimSpace(c.FormValue("mensagem"))
+		if message == "" {
+			return render(http.StatusBadRequest, "Por favor descreva o problema encontrado no perfil.")
+		}
+		if utf8.RuneCountInString(message) > maxReportTextSize {
+			return render(http.StatusBadRequest, fmt.Sprintf("Tamanho máximo da denúncia é de %d caracteres.", maxReportTextSize))
+		}
+		revisions, err := dbClient.ListProfileRevisions(candidate.Year, candidate.SequencialCandidate)
+		if err != nil {
+			log.Printf("failed to list profile revisions (%s), error %v\n", candidate.SequencialCandidate, err)
+			return render(http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde.")
+		}
+		revision := 0
+		if len(revisions) > 0 {
+			revision = revisions[0].Number
+		}
+		id, err := newID()
+		if err != nil {
+			log.Printf("failed to create ticket id, error %v\n", err)
+			return render(http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde.")
+		}
+		ticket, err := dbClient.AddTicketReport(&db.Ticket{
+			ID:           id,
+			Kind:         db.TicketProfileReport,
+			Status:       db.TicketOpen,
+			Category:     category,
+			Year:         candidate.Year,
+			SequentialID: candidate.SequencialCandidate,
+			CreatedAt:    now,
+		}, &db.TicketReport{
+			CreatedAt:      now,
+			Reporter:       reporterKey(secret, c.RealIP()),
+			RevisionNumber: revision,
+			Message:        message,
+		})
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.Conflict:
+			return render(http.StatusOK, reportReceivedMessage)
+		case err != nil:
+			log.Printf("failed to store report (%s), error %v\n", candidate.SequencialCandidate, err)
+			return render(http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde.")
+		}
+		if ticket.ID == id { // a new ticket was opened.
+			m, err := buildReportEmail(candidate, reportCategoriesUI[category], message, fmt.Sprintf("%s/admin/denuncias/%s", siteURL, id))
+			if err != nil {
+				log.Printf("failed to build report email (%s), error %v\n", id, err)
+				return render(http.StatusOK, reportReceivedMessage)
+			}
+			m.From = emailFrom
+			m.To = []string{contactEmail}
+			if _, err := enqueueEmail(dbClient, "denuncia:"+id, m); err != nil {
+				log.Printf("failed to queue report email (%s), error %v\n", id, err)
+			}
+		}
+		return render(http.StatusOK, reportReceivedMessage)
+	}
+}
+
+// reporterKey identifies the reporter by a keyed hash of the IP address, so
+// repeated reports can be detected without storing the address.
+func reporterKey(secret, ip string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/labstack/echo"
+)
+
+func postReport(t *testing.T, e *echo.Echo, ip string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/c/2020/20000000001/denunciar", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(echo.HeaderXRealIP, ip)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDenunciarPerfil(t *testing.T) {
+	e, store := newTestServer(t)
+	page := httptest.NewRecorder()
+	e.ServeHTTP(page, httptest.NewRequest(http.MethodGet, "/c/2020/20000000001", nil))
+	if !strings.Contains(page.Body.String(), `action="/c/2020/20000000001/denunciar"`) {
+		t.Errorf("want report form in the candidate page")
+	}
+
+	report := url.Values{"categoria": {"informacao-falsa"}, "mensagem": {"A candidata não é professora."}}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		if rec := postReport(t, e, ip, report); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Sua denúncia foi recebida") {
+			t.Errorf("want report received from %s, got status %d", ip, rec.Code)
+		}
+	}
+	tickets, err := store.ListTickets(db.TicketProfileReport, db.TicketOpen, 10)
+	if err != nil || len(tickets) != 1 || len(tickets[0].Reports) != 2 {
+		t.Fatalf("want 1 ticket with the 2 reports of distinct voters, got %v (error %v)", tickets, err)
+	}
+	if r := tickets[0].Reports[0]; r.Reporter == "" || strings.Contains(r.Reporter, "10.0.0.1") || r.Message != "A candidata não é professora." {
+		t.Errorf("want report stored without the IP address, got %+v", r)
+	}
+	emails, err := store.ListEmails("", 10)
+	if err != nil || len(emails) != 1 || emails[0].To[0] != testContactEmail || !strings.Contains(emails[0].Text, "/admin/denuncias/"+tickets[0].ID) {
+		t.Errorf("want team emailed once about the new ticket, got %v (error %v)", emails, err)
+	}
+
+	invalid := []url.Values{
+		{"categoria": {"desconhecida"}, "mensagem": {"Mensagem."}},
+		{"categoria": {"outro"}, "mensagem": {" "}},
+		{"categoria": {"outro"}, "mensagem": {strings.Repeat("é", maxReportTextSize+1)}},
+	}
+	for i, form := range invalid {
+		if rec := postReport(t, e, "10.0.0.3", form); rec.Code != http.StatusBadRequest {
+			t.Errorf("want status %d for invalid report %d, got %d", http.StatusBadRequest, i, rec.Code)
+		}
+	}
+	honeypot := url.Values{"categoria": {"outro"}, "mensagem": {"Spam."}, honeypotField: {"http://spam.example.com"}}
+	if rec := postReport(t, e, "10.0.0.4", honeypot); rec.Code != http.StatusOK {
+		t.Errorf("want status %d when the honeypot is filled, got %d", http.StatusOK, rec.Code)
+	}
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < reportsPerIP+1; i++ {
+		rec = postReport(t, e, "10.0.0.5", url.Values{"categoria": {"outro"}, "mensagem": {"Denúncia " + strconv.Itoa(i)}})
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("want status %d after too many reports, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+
+	createTestOperator(t, store, "leitor@exemplo.com", db.RoleViewer)
+	path := "/admin/denuncias/" + tickets[0].ID
+	if rec := adminRequest(t, e, http.MethodGet, "/admin/denuncias", "leitor@exemplo.com", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), path) {
+		t.Errorf("want ticket in the queue, got status %d", rec.Code)
+	}
+	resolve := url.Values{"status": {db.TicketResolved}, "responsavel": {testAdminUser}, "nota": {"Biografia corrigida."}}
+	if rec := adminRequest(t, e, http.MethodPost, path, "leitor@exemplo.com", resolve); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d for viewer updating ticket, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, url.Values{"status": {"fechado"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for unknown status, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, url.Values{"status": {db.TicketInReview}, "responsavel": {"leitor@exemplo.com"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d assigning a viewer, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, resolve); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after updating ticket, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodGet, path, testAdminUser, nil); !strings.Contains(rec.Body.String(), "Biografia corrigida.") {
+		t.Errorf("want note in the ticket history")
+	}
+	ticket, err := store.FindTicket(tickets[0].ID)
+	if err != nil || ticket.Status != db.TicketResolved || ticket.Assignee != testAdminUser {
+		t.Errorf("want ticket resolved by %s, got %+v (error %v)", testAdminUser, ticket, err)
+	}
+	if rec := postReport(t, e, "10.0.0.1", report); rec.Code != http.StatusOK {
+		t.Errorf("want report received, got status %d", rec.Code)
+	}
+	if tickets, _ := store.ListTickets(db.TicketProfileReport, db.TicketOpen, 10); len(tickets) != 2 {
+		t.Errorf("want a new ticket after the previous one was resolved, got %d open tickets", len(tickets))
+	}
+}
@@ -0,0 +1,266 @@
+package email
+
+import (
+	"bufio"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMessage() *Message {
+	return &Message{
+		From:    "Candidatos <contato@candidatos.info>",
+		To:      []string{"maria.jose@exemplo.com"},
+		Subject: "Link para acesso à candidatura",
+		HTML:    "<p>Olá, Maria José!</p>",
+	}
+}
+
+func TestRender(t *testing.T) {
+	m := newTestMessage()
+	m.Date = time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	data, err := Render(m)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if strings.Contains(strings.Replace(string(data), "\r\n", "", -1), "\n") {
+		t.Errorf("want CRLF line endings")
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if subject := parsed.Header.Get("Subject"); !strings.HasPrefix(subject, "=?UTF-8?q?") {
+		t.Errorf("want encoded subject, got %s", subject)
+	}
+	for key, want := range map[string]string{
+		"From":         `"Candidatos" <contato@candidatos.info>`,
+		"To":           "<maria.jose@exemplo.com>",
+		"Date":         "Thu, 01 Oct 2020 12:00:00 +0000",
+		"MIME-Version": "1.0",
+	} {
+		if got := parsed.Header.Get(key); got != want {
+			t.Errorf("want header %s %q, got %q", key, want, got)
+		}
+	}
+	if !strings.HasSuffix(m.MessageID, "@candidatos.info>") || parsed.Header.Get("Message-ID") != m.MessageID {
+		t.Errorf("want Message-ID with sender domain, got %s", m.MessageID)
+	}
+	body, err := ioutil.ReadAll(parsed.Body)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if !strings.Contains(string(body), "Maria Jos=C3=A9") {
+		t.Errorf("want quoted-printable body, got %s", body)
+	}
+}
+
+func TestRenderInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		m    *Message
+	}{
+		{"no recipients", &Message{From: "contato@candidatos.info"}},
+		{"invalid sender", &Message{From: "contato", To: []string{"a@b.com"}}},
+		{"header injection", &Message{From: "contato@candidatos.info", To: []string{"a@b.com\r\nBcc: c@d.com"}}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Render(tt.m); err == nil {
+				t.Errorf("want error rendering message")
+			}
+		})
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	s := NewMemoryMailer()
+	if err := s.Send(newTestMessage()); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := s.Send(&Message{}); err == nil {
+		t.Errorf("want error sending invalid message")
+	}
+	if msgs := s.Messages(); len(msgs) != 1 || msgs[0].To[0] != "maria.jose@exemplo.com" {
+		t.Errorf("want one message recorded, got %v", msgs)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emails")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileMailer(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := s.Send(newTestMessage()); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "out", "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("want one eml file, got %v (error %v)", files, err)
+	}
+}
+
+// fakeSMTPServer accepts a single SMTP session and sends the received
+// commands and data to the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	received := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var session strings.Builder
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			session.WriteString(line)
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				reply("250 OK")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				reply("354 go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 bye")
+				received <- session.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+		received <- session.String()
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	s, err := NewSMTPMailer(SMTPConfig{Host: host, Port: p, TLS: NoTLS})
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := s.Send(newTestMessage()); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	session := <-received
+	for _, want := range []string{"MAIL FROM:<contato@candidatos.info>", "RCPT TO:<maria.jose@exemplo.com>", "Subject: =?UTF-8?q?"} {
+		if !strings.Contains(session, want) {
+			t.Errorf("want SMTP session containing %q, got %s", want, session)
+		}
+	}
+	if _, err := NewSMTPMailer(SMTPConfig{Host: host, Port: p, TLS: "ssl"}); err == nil {
+		t.Errorf("want error for invalid TLS mode")
+	}
+}
+
+func TestRenderMultipart(t *testing.T) {
+	m := newTestMessage()
+	m.Text = "Olá, Maria José!"
+	data, err := Render(m)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("want multipart/alternative, got %s (error %v)", mediaType, err)
+	}
+	r := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{`text/plain; charset="UTF-8"`, "Olá, Maria José!"},
+		{`text/html; charset="UTF-8"`, "<p>Olá, Maria José!</p>"},
+	} {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		if p.Header.Get("Content-Type") != want.contentType {
+			t.Errorf("want part %s, got %s", want.contentType, p.Header.Get("Content-Type"))
+		}
+		body, err := ioutil.ReadAll(quotedprintable.NewReader(p))
+		if err != nil || string(body) != want.body {
+			t.Errorf("want body %q, got %q (error %v)", want.body, body, err)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"layout.txt":  `{{template "content" .}}{{block "footer" .}} -- equipe{{end}}`,
+		"layout.html": `<div>{{template "content" .}}</div>`,
+		"ola.txt":     `{{define "subject"}}Olá, {{.}}{{end}}{{define "content"}}Olá, {{.}}!{{end}}`,
+		"ola.html":    `{{define "content"}}<p>Olá, {{.}}!</p>{{end}}`,
+		"texto.txt":   `{{define "subject"}}Só texto{{end}}{{define "content"}}{{.}}{{end}}{{define "footer"}} -- {{.}}{{end}}`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	tmpl, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	m, err := tmpl.Render("ola", "<Maria>")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if m.Subject != "Olá, <Maria>" || m.Text != "Olá, <Maria>! -- equipe" || m.HTML != "<div><p>Olá, &lt;Maria&gt;!</p></div>" {
+		t.Errorf("want message rendered with escaped HTML, got %+v", m)
+	}
+	m, err = tmpl.Render("texto", "eleitor")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if m.HTML != "" || m.Text != "eleitor -- eleitor" {
+		t.Errorf("want text only message with its own footer, got %+v", m)
+	}
+	if _, err := tmpl.Render("inexistente", nil); err == nil {
+		t.Errorf("want error rendering unknown template")
+	}
+}
@@ -0,0 +1,7 @@
+package email
+
+// Mailer sends email messages.
+type Mailer interface {
+	// Send renders and delivers the message to all its recipients.
+	Send(m *Message) error
+}
@@ -0,0 +1,140 @@
+package email
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Message is an email to be sent by a Mailer. Bodies are sent as UTF-8
+// quoted-printable text. When both HTML and Text are set the message is sent
+// as multipart/alternative, so clients can pick the version they display.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+
+	// Date and MessageID are filled by Render when empty.
+	Date      time.Time
+	MessageID string
+}
+
+// Render validates the message and returns it formatted according to
+// RFC 5322, using CRLF line endings. Subjects with non-ASCII characters are
+// encoded as described in RFC 2047.
+func Render(m *Message) ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("remetente inválido (%s), erro %q", m.From, err)
+	}
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("mensagem sem destinatários")
+	}
+	var to []string
+	for _, t := range m.To {
+		addr, err := mail.ParseAddress(t)
+		if err != nil {
+			return nil, fmt.Errorf("destinatário inválido (%s), erro %q", t, err)
+		}
+		to = append(to, addr.String())
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	if m.MessageID == "" {
+		if m.MessageID, err = newMessageID(from.Address); err != nil {
+			return nil, err
+		}
+	}
+	var b bytes.Buffer
+	writeHeader(&b, "From", from.String())
+	writeHeader(&b, "To", strings.Join(to, ", "))
+	writeHeader(&b, "Subject", mime.QEncoding.Encode("UTF-8", m.Subject))
+	writeHeader(&b, "Date", m.Date.Format(time.RFC1123Z))
+	writeHeader(&b, "Message-ID", m.MessageID)
+	writeHeader(&b, "MIME-Version", "1.0")
+	switch {
+	case m.HTML != "" && m.Text != "":
+		mw := multipart.NewWriter(&b)
+		writeHeader(&b, "Content-Type", fmt.Sprintf(`multipart/alternative; boundary="%s"`, mw.Boundary()))
+		b.WriteString("\r\n")
+		// Parts are ordered by increasing preference, as in RFC 2046.
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain", m.Text},
+			{"text/html", m.HTML},
+		} {
+			w, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType + `; charset="UTF-8"`},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("falha ao codificar corpo do email, erro %q", err)
+			}
+			if err := writeQuotedPrintable(w, part.body); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, fmt.Errorf("falha ao codificar corpo do email, erro %q", err)
+		}
+	case m.HTML != "":
+		if err := writeBody(&b, "text/html", m.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		if err := writeBody(&b, "text/plain", m.Text); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// writeBody writes the headers of a single part message followed by its body.
+func writeBody(b *bytes.Buffer, contentType, body string) error {
+	writeHeader(b, "Content-Type", contentType+`; charset="UTF-8"`)
+	writeHeader(b, "Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+	return writeQuotedPrintable(b, body)
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("falha ao codificar corpo do email, erro %q", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("falha ao codificar corpo do email, erro %q", err)
+	}
+	return nil
+}
+
+func writeHeader(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	b.WriteString(": ")
+	b.WriteString(value)
+	b.WriteString("\r\n")
+}
+
+// newMessageID returns a unique Message-ID using the domain of the sender.
+func newMessageID(from string) (string, error) {
+	var r [16]byte
+	if _, err := crand.Read(r[:]); err != nil {
+		return "", fmt.Errorf("falha ao gerar Message-ID, erro %q", err)
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(r[:]), domain), nil
+}
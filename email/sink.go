@@ -0,0 +1,73 @@
+package email
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// MemoryMailer records messages instead of sending them. It is meant for
+// tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+// NewMemoryMailer returns a new MemoryMailer.
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the message after making sure it can be rendered.
+func (s *MemoryMailer) Send(m *Message) error {
+	if _, err := Render(m); err != nil {
+		return err
+	}
+	aux := *m
+	aux.To = append([]string(nil), m.To...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, &aux)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (s *MemoryMailer) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Message(nil), s.messages...)
+}
+
+// FileMailer writes each message as a .eml file in a directory instead of
+// sending it. It is meant for local development: the files can be opened by
+// any email client.
+type FileMailer struct {
+	dir string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9.@-]+`)
+
+// NewFileMailer returns a new FileMailer, creating the directory if needed.
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("falha ao criar diretório de emails (%s), erro %q", dir, err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+// Send writes the rendered message to a file named after the date and the
+// Message-ID.
+func (s *FileMailer) Send(m *Message) error {
+	data, err := Render(m)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", m.Date.Format("20060102T150405"), unsafeFileChars.ReplaceAllString(m.MessageID, ""))
+	if err := ioutil.WriteFile(filepath.Join(s.dir, name), data, 0644); err != nil {
+		return fmt.Errorf("falha ao gravar email (%s), erro %q", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package email
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// TLS modes supported by SMTPMailer.
+const (
+	// StartTLS connects in plain text and upgrades the connection using the
+	// STARTTLS command, usually on port 587.
+	StartTLS = "starttls"
+
+	// ImplicitTLS connects using TLS from the start, usually on port 465.
+	ImplicitTLS = "tls"
+
+	// NoTLS never encrypts the connection. Use it only with local relays.
+	NoTLS = "none"
+)
+
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTPConfig configures the SMTP server used to send emails.
+type SMTPConfig struct {
+	Host string
+	Port int
+	TLS  string // StartTLS, ImplicitTLS or NoTLS.
+
+	// Username and Password are used for PLAIN authentication. No
+	// authentication is done when Username is empty.
+	Username string
+	Password string
+
+	// Timeout limits connecting to the server. Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// SMTPMailer sends emails through a SMTP server.
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPMailer returns a new Mailer that sends emails using the config.
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	switch cfg.TLS {
+	case StartTLS, ImplicitTLS, NoTLS:
+	default:
+		return nil, fmt.Errorf("modo TLS inválido (%s), use %s, %s ou %s", cfg.TLS, StartTLS, ImplicitTLS, NoTLS)
+	}
+	if cfg.Host == "" || cfg.Port <= 0 {
+		return nil, fmt.Errorf("servidor SMTP inválido (%s:%d)", cfg.Host, cfg.Port)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultSMTPTimeout
+	}
+	return &SMTPMailer{cfg: cfg}, nil
+}
+
+// Send sends an email
+func (s *SMTPMailer) Send(m *Message) error {
+	data, err := Render(m)
+	if err != nil {
+		return err
+	}
+	if err := s.send(m, data); err != nil {
+		return fmt.Errorf("falha ao enviar email, erro %q", err)
+	}
+	return nil
+}
+
+func (s *SMTPMailer) send(m *Message, data []byte) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	var conn net.Conn
+	var err error
+	if s.cfg.TLS == ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if s.cfg.TLS == StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("servidor %s não suporta STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, t := range m.To {
+		to, err := mail.ParseAddress(t)
+		if err != nil {
+			return err
+		}
+		if err := c.Rcpt(to.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
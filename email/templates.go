@@ -0,0 +1,87 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	htmlLayoutFile = "layout.html"
+	textLayoutFile = "layout.txt"
+)
+
+// Templates renders messages from pairs of templates kept in a directory.
+// For a message named "acesso", acesso.txt must define the "subject" and
+// "content" templates and acesso.html, which is optional, must define
+// "content". The contents are wrapped by layout.txt and layout.html. HTML
+// templates use html/template, so data provided by users is escaped.
+type Templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// LoadTemplates parses all the message templates found in the directory.
+func LoadTemplates(dir string) (*Templates, error) {
+	textFiles, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar templates de email em %s, erro %q", dir, err)
+	}
+	t := &Templates{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+	for _, f := range textFiles {
+		base := filepath.Base(f)
+		if base == textLayoutFile {
+			continue
+		}
+		name := strings.TrimSuffix(base, ".txt")
+		if t.text[name], err = texttemplate.ParseFiles(filepath.Join(dir, textLayoutFile), f); err != nil {
+			return nil, fmt.Errorf("falha ao carregar template de email %s, erro %q", f, err)
+		}
+		if t.text[name].Lookup("subject") == nil {
+			return nil, fmt.Errorf("template de email %s não define o assunto (subject)", f)
+		}
+		htmlFile := filepath.Join(dir, name+".html")
+		if _, err := os.Stat(htmlFile); os.IsNotExist(err) {
+			continue
+		}
+		if t.html[name], err = htmltemplate.ParseFiles(filepath.Join(dir, htmlLayoutFile), htmlFile); err != nil {
+			return nil, fmt.Errorf("falha ao carregar template de email %s, erro %q", htmlFile, err)
+		}
+	}
+	return t, nil
+}
+
+// Render executes the templates of the named message with the data. The
+// returned message has no sender nor recipients.
+func (t *Templates) Render(name string, data interface{}) (*Message, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return nil, fmt.Errorf("template de email %s não encontrado", name)
+	}
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("falha ao gerar assunto do email %s, erro %q", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, textLayoutFile, data); err != nil {
+		return nil, fmt.Errorf("falha ao gerar email %s, erro %q", name, err)
+	}
+	m := &Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    body.String(),
+	}
+	if html, ok := t.html[name]; ok {
+		var b bytes.Buffer
+		if err := html.ExecuteTemplate(&b, htmlLayoutFile, data); err != nil {
+			return nil, fmt.Errorf("falha ao gerar email %s, erro %q", name, err)
+		}
+		m.HTML = b.String()
+	}
+	return m, nil
+}
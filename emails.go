@@ -2,14 +2,145 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/email"
 )
 
-func buildReportEmail(candidate *descritor.CandidateForDB, report string) string {
-	var emailBodyBuilder strings.Builder
-	emailBodyBuilder.WriteString(fmt.Sprintf("Nova denúcia do candidato %s: <br><br>", candidate.Name))
-	emailBodyBuilder.WriteString(fmt.Sprintf("\n\n%s\n", report))
-	return emailBodyBuilder.String()
+const emailTemplatesDir = "web/emails"
+
+var emailTemplates = mustLoadEmailTemplates()
+
+func mustLoadEmailTemplates() *email.Templates {
+	t, err := email.LoadTemplates(emailTemplatesDir)
+	if err != nil {
+		log.Fatalf("error loading email templates from %s:%q", emailTemplatesDir, err)
+	}
+	return t
+}
+
+type accessLink struct {
+	Candidate *descritor.CandidateForDB
+	Link      string
+}
+
+type accessEmailData struct {
+	Email      string
+	Year       int
+	TTLMinutes int
+	Links      []accessLink
+}
+
+// buildProfileAccessEmail builds the email with the access links. links[i]
+// gives access to candidates[i].
+func buildProfileAccessEmail(address string, candidates []*descritor.CandidateForDB, links []string) (*email.Message, error) {
+	data := accessEmailData{
+		Email:      strings.ToLower(address),
+		Year:       globals.Year,
+		TTLMinutes: int(loginTokenTTL.Minutes()),
+	}
+	for i, c := range candidates {
+		data.Links = append(data.Links, accessLink{Candidate: c, Link: links[i]})
+	}
+	return emailTemplates.Render("acesso", data)
+}
+
+type faleConoscoEmailData struct {
+	Type      string
+	Subject   string
+	Content   string
+	Candidate *descritor.CandidateForDB
+	TicketURL string
+}
+
+// buildFaleConoscoEmail builds the email telling the team about a message of
+// the candidate, which can be answered at ticketURL.
+func buildFaleConoscoEmail(candidate *descritor.CandidateForDB, mType, subject, content, ticketURL string) (*email.Message, error) {
+	return emailTemplates.Render("fale-conosco", faleConoscoEmailData{
+		Type:      mType,
+		Subject:   subject,
+		Content:   content,
+		Candidate: candidate,
+		TicketURL: ticketURL,
+	})
+}
+
+type faleConoscoReplyEmailData struct {
+	Candidate *descritor.CandidateForDB
+	Subject   string
+	Reply     string
+	LoginURL  string
+}
+
+// buildFaleConoscoReplyEmail builds the email with the reply of the team to
+// a message of the candidate.
+func buildFaleConoscoReplyEmail(candidate *descritor.CandidateForDB, subject, reply string) (*email.Message, error) {
+	return emailTemplates.Render("fale-conosco-resposta", faleConoscoReplyEmailData{
+		Candidate: candidate,
+		Subject:   subject,
+		Reply:     reply,
+		LoginURL:  fmt.Sprintf("%s/sou-candidato", siteURL),
+	})
+}
+
+// buildPhotoRejectedEmail builds the email telling the candidate why the
+// uploaded photo was not published.
+func buildPhotoRejectedEmail(candidate *descritor.CandidateForDB, reason string) (*email.Message, error) {
+	return emailTemplates.Render("foto-rejeitada", submissionRejectedEmailData{
+		Candidate: candidate,
+		Reason:    reason,
+		LoginURL:  fmt.Sprintf("%s/sou-candidato", siteURL),
+	})
+}
+
+type reportEmailData struct {
+	Candidate *descritor.CandidateForDB
+	Category  string
+	Report    string
+	TicketURL string
+}
+
+// buildReportEmail builds the email telling the team a voter reported the
+// profile of the candidate.
+func buildReportEmail(candidate *descritor.CandidateForDB, category, report, ticketURL string) (*email.Message, error) {
+	return emailTemplates.Render("denuncia", reportEmailData{
+		Candidate: candidate,
+		Category:  category,
+		Report:    report,
+		TicketURL: ticketURL,
+	})
+}
+
+type requestProposalsEmailData struct {
+	Candidate  *descritor.CandidateForDB
+	SiteURL    string
+	ProfileURL string
+}
+
+// buildRequestProposalsEmail builds the text only email voters send, from
+// their own email clients, to candidates without proposals.
+func buildRequestProposalsEmail(candidate *descritor.CandidateForDB) (*email.Message, error) {
+	return emailTemplates.Render("solicitar-propostas", requestProposalsEmailData{
+		Candidate:  candidate,
+		SiteURL:    siteURL,
+		ProfileURL: fmt.Sprintf("%s/c/%d/%s", siteURL, candidate.Year, candidate.SequencialCandidate),
+	})
+}
+
+type submissionRejectedEmailData struct {
+	Candidate *descritor.CandidateForDB
+	Reason    string
+	LoginURL  string
+}
+
+// buildSubmissionRejectedEmail builds the email telling the candidate why
+// the changes to the profile were not published.
+func buildSubmissionRejectedEmail(candidate *descritor.CandidateForDB, reason string) (*email.Message, error) {
+	return emailTemplates.Render("moderacao-rejeitada", submissionRejectedEmailData{
+		Candidate: candidate,
+		Reason:    reason,
+		LoginURL:  fmt.Sprintf("%s/sou-candidato", siteURL),
+	})
 }
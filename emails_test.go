@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/email"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of emails")
+
+func checkGoldenEmail(t *testing.T, name string, m *email.Message) {
+	t.Helper()
+	got := []byte(fmt.Sprintf("Subject: %s\n\n== text ==\n%s\n== html ==\n%s", m.Subject, m.Text, m.HTML))
+	golden := filepath.Join("testdata", "emails", name+".golden")
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("email %s differs from %s (run go test -update to update it):\n%s", name, golden, got)
+	}
+}
+
+func TestEmails(t *testing.T) {
+	_, store := newTestServer(t)
+	find := func(seqID string) *descritor.CandidateForDB {
+		c, err := store.FindCandidateBySequencialIDAndYear(2020, seqID)
+		if err != nil {
+			t.Fatalf("want error nil, got %q", err)
+		}
+		return c
+	}
+	maria, joao, antonio := find("20000000001"), find("20000000002"), find("20000000003")
+	// Fields provided by candidates must be escaped in HTML.
+	antonio.Name = `ANTÔNIO <script>alert("oi")</script>`
+
+	testCases := []struct {
+		name  string
+		build func() (*email.Message, error)
+	}{
+		{"acesso", func() (*email.Message, error) {
+			return buildProfileAccessEmail("maria.jose@exemplo.com", []*descritor.CandidateForDB{maria}, []string{"http://localhost/entrar?token=abc"})
+		}},
+		{"acesso-varias-candidaturas", func() (*email.Message, error) {
+			return buildProfileAccessEmail("COMITE@EXEMPLO.COM", []*descritor.CandidateForDB{joao, antonio}, []string{"http://localhost/entrar?token=abc", "http://localhost/entrar?token=def"})
+		}},
+		{"fale-conosco", func() (*email.Message, error) {
+			return buildFaleConoscoEmail(antonio, "sugestão", "Nova pauta", "Gostaria de sugerir a pauta <b>Cultura</b>.\nObrigado!", "http://localhost/admin/fale-conosco/abc")
+		}},
+		{"fale-conosco-resposta", func() (*email.Message, error) {
+			return buildFaleConoscoReplyEmail(antonio, "Nova pauta", "Obrigado pela sugestão, a pauta <b>Cultura</b> foi incluída.")
+		}},
+		{"denuncia", func() (*email.Message, error) {
+			return buildReportEmail(antonio, "Outro motivo", "O perfil contém <a href=\"http://exemplo.com\">link</a> indevido.", "http://localhost/admin/denuncias/abc")
+		}},
+		{"solicitar-propostas", func() (*email.Message, error) {
+			return buildRequestProposalsEmail(joao)
+		}},
+		{"moderacao-rejeitada", func() (*email.Message, error) {
+			return buildSubmissionRejectedEmail(antonio, "A biografia contém <b>ofensas</b> a outros candidatos.")
+		}},
+		{"foto-rejeitada", func() (*email.Message, error) {
+			return buildPhotoRejectedEmail(antonio, "A foto não mostra o rosto do candidato.")
+		}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.build()
+			if err != nil {
+				t.Fatalf("want error nil, got %q", err)
+			}
+			checkGoldenEmail(t, tt.name, m)
+		})
+	}
+}
@@ -1,139 +1,242 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/candidatos-info/descritor"
 	"github.com/candidatos-info/site/db"
-	"github.com/candidatos-info/site/email"
 	"github.com/candidatos-info/site/exception"
-	"github.com/candidatos-info/site/token"
 	"github.com/labstack/echo"
 )
 
-func newFaleConoscoHandler() echo.HandlerFunc {
+const (
+	maxFaleConoscoSubjectSize = 200
+	maxFaleConoscoTextSize    = 5000
+)
+
+var (
+	faleConoscoTypes = []struct {
+		Label string
+		Value string
+	}{
+		{Label: "Sugestão", Value: "sugestão"},
+		{Label: "Reclamação", Value: "reclamação"},
+		{Label: "Denúncia", Value: "denúncia"},
+		{Label: "Pergunta", Value: "pergunta"},
+		{Label: "Requisitar nova Causa/Pauta", Value: "nova-causa"},
+	}
+
+	formKeyRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+func validFaleConoscoType(mType string) bool {
+	for _, t := range faleConoscoTypes {
+		if t.Value == mType {
+			return true
+		}
+	}
+	return false
+}
+
+// faleConoscoTicketPath returns the path of the ticket for the candidate,
+// authenticated by the access token.
+func faleConoscoTicketPath(id, accessToken string) string {
+	return fmt.Sprintf("/fale-conosco/%s?access_token=%s", id, url.QueryEscape(accessToken))
+}
+
+// GET /fale-conosco shows the form to contact the team and the tickets of
+// the candidature.
+func newFaleConoscoHandler(tickets db.TicketStore) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		encodedAccessToken := c.QueryParam("access_token")
-		accessTokenBytes, err := base64.StdEncoding.DecodeString(encodedAccessToken)
+		formKey, err := newID()
 		if err != nil {
-			log.Printf("error decoding access token %s", string(encodedAccessToken))
-			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-				"ErrorMsg": "Código de acesso inválido",
-				"Success":  false,
-			})
+			log.Printf("failed to create form key:%q\n", err)
 		}
-		if !tokenService.IsValid(string(accessTokenBytes)) {
-			log.Printf("invalid access token")
-			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-				"ErrorMsg": "Código de acesso inválido",
-				"Success":  false,
-			})
+		cand := authenticatedCandidate(c)
+		list, err := tickets.ListCandidatureTickets(db.TicketContact, cand.Year, cand.SequencialCandidate)
+		if err != nil {
+			log.Printf("failed to list tickets of candidature (%s), error %v\n", cand.SequencialCandidate, err)
 		}
 		return c.Render(http.StatusOK, "fale-conosco.html", map[string]interface{}{
-			"Token": encodedAccessToken,
-			"TypeOptions": []struct {
-				Label string
-				Value string
-			}{
-				{Label: "Sugestão", Value: "sugestão"},
-				{Label: "Reclamação", Value: "reclamação"},
-				{Label: "Denúncia", Value: "denúncia"},
-				{Label: "Pergunta", Value: "pergunta"},
-				{Label: "Requisitar nova Causa/Pauta", Value: "nova-causa"},
-			},
+			"Token":       authenticatedAccessToken(c),
+			"FormKey":     formKey,
+			"TypeOptions": faleConoscoTypes,
+			"Tickets":     list,
 		})
 	}
 }
 
-func newFaleConoscoFormHandler(db *db.Client, tokenService *token.Token, emailClient *email.Client, contactEmail string) echo.HandlerFunc {
+// POST /fale-conosco stores the message of the candidate as a ticket and
+// emails the team. The form key makes resubmitting the form harmless.
+func newFaleConoscoFormHandler(dbClient db.Store, contactEmail string) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		encodedAccessToken := c.FormValue("access_token")
-		accessTokenBytes, err := base64.StdEncoding.DecodeString(encodedAccessToken)
-		if err != nil {
-			log.Printf("error decoding access token %s", string(encodedAccessToken))
+		mType := c.FormValue("tipo")
+		subject := strings.TrimSpace(c.FormValue("assunto"))
+		content := strings.TrimSpace(c.FormValue("descricao"))
+		if mType == "" || subject == "" || content == "" {
 			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-				"ErrorMsg": "Código de acesso inválido",
+				"ErrorMsg": "Tipo, assunto e descrição são campos obrigatórios.",
 				"Success":  false,
 			})
 		}
-		if !tokenService.IsValid(string(accessTokenBytes)) {
-			log.Printf("invalid access token")
-			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-				"ErrorMsg": "Código de acesso inválido",
+		if !validFaleConoscoType(mType) {
+			return c.Render(http.StatusBadRequest, "fale-conosco-success.html", map[string]interface{}{
+				"ErrorMsg": "Tipo de mensagem inválido.",
 				"Success":  false,
 			})
 		}
-		mType := c.FormValue("tipo")
-		subject := c.FormValue("assunto")
-		content := c.FormValue("descricao")
-		if mType == "" || subject == "" || content == "" {
-			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-				"ErrorMsg": "Tipo, assunto e descrição são campos obrigatórios.",
+		if utf8.RuneCountInString(subject) > maxFaleConoscoSubjectSize || utf8.RuneCountInString(content) > maxFaleConoscoTextSize {
+			return c.Render(http.StatusBadRequest, "fale-conosco-success.html", map[string]interface{}{
+				"ErrorMsg": fmt.Sprintf("O tamanho máximo do assunto é de %d caracteres e da descrição é de %d caracteres.", maxFaleConoscoSubjectSize, maxFaleConoscoTextSize),
 				"Success":  false,
 			})
 		}
-		claims, err := token.GetClaims(string(accessTokenBytes))
+		cand := authenticatedCandidate(c)
+		id, err := faleConoscoTicketID(cand, c.FormValue("chave"))
 		if err != nil {
-			log.Printf("failed to extract email from token claims, erro %v\n", err)
+			log.Printf("failed to create ticket id, error %v\n", err)
 			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
 				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
 				"Success":  false,
 			})
 		}
-		var cand *descritor.CandidateForDB
-		if s, ok := claims["seqid"]; ok {
-			cand, err = db.FindCandidateBySequencialIDAndYear(globals.Year, s)
-			if err != nil {
-				log.Printf("Failed find candidate on DB (seqID:%s, year:%d), error %q\n", s, globals.Year, err)
-				if err != nil {
-					return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-						"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-						"Success":  false,
-					})
-				}
-			}
-		}
-		if cand == nil { // fallback on the old behavior.
-			email := claims["email"]
-			cand, err = db.GetCandidateByEmail(email, globals.Year)
-			if err != nil {
-				log.Printf("failed find candidate on DB (email:%s), error %v\n", email, err)
-				switch {
-				case err != nil && err.(*exception.Exception).Code == exception.NotFound:
-					return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-						"ErrorMsg": fmt.Sprintf("Não encontramos um cadastro de candidatura através do email %s. Por favor verifique se o email está correto.", email),
-						"Success":  false,
-					})
-				case err != nil:
-					return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
-						"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
-						"Success":  false,
-					})
-				}
-			}
-		}
-		mSub := fmt.Sprintf("[Fale conosco] %s", mType)
-		mContent := fmt.Sprintf(`
-Saudações Equipe Técnica do Candidatos.info,
-
-%s
-
-Cordialmente,
-%s(%s),%d`, content, cand.BallotName, cand.Name, cand.BallotNumber)
-		if err := emailClient.Send(emailClient.Email, []string{contactEmail}, mSub, mContent); err != nil {
-			log.Printf("failed to sending email (%s):%q", contactEmail, err)
+		now := time.Now().UTC()
+		err = dbClient.CreateTicket(&db.Ticket{
+			ID:           id,
+			Kind:         db.TicketContact,
+			Status:       db.TicketOpen,
+			Category:     mType,
+			Year:         cand.Year,
+			SequentialID: cand.SequencialCandidate,
+			Email:        strings.ToLower(cand.Email),
+			Subject:      subject,
+			Messages:     []*db.TicketMessage{{CreatedAt: now, Author: candidateActor(cand), Text: content}},
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		})
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.Conflict:
+			// The form was submitted again, the message is already stored.
+		case err != nil:
+			log.Printf("failed to store fale conosco ticket (%s), error %v\n", cand.SequencialCandidate, err)
 			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
 				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
 				"Success":  false,
 			})
+		default:
+			notifyTeamOfFaleConosco(dbClient, contactEmail, cand, id, mType, subject, content, "fale-conosco/"+id)
 		}
 		return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
 			"Candidate":    cand,
 			"Success":      true,
+			"Year":         cand.Year,
 			"SequentialID": cand.SequencialCandidate,
+			"TicketPath":   faleConoscoTicketPath(id, authenticatedAccessToken(c)),
 		})
 	}
 }
+
+// faleConoscoTicketID returns the ID of the ticket opened by the form with
+// the given key, random if the key is missing or malformed.
+func faleConoscoTicketID(cand *descritor.CandidateForDB, formKey string) (string, error) {
+	if !formKeyRegex.MatchString(formKey) {
+		return newID()
+	}
+	return fmt.Sprintf("%s-%s", cand.SequencialCandidate, formKey), nil
+}
+
+// notifyTeamOfFaleConosco queues the email telling the team about a message
+// of the candidate. Failures are only logged, the message is already stored.
+func notifyTeamOfFaleConosco(outbox db.OutboxStore, contactEmail string, cand *descritor.CandidateForDB, ticketID, mType, subject, content, idempotencyKey string) {
+	message, err := buildFaleConoscoEmail(cand, mType, subject, content, fmt.Sprintf("%s/admin/fale-conosco/%s", siteURL, ticketID))
+	if err != nil {
+		log.Printf("failed to build fale conosco email, error %v\n", err)
+		return
+	}
+	message.From = emailFrom
+	message.To = []string{contactEmail}
+	if _, err := enqueueEmail(outbox, idempotencyKey, message); err != nil {
+		log.Printf("failed to queue email (%s):%q", contactEmail, err)
+	}
+}
+
+// findCandidateTicket returns the ticket of the id path param if it belongs
+// to the authenticated candidature. On failure it returns a nil ticket and
+// the error response.
+func findCandidateTicket(c echo.Context, tickets db.TicketStore) (*db.Ticket, error) {
+	cand := authenticatedCandidate(c)
+	t, err := tickets.FindTicket(c.Param("id"))
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		t = nil
+	case err != nil:
+		log.Printf("failed to find ticket (%s), error %v\n", c.Param("id"), err)
+		return nil, c.Render(http.StatusInternalServerError, "fale-conosco-success.html", map[string]interface{}{
+			"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+			"Success":  false,
+		})
+	}
+	if t == nil || t.Kind != db.TicketContact || t.Year != cand.Year || t.SequentialID != cand.SequencialCandidate {
+		return nil, c.Render(http.StatusNotFound, "fale-conosco-success.html", map[string]interface{}{
+			"ErrorMsg": "Mensagem não encontrada.",
+			"Success":  false,
+		})
+	}
+	return t, nil
+}
+
+// GET /fale-conosco/:id shows the conversation of the ticket.
+func newFaleConoscoTicketHandler(tickets db.TicketStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, err := findCandidateTicket(c, tickets)
+		if t == nil {
+			return err
+		}
+		return c.Render(http.StatusOK, "fale-conosco-ticket.html", map[string]interface{}{
+			"Token":  authenticatedAccessToken(c),
+			"Ticket": t,
+		})
+	}
+}
+
+// POST /fale-conosco/:id adds the reply of the candidate to the ticket,
+// reopening it if needed, and emails the team.
+func newFaleConoscoReplyHandler(dbClient db.Store, contactEmail string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, err := findCandidateTicket(c, dbClient)
+		if t == nil {
+			return err
+		}
+		text := strings.TrimSpace(c.FormValue("mensagem"))
+		if text == "" || utf8.RuneCountInString(text) > maxFaleConoscoTextSize {
+			return c.Render(http.StatusBadRequest, "fale-conosco-success.html", map[string]interface{}{
+				"ErrorMsg": fmt.Sprintf("A mensagem é obrigatória e pode ter até %d caracteres.", maxFaleConoscoTextSize),
+				"Success":  false,
+			})
+		}
+		cand := authenticatedCandidate(c)
+		now := time.Now().UTC()
+		if err := dbClient.AddTicketMessage(t.ID, &db.TicketMessage{CreatedAt: now, Author: candidateActor(cand), Text: text}); err != nil {
+			log.Printf("failed to add message to ticket (%s), error %v\n", t.ID, err)
+			return c.Render(http.StatusOK, "fale-conosco-success.html", map[string]interface{}{
+				"ErrorMsg": "Erro inesperado. Por favor, tente novamente mais tarde.",
+				"Success":  false,
+			})
+		}
+		if !db.TicketIsOpen(t.Status) {
+			event := &db.TicketEvent{CreatedAt: now, Actor: candidateActor(cand), Status: db.TicketOpen, Note: "reaberto pela resposta do candidato"}
+			if err := dbClient.UpdateTicketStatus(t.ID, t.Assignee, event); err != nil {
+				log.Printf("failed to reopen ticket (%s), error %v\n", t.ID, err)
+			}
+		}
+		notifyTeamOfFaleConosco(dbClient, contactEmail, cand, t.ID, t.Category, "Re: "+t.Subject, text, "")
+		return c.Redirect(http.StatusSeeOther, faleConoscoTicketPath(t.ID, authenticatedAccessToken(c)))
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+// loginTestCandidate logs the candidature in and returns its access token.
+func loginTestCandidate(t *testing.T, e *echo.Echo, store *db.MemoryClient, address, seqID string) string {
+	t.Helper()
+	link, err := newLoginLink(store, tokenService, address, seqID, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	u, _ := url.Parse(link)
+	location, err := url.Parse(postForm(t, e, "/entrar", url.Values{"token": {u.Query().Get("token")}}).Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	return location.Query().Get("access_token")
+}
+
+func TestFaleConoscoTickets(t *testing.T) {
+	e, store := newTestServer(t)
+	requireCandidate := newCandidateAuthMiddleware(store, tokenService)
+	e.GET("/fale-conosco", newFaleConoscoHandler(store), requireCandidate)
+	e.POST("/fale-conosco", newFaleConoscoFormHandler(store, testContactEmail), requireCandidate)
+	e.GET("/fale-conosco/:id", newFaleConoscoTicketHandler(store), requireCandidate)
+	e.POST("/fale-conosco/:id", newFaleConoscoReplyHandler(store, testContactEmail), requireCandidate)
+	accessToken := loginTestCandidate(t, e, store, "maria.jose@exemplo.com", "20000000001")
+
+	message := url.Values{
+		"access_token": {accessToken},
+		"chave":        {"0123456789abcdef0123456789abcdef"},
+		"tipo":         {"pergunta"},
+		"assunto":      {"Foto do perfil"},
+		"descricao":    {"Como altero a minha foto?"},
+	}
+	for i := 0; i < 2; i++ {
+		if rec := postForm(t, e, "/fale-conosco", message); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/fale-conosco/20000000001-") {
+			t.Fatalf("want link to the ticket, got status %d", rec.Code)
+		}
+	}
+	tickets, err := store.ListCandidatureTickets(db.TicketContact, 2020, "20000000001")
+	if err != nil || len(tickets) != 1 || tickets[0].Status != db.TicketOpen || len(tickets[0].Messages) != 1 {
+		t.Fatalf("want 1 open ticket after resubmitting the form, got %v (error %v)", tickets, err)
+	}
+	id := tickets[0].ID
+	emails, err := store.ListEmails("", 10)
+	if err != nil || len(emails) != 1 || emails[0].To[0] != testContactEmail || !strings.Contains(emails[0].Text, "/admin/fale-conosco/"+id) {
+		t.Errorf("want team emailed once about the new ticket, got %v (error %v)", emails, err)
+	}
+	invalid := url.Values{"access_token": {accessToken}, "tipo": {"elogio"}, "assunto": {"Assunto"}, "descricao": {"Descrição."}}
+	if rec := postForm(t, e, "/fale-conosco", invalid); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for unknown type, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	path := "/admin/fale-conosco/" + id
+	if rec := adminRequest(t, e, http.MethodGet, "/admin/fale-conosco", testAdminUser, nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), path) {
+		t.Errorf("want ticket in the queue, got status %d", rec.Code)
+	}
+	reply := url.Values{"mensagem": {"Envie a nova foto pelo formulário de perfil."}}
+	if rec := adminRequest(t, e, http.MethodPost, path+"/responder", testAdminUser, reply); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after replying, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, path, testAdminUser, url.Values{"status": {db.TicketResolved}, "responsavel": {testAdminUser}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after resolving, got status %d", rec.Code)
+	}
+	emails, _ = store.ListEmails("", 10)
+	found := false
+	for _, m := range emails {
+		if m.To[0] == "MARIA.JOSE@EXEMPLO.COM" && strings.Contains(m.Text, "Envie a nova foto pelo formulário de perfil.") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("want reply emailed to the candidate, got %v", emails)
+	}
+
+	ticketPath := "/fale-conosco/" + id + "?access_token=" + url.QueryEscape(accessToken)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ticketPath, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Envie a nova foto pelo formulário de perfil.") {
+		t.Errorf("want reply in the conversation, got status %d", rec.Code)
+	}
+	answer := url.Values{"access_token": {accessToken}, "mensagem": {"Não encontrei o campo da foto."}}
+	if rec := postForm(t, e, "/fale-conosco/"+id, answer); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after answering, got status %d", rec.Code)
+	}
+	ticket, err := store.FindTicket(id)
+	if err != nil || ticket.Status != db.TicketOpen || len(ticket.Messages) != 3 {
+		t.Errorf("want ticket reopened with 3 messages, got %+v (error %v)", ticket, err)
+	}
+
+	otherToken := loginTestCandidate(t, e, store, "comite@exemplo.com", "20000000002")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fale-conosco/"+id+"?access_token="+url.QueryEscape(otherToken), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("want status %d for ticket of another candidature, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// failingOutboxStore fails to queue every email.
+type failingOutboxStore struct {
+	db.Store
+}
+
+func (s *failingOutboxStore) EnqueueEmail(e *db.OutboxEmail) (bool, error) {
+	return false, exception.New(exception.ProcessmentError, "Falha ao salvar email", nil)
+}
+
+func TestAdminReplyTicketEmailFailure(t *testing.T) {
+	_, store := newTestServer(t)
+	ticket := &db.Ticket{ID: "t1", Kind: db.TicketContact, Status: db.TicketOpen, Year: 2020, SequentialID: "20000000001", Subject: "Foto", CreatedAt: time.Now()}
+	if err := store.CreateTicket(ticket); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/fale-conosco/t1/responder", strings.NewReader(url.Values{"mensagem": {"Olá!"}}.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("t1")
+	c.Set(operatorContextKey, &db.Operator{ID: testAdminUser})
+	if err := newAdminReplyTicketHandler(&failingOutboxStore{store})(c); err != nil || rec.Code != http.StatusSeeOther {
+		t.Errorf("want redirect when the email can not be queued, got status %d (error %v)", rec.Code, err)
+	}
+	if ticket, err := store.FindTicket("t1"); err != nil || len(ticket.Messages) != 1 {
+		t.Errorf("want the reply saved once, got %+v (error %v)", ticket, err)
+	}
+}
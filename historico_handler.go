@@ -0,0 +1,103 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/labstack/echo"
+)
+
+// Fields of the profile which are shown in the public history.
+var publicProfileFields = map[string]bool{
+	"biografia": true,
+	"propostas": true,
+	"contatos":  true,
+}
+
+type publicRevision struct {
+	Number       int
+	CreatedAt    time.Time
+	Author       string
+	Changes      []*db.FieldChange
+	RestoredFrom int
+}
+
+// GET /c/:year/:id/historico shows the changes made to the profile, so
+// voters can compare what candidates promise over time.
+func newHistoricoHandler(dbClient db.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		year, err := strconv.Atoi(c.Param("year"))
+		if err != nil {
+			log.Printf("Parâmetro year inválido (%s):%q\n", c.Param("year"), err)
+			return echo.ErrBadRequest
+		}
+		candidate, err := dbClient.FindCandidateBySequencialIDAndYear(year, c.Param("id"))
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			log.Printf("failed to find candidate (%s/%d), error %v\n", c.Param("id"), year, err)
+			return echo.ErrInternalServerError
+		}
+		disabled, err := isProfileDisabled(dbClient, year, candidate.SequencialCandidate)
+		if err != nil {
+			log.Printf("failed to check if profile is disabled (%s), error %v\n", candidate.SequencialCandidate, err)
+			return echo.ErrInternalServerError
+		}
+		var revisions []*publicRevision
+		if !disabled {
+			stored, err := dbClient.ListProfileRevisions(year, candidate.SequencialCandidate)
+			if err != nil {
+				log.Printf("failed to list profile revisions (%s), error %v\n", candidate.SequencialCandidate, err)
+				return echo.ErrInternalServerError
+			}
+			revisions = publicRevisions(stored)
+		}
+		return c.Render(http.StatusOK, "historico.html", map[string]interface{}{
+			"Candidato": candidate,
+			"Disabled":  disabled,
+			"Revisions": revisions,
+		})
+	}
+}
+
+// publicRevisions hides who exactly made each change and the changes which
+// are not part of the public profile, such as the acceptance of the terms.
+func publicRevisions(revisions []*db.ProfileRevision) []*publicRevision {
+	var public []*publicRevision
+	for _, r := range revisions {
+		var changes []*db.FieldChange
+		for _, change := range r.Changes {
+			if publicProfileFields[change.Field] {
+				changes = append(changes, change)
+			}
+		}
+		if len(changes) == 0 && r.Actor != importActor {
+			continue
+		}
+		public = append(public, &publicRevision{
+			Number:       r.Number,
+			CreatedAt:    r.CreatedAt,
+			Author:       revisionAuthor(r.Actor),
+			Changes:      changes,
+			RestoredFrom: r.RestoredFrom,
+		})
+	}
+	return public
+}
+
+func revisionAuthor(actor string) string {
+	switch {
+	case actor == importActor:
+		return "Perfil original"
+	case strings.HasPrefix(actor, "operador:"):
+		return "Equipe candidatos.info"
+	default:
+		return "Candidatura"
+	}
+}
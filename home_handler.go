@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/candidatos-info/descritor"
 	"github.com/candidatos-info/site/db"
 	"github.com/candidatos-info/site/exception"
 	"github.com/labstack/echo"
@@ -28,30 +27,34 @@ var (
 
 // struct with the result set from db
 type rawHomeResultSet struct {
-	transparentCandidatures    []*descritor.CandidateForDB
-	nonTransparentCandidatures []*descritor.CandidateForDB
+	transparentCandidatures    *db.CandidaturesPage
+	nonTransparentCandidatures *db.CandidaturesPage
+	transparentPage            db.Page
+	nonTransparentPage         db.Page
 }
 
 // struct which holds candidatures to be show on UI
 type homeResultSet struct {
 	transparentCandidatures    []*candidateCard
 	nonTransparentCandidatures []*candidateCard
+	transparentPagination      *pagination
+	nonTransparentPagination   *pagination
+	seed                       int64
 }
 
 type homeFilter struct {
-	State    string
-	Year     string
-	City     string
-	Role     string
-	Tag      []string
-	NextPage int
-	Name     string
+	State string
+	Year  string
+	City  string
+	Role  string
+	Tag   []string
+	Name  string
+	Party string
 }
 
-func newHomeHandler(db *db.Client) echo.HandlerFunc {
+func newHomeHandler(db db.Store) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		cities := []string{}
-		page := 0
 
 		year := c.QueryParam("ano")
 		if year == "" {
@@ -104,13 +107,18 @@ func newHomeHandler(db *db.Client) echo.HandlerFunc {
 			}
 		}
 		filter := &homeFilter{
-			State:    state,
-			City:     c.QueryParam("cidade"),
-			Year:     year,
-			Role:     c.QueryParam("cargo"),
-			NextPage: page + 1,
-			Tag:      c.Request().URL.Query()["tags"],
-			Name:     c.QueryParam("nome"),
+			State: state,
+			City:  c.QueryParam("cidade"),
+			Year:  year,
+			Role:  c.QueryParam("cargo"),
+			Tag:   c.Request().URL.Query()["tags"],
+			Name:  c.QueryParam("nome"),
+			Party: c.QueryParam("partido"),
+		}
+		// Landing page with the statistics of the location searched.
+		locationPage := ""
+		if y, err := strconv.Atoi(year); err == nil && state != "" {
+			locationPage = locationURL(y, state, city)
 		}
 		r := c.Render(http.StatusOK, "index.html", map[string]interface{}{
 			"AllStates":                uiStates,
@@ -122,6 +130,10 @@ func newHomeHandler(db *db.Client) echo.HandlerFunc {
 			"TransparentMaxCards":      transparentMaxCards,
 			"NonTransparentMaxCards":   nonTransparentMaxCards,
 			"NonTransparentCandidates": homeResultSet.nonTransparentCandidatures,
+			"TransparentPagination":    homeResultSet.transparentPagination,
+			"NonTransparentPagination": homeResultSet.nonTransparentPagination,
+			"MaxComparedCandidates":    maxComparedCandidates,
+			"LocationURL":              locationPage,
 		})
 		fmt.Println(r)
 		c.SetCookie(&http.Cookie{
@@ -133,70 +145,55 @@ func newHomeHandler(db *db.Client) echo.HandlerFunc {
 	}
 }
 
-func filterCandidates(c echo.Context, dbClient *db.Client) (*homeResultSet, error) {
+func filterCandidates(c echo.Context, dbClient db.Store) (*homeResultSet, error) {
 	rawHomeResultSet, err := getCandidatesByParams(c, dbClient)
 	if err != nil {
 		return nil, err
 	}
 	var transparentCandidatures []*candidateCard
-	for _, c := range rawHomeResultSet.transparentCandidatures {
-		var candidateTags []string
-		for _, proposal := range c.Proposals {
-			candidateTags = append(candidateTags, proposal.Topic)
-		}
-		transparentCandidatures = append(transparentCandidatures, &candidateCard{
-			c.Transparency,
-			c.PhotoURL,
-			c.BallotName,
-			strings.Title(strings.ToLower(c.City)),
-			c.State,
-			uiRoles[c.Role],
-			c.Party,
-			c.BallotNumber,
-			candidateTags,
-			c.SequencialCandidate,
-			c.Gender,
-		})
+	for _, c := range rawHomeResultSet.transparentCandidatures.Candidatures {
+		transparentCandidatures = append(transparentCandidatures, newCandidateCard(c))
 	}
 	var nonTransparentCandidatures []*candidateCard
-	for _, c := range rawHomeResultSet.nonTransparentCandidatures {
-		var candidateTags []string
-		for _, proposal := range c.Proposals {
-			candidateTags = append(candidateTags, proposal.Topic)
-		}
-		nonTransparentCandidatures = append(nonTransparentCandidatures, &candidateCard{
-			c.Transparency,
-			c.PhotoURL,
-			c.BallotName,
-			strings.Title(strings.ToLower(c.City)),
-			c.State,
-			uiRoles[c.Role],
-			c.Party,
-			c.BallotNumber,
-			candidateTags,
-			c.SequencialCandidate,
-			c.Gender,
-		})
+	for _, c := range rawHomeResultSet.nonTransparentCandidatures.Candidatures {
+		nonTransparentCandidatures = append(nonTransparentCandidatures, newCandidateCard(c))
 	}
+	u := c.Request().URL
 	return &homeResultSet{
 		transparentCandidatures:    transparentCandidatures,
 		nonTransparentCandidatures: nonTransparentCandidatures,
+		transparentPagination:      newPagination(u, pageQueryParam, rawHomeResultSet.transparentPage, rawHomeResultSet.transparentCandidatures),
+		nonTransparentPagination:   newPagination(u, nonTransparentPageQueryParam, rawHomeResultSet.nonTransparentPage, rawHomeResultSet.nonTransparentCandidatures),
+		seed:                       rawHomeResultSet.transparentPage.Seed,
 	}, nil
 }
 
-func getCandidatesByParams(c echo.Context, dbClient *db.Client) (*rawHomeResultSet, error) {
+// getCandidatesByParams returns the pages of candidatures matching the query
+// string.
+func getCandidatesByParams(c echo.Context, dbClient db.CandidateStore) (*rawHomeResultSet, error) {
 	queryMap, err := getQueryFilters(c)
 	if err != nil {
 		log.Printf("failed to get filters, error %v\n", err)
 		return nil, err
 	}
 	fmt.Println("QUERY MAP TO FILTER ", queryMap)
-	transparentCandidatures, err := dbClient.FindTransparentCandidatures(queryMap, transparentMaxCards)
-	nonTransparentCandidatures, err := dbClient.FindNonTransparentCandidatures(queryMap, nonTransparentMaxCards)
+	seed := searchSeed(c)
+	transparentPage := db.Page{Number: pageNumber(c, pageQueryParam), Size: transparentMaxCards, Seed: seed}
+	transparentCandidatures, err := dbClient.FindTransparentCandidatures(queryMap, transparentPage)
+	if err != nil {
+		return nil, err
+	}
+	nonTransparentPage := db.Page{Number: pageNumber(c, nonTransparentPageQueryParam), Size: nonTransparentMaxCards, Seed: seed}
+	nonTransparentCandidatures, err := dbClient.FindNonTransparentCandidatures(queryMap, nonTransparentPage)
+	if err != nil {
+		return nil, err
+	}
 	return &rawHomeResultSet{
 		transparentCandidatures:    transparentCandidatures,
 		nonTransparentCandidatures: nonTransparentCandidatures,
-	}, err
+		transparentPage:            transparentPage,
+		nonTransparentPage:         nonTransparentPage,
+	}, nil
 }
 
 func getQueryFilters(c echo.Context) (map[string]interface{}, error) {
@@ -207,6 +204,7 @@ func getQueryFilters(c echo.Context) (map[string]interface{}, error) {
 	gender := c.QueryParam("genero")
 	name := c.QueryParam("nome")
 	role := c.QueryParam("cargo")
+	party := c.QueryParam("partido")
 	tags := c.Request().URL.Query()["tags"]
 
 	queryMap := make(map[string]interface{})
@@ -220,7 +218,7 @@ func getQueryFilters(c echo.Context) (map[string]interface{}, error) {
 		y, err := strconv.Atoi(year)
 		if err != nil {
 			log.Printf("failed to parse year from string [%s] to int, error %v\n", year, err)
-			return nil, exception.New(exception.ProcessmentError, "Ano fornecido é inválido.", nil)
+			return nil, exception.New(exception.InvalidParameters, "Ano fornecido é inválido.", nil)
 		}
 		queryMap["year"] = y
 	}
@@ -231,6 +229,9 @@ func getQueryFilters(c echo.Context) (map[string]interface{}, error) {
 	if role != "" {
 		queryMap["role"] = role
 	}
+	if party != "" {
+		queryMap["party"] = party
+	}
 	if len(tags) > 0 {
 		queryMap["tags"] = tags
 	}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/search"
+	"github.com/labstack/echo"
+)
+
+// Landing pages of states and cities, at /:year/:estado and
+// /:year/:estado/:cidade, show statistics of their candidatures with links to
+// the search of the home page. Cities are identified by slugs, as in
+// /2020/al/maceio. Pages exist for the election years in the database when
+// the site starts.
+
+const (
+	locationStatsTTL  = time.Hour
+	maxLocationTopics = 10
+)
+
+type locationStatsEntry struct {
+	stats   *db.LocationStats
+	expires time.Time
+}
+
+// locationStatsCache keeps the statistics of states and cities for a while,
+// as they are aggregations over all their candidatures.
+type locationStatsCache struct {
+	store   db.StatsStore
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*locationStatsEntry
+}
+
+func newLocationStatsCache(store db.StatsStore, ttl time.Duration) *locationStatsCache {
+	return &locationStatsCache{store: store, ttl: ttl, entries: make(map[string]*locationStatsEntry)}
+}
+
+// get returns the statistics of the state or, if city is not empty, of the
+// city, computing them again once expired.
+func (sc *locationStatsCache) get(year int, state, city string) (*db.LocationStats, error) {
+	key := fmt.Sprintf("%d/%s/%s", year, state, city)
+	now := time.Now()
+	sc.mu.Lock()
+	e, ok := sc.entries[key]
+	sc.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.stats, nil
+	}
+	stats, err := sc.store.LocationStats(year, state, city)
+	if err != nil {
+		return nil, err
+	}
+	sc.mu.Lock()
+	sc.entries[key] = &locationStatsEntry{stats: stats, expires: now.Add(sc.ttl)}
+	sc.mu.Unlock()
+	return stats, nil
+}
+
+// statsRow is a line of a table of statistics.
+type statsRow struct {
+	Label   string
+	Total   int
+	Percent int
+	URL     string // search of the candidatures counted.
+}
+
+type cityLink struct {
+	Name string
+	URL  string
+}
+
+// locationSlug returns the name of the city as used in URLs, lowercase and
+// without accents, as in "sao-miguel-dos-campos".
+func locationSlug(name string) string {
+	return strings.Join(search.Tokenize(name), "-")
+}
+
+// locationYears are the election years with landing pages, set by
+// registerLocationRoutes.
+var locationYears = make(map[int]bool)
+
+// registerLocationRoutes registers the landing pages of the election years.
+// Years are static segments of the routes: the router of echo does not fall
+// back from a /:year parameter to the static files served at the root, such
+// as /robots.txt.
+func registerLocationRoutes(e *echo.Echo, dbClient db.Store, years []int) {
+	cache := newLocationStatsCache(dbClient, locationStatsTTL)
+	for _, year := range years {
+		h := newLocalidadeHandler(dbClient, cache, year)
+		e.GET(fmt.Sprintf("/%d/:estado", year), h)
+		e.GET(fmt.Sprintf("/%d/:estado/:cidade", year), h)
+		locationYears[year] = true
+	}
+}
+
+// locationURL returns the URL of the landing page of the state or, if city is
+// not empty, of the city. It is empty for years without landing pages.
+func locationURL(year int, state, city string) string {
+	if !locationYears[year] {
+		return ""
+	}
+	u := fmt.Sprintf("/%d/%s", year, strings.ToLower(state))
+	if city != "" {
+		u += "/" + locationSlug(city)
+	}
+	return u
+}
+
+// locationSearchURL returns the URL of the home page searching the
+// candidatures of the location, filtered by param if not empty.
+func locationSearchURL(year int, state, city, param, value string) string {
+	v := url.Values{}
+	v.Set("ano", strconv.Itoa(year))
+	v.Set("estado", state)
+	if city != "" {
+		v.Set("cidade", city)
+	}
+	if param != "" {
+		v.Set(param, value)
+	}
+	return "/?" + v.Encode()
+}
+
+// newStatsRows returns the rows of the counts, with the percentages of total.
+// The search of each row is filtered by param, if not empty.
+func newStatsRows(counts []*db.Count, total int, labels map[string]string, param string, searchURL func(param, value string) string) []*statsRow {
+	var rows []*statsRow
+	for _, count := range counts {
+		r := &statsRow{Label: count.Value, Total: count.Total}
+		if l, ok := labels[count.Value]; ok {
+			r.Label = l
+		}
+		if r.Label == "" {
+			r.Label = "Não informado"
+		}
+		if total > 0 {
+			r.Percent = count.Total * 100 / total
+		}
+		if param != "" && count.Value != "" {
+			r.URL = searchURL(param, count.Value)
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+// GET /:year/:estado and /:year/:estado/:cidade show the statistics of the
+// candidatures of the state or city in the election of the year. Other
+// spellings of the location are redirected to the canonical URL.
+func newLocalidadeHandler(dbClient db.CandidateStore, cache *locationStatsCache, year int) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := strings.ToUpper(c.Param("estado"))
+		cities, err := dbClient.GetCities(state)
+		switch {
+		case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+			return echo.ErrNotFound
+		case err != nil:
+			log.Printf("failed to find cities (%s), error %v\n", state, err)
+			return echo.ErrInternalServerError
+		}
+		city := ""
+		if slug := c.Param("cidade"); slug != "" {
+			for _, name := range cities {
+				if locationSlug(name) == locationSlug(slug) {
+					city = name
+					break
+				}
+			}
+			if city == "" {
+				return echo.ErrNotFound
+			}
+		}
+		if u := locationURL(year, state, city); c.Request().URL.Path != u {
+			return c.Redirect(http.StatusMovedPermanently, u)
+		}
+		stats, err := cache.get(year, state, city)
+		if err != nil {
+			log.Printf("failed to compute stats (%d/%s/%s), error %v\n", year, state, city, err)
+			return echo.ErrInternalServerError
+		}
+		if stats.Total == 0 {
+			return echo.ErrNotFound
+		}
+		searchURL := func(param, value string) string {
+			return locationSearchURL(year, state, city, param, value)
+		}
+		topics := stats.Topics
+		if len(topics) > maxLocationTopics {
+			topics = topics[:maxLocationTopics]
+		}
+		stateName, ok := uiStates[state]
+		if !ok {
+			stateName = state
+		}
+		data := map[string]interface{}{
+			"ElectionYear":        year,
+			"State":               state,
+			"StateName":           stateName,
+			"Stats":               stats,
+			"TransparencyPercent": stats.Transparent * 100 / stats.Total,
+			"Roles":               newStatsRows(stats.Roles, stats.Total, uiRoles, "cargo", searchURL),
+			"Genders":             newStatsRows(stats.Genders, stats.Total, gendersUI, "genero", searchURL),
+			"Parties":             newStatsRows(stats.Parties, stats.Total, nil, "partido", searchURL),
+			"Topics":              newStatsRows(topics, stats.Transparent, nil, "tags", searchURL),
+			"SearchURL":           searchURL("", ""),
+		}
+		if city != "" {
+			data["City"] = strings.Title(strings.ToLower(city))
+			data["StateURL"] = locationURL(year, state, "")
+		} else {
+			var links []*cityLink
+			for _, name := range cities {
+				links = append(links, &cityLink{Name: strings.Title(strings.ToLower(name)), URL: locationURL(year, state, name)})
+			}
+			data["Cities"] = links
+		}
+		return c.Render(http.StatusOK, "localidade.html", data)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+)
+
+func TestLocalidade(t *testing.T) {
+	e, _ := newTestServer(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/2020/al/maceio")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Maceió/AL - Eleições 2020",
+		"66%",
+		`href="/?ano=2020&amp;cidade=MACEI%C3%93&amp;estado=AL&amp;tags=Educa%C3%A7%C3%A3o"`,
+		`href="/?ano=2020&amp;cargo=vereador&amp;cidade=MACEI%C3%93&amp;estado=AL"`,
+		`href="/?ano=2020&amp;cidade=MACEI%C3%93&amp;estado=AL&amp;partido=PARTIDO&#43;B"`,
+		`href="/2020/al"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("want %s in the city page", want)
+		}
+	}
+
+	// The search linked from a party finds exactly the candidatures counted.
+	rec = get("/api/v1/candidatos?ano=2020&cidade=MACEI%C3%93&estado=AL&partido=PARTIDO+B")
+	var resp apiSearchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if n := resp.TransparentPagination.Total + resp.NonTransparentPagination.Total; n != 2 {
+		t.Errorf("want the 2 candidatures of the party, got %d", n)
+	}
+
+	rec = get("/2020/al")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/2020/al/arapiraca"`) {
+		t.Errorf("want state page linking to its cities, got status %d", rec.Code)
+	}
+
+	if rec := get("/2020/AL/MACEI%C3%93"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/2020/al/maceio" {
+		t.Errorf("want redirect to the canonical URL, got status %d and %q", rec.Code, rec.Header().Get("Location"))
+	}
+	for _, path := range []string{"/2020/xx", "/2020/al/recife", "/dois/al", "/2012/al/maceio"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("want status %d for %s, got %d", http.StatusNotFound, path, rec.Code)
+		}
+	}
+	for _, path := range []string{"/css/custom-css-bootstrap.css", "/img/candidata.png", "/robots.txt"} {
+		if rec := get(path); rec.Code != http.StatusOK {
+			t.Errorf("want static file %s served, got status %d", path, rec.Code)
+		}
+	}
+	if rec := get("/c/2020/20000000001"); !strings.Contains(rec.Body.String(), `href="/2020/al/maceio"`) {
+		t.Errorf("want candidate page linking to the city page")
+	}
+
+	// Past elections in the data have their own pages.
+	if rec := get("/2016/al/maceio"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Maceió/AL - Eleições 2016") {
+		t.Errorf("want the city page of 2016, got status %d", rec.Code)
+	}
+	if rec := get("/c/2016/16000000001"); !strings.Contains(rec.Body.String(), `href="/2016/al/maceio"`) {
+		t.Errorf("want candidate page of 2016 linking to the city page of 2016")
+	}
+}
+
+type countingStatsStore struct {
+	db.StatsStore
+	calls int
+}
+
+func (s *countingStatsStore) LocationStats(year int, state, city string) (*db.LocationStats, error) {
+	s.calls++
+	return s.StatsStore.LocationStats(year, state, city)
+}
+
+func TestLocationStatsCache(t *testing.T) {
+	_, store := newTestServer(t)
+	for _, tc := range []struct {
+		ttl   time.Duration
+		calls int
+	}{{time.Hour, 2}, {0, 3}} {
+		counter := &countingStatsStore{StatsStore: store}
+		cache := newLocationStatsCache(counter, tc.ttl)
+		for _, city := range []string{"MACEIÓ", "MACEIÓ", ""} {
+			if _, err := cache.get(2020, "AL", city); err != nil {
+				t.Fatalf("want error nil, got %q", err)
+			}
+		}
+		if counter.calls != tc.calls {
+			t.Errorf("want %d computations with ttl %v, got %d", tc.calls, tc.ttl, counter.calls)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/site/challenge"
+	"github.com/candidatos-info/site/ratelimit"
+	"github.com/labstack/echo"
+)
+
+const (
+	loginChallengeDifficulty = 16 // leading zero bits, about 65 thousand hashes on average.
+	loginChallengeMaxAge     = 30 * time.Minute
+	loginRateWindow          = time.Hour
+	loginAttemptsPerIP       = 10
+	loginAttemptsPerEmail    = 3
+
+	// Name of the form field hidden from people. Bots filling every field
+	// in the form give themselves away.
+	honeypotField = "site"
+)
+
+// loginGuard protects the login form against abuse: it limits the attempts
+// per IP and per email and requires a proof-of-work challenge to be solved by
+// the browser before submitting the form.
+type loginGuard struct {
+	challenges *challenge.Service
+	counters   ratelimit.Store
+	perIP      *ratelimit.Limiter
+	perEmail   *ratelimit.Limiter
+}
+
+func newLoginGuard(counters ratelimit.Store, challenges *challenge.Service) *loginGuard {
+	return &loginGuard{
+		challenges: challenges,
+		counters:   counters,
+		perIP:      ratelimit.New(counters, "login-ip", loginAttemptsPerIP, loginRateWindow),
+		perEmail:   ratelimit.New(counters, "login-email", loginAttemptsPerEmail, loginRateWindow),
+	}
+}
+
+// check returns the status and message to be shown when the submission must
+// not go on. An empty message means the login can proceed. Submissions which
+// are ignored get the same message as successful ones, so the response does
+// not tell whether the email is registered or was protected.
+func (g *loginGuard) check(c echo.Context, address string, now time.Time) (int, string) {
+	if c.FormValue(honeypotField) != "" {
+		log.Printf("ignoring login form with honeypot filled from %s\n", c.RealIP())
+		return http.StatusOK, loginRequestedMessage(address)
+	}
+	ok, err := g.perIP.Allow(c.RealIP(), now)
+	if err != nil {
+		log.Printf("failed to check login rate limit for ip (%s):%q\n", c.RealIP(), err)
+		return http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde."
+	}
+	if !ok {
+		return http.StatusTooManyRequests, "Muitas tentativas a partir da sua rede. Por favor tentar novamente mais tarde."
+	}
+	desafio := c.FormValue("desafio")
+	if err := g.challenges.Verify(desafio, c.FormValue("prova"), now); err != nil {
+		log.Printf("invalid login challenge from %s:%q\n", c.RealIP(), err)
+		return http.StatusBadRequest, "Não foi possível verificar o envio do formulário. Por favor recarregue a página e tente novamente."
+	}
+	n, err := g.counters.IncrementRateCounter("login-challenge:"+desafio, now.Add(loginChallengeMaxAge))
+	if err != nil {
+		log.Printf("failed to check login challenge use (%s):%q\n", desafio, err)
+		return http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde."
+	}
+	if n > 1 { // the form was submitted again.
+		return http.StatusOK, loginRequestedMessage(address)
+	}
+	if !emailRegex.MatchString(address) {
+		return http.StatusOK, ""
+	}
+	ok, err = g.perEmail.Allow(strings.ToLower(address), now)
+	if err != nil {
+		log.Printf("failed to check login rate limit for email (%s):%q\n", address, err)
+		return http.StatusInternalServerError, "Erro inesperado. Por favor tentar novamente mais tarde."
+	}
+	if !ok {
+		log.Printf("too many login attempts for email (%s)\n", address)
+		return http.StatusOK, loginRequestedMessage(address)
+	}
+	return http.StatusOK, ""
+}
@@ -8,11 +8,18 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/blob"
+	"github.com/candidatos-info/site/challenge"
 	"github.com/candidatos-info/site/db"
 	"github.com/candidatos-info/site/email"
+	"github.com/candidatos-info/site/ratelimit"
+	"github.com/candidatos-info/site/search"
 	"github.com/candidatos-info/site/token"
 	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
 )
 
 const (
@@ -24,10 +31,13 @@ const (
 	searchCookieExpiration = 360 //in hours
 	searchCacheCookie      = "searchCookie"
 	prodEnvironmentName    = "standard"
+	blobPath               = "/arquivos" // where files kept by the file blob store are served.
+	photoUploadLimit       = "6M"        // photo.MaxFileSize plus the other form fields.
 )
 
 var (
-	emailClient    *email.Client
+	emailClient    email.Mailer
+	emailFrom      string
 	tokenService   *token.Token
 	candidateRoles = []string{"vereador", "prefeito"} // available candidate roles
 	siteURL        string
@@ -56,6 +66,26 @@ type candidateCard struct {
 	Gender       string   `json:"gender"`
 }
 
+func newCandidateCard(c *descritor.CandidateForDB) *candidateCard {
+	var candidateTags []string
+	for _, proposal := range c.Proposals {
+		candidateTags = append(candidateTags, proposal.Topic)
+	}
+	return &candidateCard{
+		c.Transparency,
+		photoRenditionURL(c.PhotoURL, photoCardSize),
+		c.BallotName,
+		strings.Title(strings.ToLower(c.City)),
+		c.State,
+		uiRoles[c.Role],
+		c.Party,
+		c.BallotNumber,
+		candidateTags,
+		c.SequencialCandidate,
+		c.Gender,
+	}
+}
+
 // Shared **read-only** variable. Used by templates and other functions.
 // Please keep it short and instantiated in the beginning of the main.
 // Keep this struct close to templateRegistry, which is where it is used.
@@ -98,36 +128,20 @@ func main() {
 	globals.Env = os.Getenv("GAE_ENV") // should be correlated to prodEnvironmentName to be able to identify when the server is running in production.
 
 	// Other environment variables.
-	urlConnection := os.Getenv("DB_URL")
-	if urlConnection == "" {
-		log.Fatal("missing DB_URL environment variable")
-	}
-	dbName := os.Getenv("DB_NAME")
-	if dbName == "" {
-		log.Fatal("missing DN_NAME environment variable")
-	}
-	dbClient, err := db.NewMongoClient(urlConnection, dbName)
-	if err != nil {
-		log.Fatalf("failed to connect to database at URL [%s], error %v\n", urlConnection, err)
-	}
-	log.Println("connected to database")
-	emailAccount := os.Getenv("EMAIL")
-	if emailAccount == "" {
+	dbClient := mustCreateStore()
+	emailFrom = os.Getenv("EMAIL")
+	if emailFrom == "" {
 		log.Fatal("missing EMAIL environment variable")
 	}
 	contactEmail := os.Getenv("FALE_CONOSCO_EMAIL")
 	if contactEmail == "" {
 		log.Fatal("missing FALE_CONOSCO_EMAIL environment variable")
 	}
-	password := os.Getenv("PASSWORD")
-	if password == "" {
-		log.Fatal("missing PASSWORD environment variable")
-	}
 	siteURL = os.Getenv("SITE_URL")
 	if siteURL == "" {
 		log.Fatal("missing SITE_URL environment variable")
 	}
-	emailClient = email.New(emailAccount, password)
+	emailClient = mustCreateMailer(emailFrom)
 	authSecret := os.Getenv("SECRET")
 	if authSecret == "" {
 		log.Fatal("missing SECRET environment variable")
@@ -142,38 +156,61 @@ func main() {
 		log.Fatalf("failed to parte environment variable UPDATE_PROFILE with value [%s] to int, error %v", updateProfile, err)
 	}
 	allowedToUpdateProfile = r == 1
+	// With MODERATION=1 changes to biographies and proposals are only
+	// published after approved in the back office.
+	moderation := os.Getenv("MODERATION") == "1"
 
-	// Template registration.
-	// Template data MUST BE either nil or a map[string]interface{}.
-	templates := make(map[string]*template.Template)
-	templates["index.html"] = template.Must(template.ParseFiles("web/templates/index.html", "web/templates/layout.html"))
-	templates["sobre.html"] = template.Must(template.ParseFiles("web/templates/sobre.html", "web/templates/layout.html"))
-	templates["candidato.html"] = template.Must(template.ParseFiles("web/templates/candidato.html", "web/templates/layout.html"))
-	templates["sou-candidato.html"] = template.Must(template.ParseFiles("web/templates/sou-candidato.html", "web/templates/layout.html"))
-	templates["sou-candidato-success.html"] = template.Must(template.ParseFiles("web/templates/sou-candidato-success.html", "web/templates/layout.html"))
-	templates["aceitar-termo.html"] = template.Must(template.ParseFiles("web/templates/aceitar-termo.html", "web/templates/layout.html"))
-	templates["atualizar-candidato.html"] = template.Must(template.ParseFiles("web/templates/atualizar-candidato.html", "web/templates/layout.html"))
-	templates["atualizar-candidato-success.html"] = template.Must(template.ParseFiles("web/templates/atualizar-candidato-success.html", "web/templates/layout.html"))
-	templates["fale-conosco.html"] = template.Must(template.ParseFiles("web/templates/fale-conosco.html", "web/templates/layout.html"))
-	templates["fale-conosco-success.html"] = template.Must(template.ParseFiles("web/templates/fale-conosco-success.html", "web/templates/layout.html"))
+	suggestionIndex := search.NewPrefixIndex()
+	go keepSuggestionsUpdated(dbClient, suggestionIndex, suggestionsRebuildInterval)
+	go keepDeliveringEmails(dbClient, emailClient, outboxInterval)
 
 	e := echo.New()
 	e.Renderer = &templateRegistry{
-		templates: templates,
+		templates: mustLoadTemplates(),
 	}
 
 	// Rotes.
 	e.Static("/", "web/public")
 	e.GET("/", newHomeHandler(dbClient))
 	e.GET("/c/:year/:id", newCandidateHandler(dbClient))
+	e.GET("/c/:year/:id/historico", newHistoricoHandler(dbClient))
 	e.GET("/sobre", sobreHandler)
-	e.GET("/sou-candidato", souCandidatoGET)
-	e.POST("/sou-candidato", newSouCandidatoFormHandler(dbClient, tokenService, emailClient))
-	e.GET("/atualizar-candidatura", newAtualizarCandidaturaHandler(dbClient, tags))
-	e.POST("/atualizar-candidatura", newAtualizarCandidaturaFormHandler(dbClient))
-	e.POST("/aceitar-termo", newAceitarTermoFormHandler(dbClient))
-	e.GET("/fale-conosco", newFaleConoscoHandler())
-	e.POST("/fale-conosco", newFaleConoscoFormHandler(dbClient, tokenService, emailClient, contactEmail))
+	e.GET("/afinidade", newAfinidadeHandler(dbClient))
+	e.GET("/comparar", newCompararHandler(dbClient))
+	e.GET("/cola", newColaHandler(dbClient))
+	e.GET("/cola.pdf", newColaPDFHandler(dbClient))
+	electionYears, err := dbClient.ElectionYears()
+	if err != nil {
+		log.Fatalf("failed to find election years, error %v\n", err)
+	}
+	registerLocationRoutes(e, dbClient, electionYears)
+	rateLimitStore := mustCreateRateLimitStore(dbClient)
+	e.POST("/c/:year/:id/denunciar", newDenunciarHandler(dbClient, rateLimitStore, authSecret, contactEmail))
+	guard := newLoginGuard(rateLimitStore, challenge.New(authSecret, loginChallengeDifficulty, loginChallengeMaxAge))
+	calendar := newCampaignCalendar(dbClient, allowedToUpdateProfile)
+	e.GET("/sou-candidato", newSouCandidatoHandler(guard), requirePhase(calendar, db.PhaseLogin))
+	e.POST("/sou-candidato", newSouCandidatoFormHandler(dbClient, tokenService, guard), requirePhase(calendar, db.PhaseLogin))
+	e.GET("/entrar", entrarGET)
+	e.POST("/entrar", newEntrarFormHandler(dbClient, tokenService))
+	e.POST("/sair", newSairFormHandler(dbClient, tokenService))
+	requireCandidate := newCandidateAuthMiddleware(dbClient, tokenService)
+	files := mustCreateBlobStore(e)
+	e.GET("/atualizar-candidatura", newAtualizarCandidaturaHandler(dbClient, calendar, tags, files != nil), requireCandidate)
+	e.POST("/atualizar-candidatura", newAtualizarCandidaturaFormHandler(dbClient, tags, files != nil, moderation), requireCandidate, requirePhase(calendar, db.PhaseEdit))
+	if files != nil {
+		e.POST("/atualizar-candidatura/foto", newAtualizarFotoHandler(dbClient, files), middleware.BodyLimit(photoUploadLimit), requireCandidate, requirePhase(calendar, db.PhaseEdit))
+	}
+	e.POST("/aceitar-termo", newAceitarTermoFormHandler(dbClient), requireCandidate, requirePhase(calendar, db.PhaseTerms))
+	e.GET("/fale-conosco", newFaleConoscoHandler(dbClient), requireCandidate)
+	e.POST("/fale-conosco", newFaleConoscoFormHandler(dbClient, contactEmail), requireCandidate)
+	e.GET("/fale-conosco/:id", newFaleConoscoTicketHandler(dbClient), requireCandidate)
+	e.POST("/fale-conosco/:id", newFaleConoscoReplyHandler(dbClient, contactEmail), requireCandidate)
+	registerAPIRoutes(e.Group("/api/v1"), dbClient, suggestionIndex)
+	if adminPassword := os.Getenv("ADMIN_PASSWORD"); adminPassword != "" {
+		registerAdminRoutes(e.Group("/admin"), dbClient, calendar, tokenService, getEnvOrDefault("ADMIN_USER", "admin"), adminPassword)
+	} else {
+		log.Println("ADMIN_PASSWORD not set, back office disabled")
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -181,3 +218,159 @@ func main() {
 	}
 	log.Fatal(e.Start(":" + port))
 }
+
+// mustCreateStore returns the in-memory store when DB_FIXTURES points to a
+// fixtures directory, otherwise it connects to MongoDB using DB_URL and DB_NAME.
+func mustCreateStore() db.Store {
+	if fixturesDir := os.Getenv("DB_FIXTURES"); fixturesDir != "" {
+		memoryClient, err := db.NewMemoryClient(fixturesDir)
+		if err != nil {
+			log.Fatalf("failed to load fixtures from [%s], error %v\n", fixturesDir, err)
+		}
+		log.Printf("using in-memory database loaded from %s\n", fixturesDir)
+		return memoryClient
+	}
+	urlConnection := os.Getenv("DB_URL")
+	if urlConnection == "" {
+		log.Fatal("missing DB_URL environment variable")
+	}
+	dbName := os.Getenv("DB_NAME")
+	if dbName == "" {
+		log.Fatal("missing DN_NAME environment variable")
+	}
+	dbClient, err := db.NewMongoClient(urlConnection, dbName)
+	if err != nil {
+		log.Fatalf("failed to connect to database at URL [%s], error %v\n", urlConnection, err)
+	}
+	if err := dbClient.EnsureIndexes(); err != nil {
+		log.Fatalf("failed to create database indexes, error %v\n", err)
+	}
+	log.Println("connected to database")
+	return dbClient
+}
+
+// mustCreateRateLimitStore returns the store of rate limit counters selected
+// by RATE_LIMIT_STORE. The default "db" keeps the counters in the database,
+// shared by all instances of the site, while "memory" keeps them in the
+// instance memory.
+func mustCreateRateLimitStore(dbClient db.Store) ratelimit.Store {
+	switch s := getEnvOrDefault("RATE_LIMIT_STORE", "db"); s {
+	case "db":
+		return dbClient
+	case "memory":
+		return ratelimit.NewMemoryStore()
+	default:
+		log.Fatalf("invalid RATE_LIMIT_STORE [%s], want db or memory", s)
+		return nil
+	}
+}
+
+// mustCreateBlobStore returns the store of files uploaded by candidates
+// selected by BLOB_STORE, or nil when uploads are disabled. The default, and
+// for now only, "file" store keeps them in BLOB_DIR, served by the site under
+// /arquivos. Uploads are disabled when BLOB_DIR is not set.
+func mustCreateBlobStore(e *echo.Echo) blob.Store {
+	switch s := getEnvOrDefault("BLOB_STORE", "file"); s {
+	case "file":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			log.Println("BLOB_DIR not set, photo uploads disabled")
+			return nil
+		}
+		files, err := blob.NewFileStore(dir, siteURL+blobPath)
+		if err != nil {
+			log.Fatalf("failed to create file blob store, error %v\n", err)
+		}
+		e.Static(blobPath, dir)
+		log.Printf("storing uploaded files in %s\n", dir)
+		return files
+	default:
+		log.Fatalf("invalid BLOB_STORE [%s], want file", s)
+		return nil
+	}
+}
+
+// mustCreateMailer returns the Mailer selected by EMAIL_BACKEND. The "file"
+// backend writes messages to EMAIL_DIR and is meant for local development.
+// The default "smtp" backend is configured by SMTP_HOST, SMTP_PORT, SMTP_TLS
+// (starttls, tls or none), SMTP_USERNAME and PASSWORD, defaulting to Gmail
+// authenticated as the sender account. An empty SMTP_USERNAME disables
+// authentication.
+func mustCreateMailer(account string) email.Mailer {
+	switch backend := os.Getenv("EMAIL_BACKEND"); backend {
+	case "file":
+		dir := os.Getenv("EMAIL_DIR")
+		if dir == "" {
+			log.Fatal("missing EMAIL_DIR environment variable")
+		}
+		mailer, err := email.NewFileMailer(dir)
+		if err != nil {
+			log.Fatalf("failed to create file mailer, error %v\n", err)
+		}
+		log.Printf("writing emails to %s\n", dir)
+		return mailer
+	case "", "smtp":
+		cfg := email.SMTPConfig{
+			Host:     getEnvOrDefault("SMTP_HOST", "smtp.gmail.com"),
+			TLS:      getEnvOrDefault("SMTP_TLS", email.StartTLS),
+			Username: account,
+			Password: os.Getenv("PASSWORD"),
+		}
+		if username, ok := os.LookupEnv("SMTP_USERNAME"); ok { // empty disables authentication.
+			cfg.Username = username
+		}
+		port, err := strconv.Atoi(getEnvOrDefault("SMTP_PORT", "587"))
+		if err != nil {
+			log.Fatalf("failed to parse environment variable SMTP_PORT with value [%s] to int, error %v", os.Getenv("SMTP_PORT"), err)
+		}
+		cfg.Port = port
+		if cfg.Username != "" && cfg.Password == "" {
+			log.Fatal("missing PASSWORD environment variable")
+		}
+		mailer, err := email.NewSMTPMailer(cfg)
+		if err != nil {
+			log.Fatalf("failed to create smtp mailer, error %v\n", err)
+		}
+		return mailer
+	default:
+		log.Fatalf("invalid EMAIL_BACKEND [%s], use smtp or file", backend)
+	}
+	return nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// Template registration.
+// Template data MUST BE either nil or a map[string]interface{}.
+func mustLoadTemplates() map[string]*template.Template {
+	templates := make(map[string]*template.Template)
+	templates["index.html"] = template.Must(template.ParseFiles("web/templates/index.html", "web/templates/layout.html"))
+	templates["comparar.html"] = template.Must(template.ParseFiles("web/templates/comparar.html", "web/templates/layout.html"))
+	templates["localidade.html"] = template.Must(template.ParseFiles("web/templates/localidade.html", "web/templates/layout.html"))
+	templates["cola.html"] = template.Must(template.ParseFiles("web/templates/cola.html", "web/templates/layout.html"))
+	templates["afinidade.html"] = template.Must(template.ParseFiles("web/templates/afinidade.html", "web/templates/layout.html"))
+	templates["sobre.html"] = template.Must(template.ParseFiles("web/templates/sobre.html", "web/templates/layout.html"))
+	templates["candidato.html"] = template.Must(template.ParseFiles("web/templates/candidato.html", "web/templates/layout.html"))
+	templates["historico.html"] = template.Must(template.ParseFiles("web/templates/historico.html", "web/templates/layout.html"))
+	templates["denunciar-perfil.html"] = template.Must(template.ParseFiles("web/templates/denunciar-perfil.html", "web/templates/layout.html"))
+	templates["sou-candidato.html"] = template.Must(template.ParseFiles("web/templates/sou-candidato.html", "web/templates/layout.html"))
+	templates["sou-candidato-success.html"] = template.Must(template.ParseFiles("web/templates/sou-candidato-success.html", "web/templates/layout.html"))
+	templates["aceitar-termo.html"] = template.Must(template.ParseFiles("web/templates/aceitar-termo.html", "web/templates/layout.html"))
+	templates["atualizar-candidato.html"] = template.Must(template.ParseFiles("web/templates/atualizar-candidato.html", "web/templates/layout.html"))
+	templates["atualizar-candidato-success.html"] = template.Must(template.ParseFiles("web/templates/atualizar-candidato-success.html", "web/templates/layout.html"))
+	templates["fale-conosco.html"] = template.Must(template.ParseFiles("web/templates/fale-conosco.html", "web/templates/layout.html"))
+	templates["entrar.html"] = template.Must(template.ParseFiles("web/templates/entrar.html", "web/templates/layout.html"))
+	for _, name := range []string{"admin-emails.html", "admin-candidaturas.html", "admin-candidatura.html", "admin-auditoria.html", "admin-operadores.html", "admin-moderacao.html", "admin-submissao.html", "admin-denuncias.html", "admin-denuncia.html", "admin-fale-conosco.html", "admin-fale-conosco-ticket.html", "admin-calendario.html"} {
+		templates[name] = template.Must(template.ParseFiles("web/templates/"+name, "web/templates/admin-partials.html", "web/templates/layout.html"))
+	}
+	templates["periodo-fechado.html"] = template.Must(template.ParseFiles("web/templates/periodo-fechado.html", "web/templates/layout.html"))
+	templates["acesso-invalido.html"] = template.Must(template.ParseFiles("web/templates/acesso-invalido.html", "web/templates/layout.html"))
+	templates["fale-conosco-success.html"] = template.Must(template.ParseFiles("web/templates/fale-conosco-success.html", "web/templates/layout.html"))
+	templates["fale-conosco-ticket.html"] = template.Must(template.ParseFiles("web/templates/fale-conosco-ticket.html", "web/templates/layout.html"))
+	return templates
+}
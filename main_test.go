@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/candidatos-info/site/challenge"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/email"
+	"github.com/candidatos-info/site/search"
+	"github.com/candidatos-info/site/token"
+	"github.com/labstack/echo"
+)
+
+const (
+	testAdminUser     = "admin"
+	testAdminPassword = "test password"
+	testContactEmail  = "fale-conosco@candidatos.info"
+
+	testLoginChallengeDifficulty = 4
+)
+
+var testLoginChallenges = challenge.New("test secret", testLoginChallengeDifficulty, loginChallengeMaxAge)
+
+// newTestServer returns an echo server backed by the in-memory store loaded
+// from the fixtures used in the db package tests.
+func newTestServer(t *testing.T) (*echo.Echo, *db.MemoryClient) {
+	t.Helper()
+	store, err := db.NewMemoryClient("db/fixtures")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	globals.Year = 2020
+	siteURL = "http://localhost"
+	tokenService = token.New("test secret")
+	emailFrom = "contato@candidatos.info"
+	emailClient = email.NewMemoryMailer()
+	e := echo.New()
+	e.Renderer = &templateRegistry{
+		templates: mustLoadTemplates(),
+	}
+	e.Static("/", "web/public")
+	e.GET("/", newHomeHandler(store))
+	e.GET("/c/:year/:id", newCandidateHandler(store))
+	e.GET("/c/:year/:id/historico", newHistoricoHandler(store))
+	e.GET("/afinidade", newAfinidadeHandler(store))
+	e.GET("/comparar", newCompararHandler(store))
+	e.GET("/cola", newColaHandler(store))
+	e.GET("/cola.pdf", newColaPDFHandler(store))
+	electionYears, err := store.ElectionYears()
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	registerLocationRoutes(e, store, electionYears)
+	e.POST("/c/:year/:id/denunciar", newDenunciarHandler(store, store, "test secret", testContactEmail))
+	guard := newLoginGuard(store, testLoginChallenges)
+	calendar := newCampaignCalendar(store, true)
+	e.GET("/sou-candidato", newSouCandidatoHandler(guard), requirePhase(calendar, db.PhaseLogin))
+	e.POST("/sou-candidato", newSouCandidatoFormHandler(store, tokenService, guard), requirePhase(calendar, db.PhaseLogin))
+	e.GET("/entrar", entrarGET)
+	e.POST("/entrar", newEntrarFormHandler(store, tokenService))
+	e.POST("/sair", newSairFormHandler(store, tokenService))
+	requireCandidate := newCandidateAuthMiddleware(store, tokenService)
+	e.GET("/atualizar-candidatura", newAtualizarCandidaturaHandler(store, calendar, tags, true), requireCandidate)
+	e.POST("/aceitar-termo", newAceitarTermoFormHandler(store), requireCandidate, requirePhase(calendar, db.PhaseTerms))
+	suggestionIndex := search.NewPrefixIndex()
+	if err := rebuildSuggestions(store, suggestionIndex); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	registerAPIRoutes(e.Group("/api/v1"), store, suggestionIndex)
+	registerAdminRoutes(e.Group("/admin"), store, calendar, tokenService, testAdminUser, testAdminPassword)
+	return e, store
+}
+
+func TestHomeAndCandidatePages(t *testing.T) {
+	e, _ := newTestServer(t)
+	testCases := []struct {
+		name   string
+		target string
+		status int
+		want   string
+	}{
+		{"home", "/?ano=2020", http.StatusOK, "Busque por candidaturas"},
+		{"home filtered", "/?ano=2020&estado=AL&cidade=MACEI%C3%93", http.StatusOK, "PROFESSORA MARIA JOSÉ"},
+		{"candidate", "/c/2020/20000000001", http.StatusOK, "Ampliar o número de creches"},
+		{"candidate not found", "/c/2020/1", http.StatusNotFound, ""},
+		{"candidate invalid year", "/c/abc/20000000001", http.StatusBadRequest, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			if rec.Code != tc.status {
+				t.Fatalf("want status %d, got %d", tc.status, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tc.want) {
+				t.Errorf("want body to contain %q", tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+)
+
+// With pre-moderation enabled, changes to the biography and proposals of a
+// profile are stored as submissions and only published after approved by an
+// editor. The candidature keeps the last approved version, so the public
+// pages need no special handling. Contacts are not moderated.
+
+// needsModeration reports whether the biography or the proposals of the
+// candidate differ from the given ones.
+func needsModeration(candidate *descritor.CandidateForDB, biography string, proposals []*descritor.Proposal) bool {
+	return candidate.Biography != biography || formatProposals(candidate.Proposals) != formatProposals(proposals)
+}
+
+// submitProfile sends the biography and proposals to the moderation queue,
+// superseding pending submissions of the candidature.
+func submitProfile(store db.ModerationStore, candidate *descritor.CandidateForDB, sessionID, biography string, proposals []*descritor.Proposal) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	return store.CreateSubmission(&db.ProfileSubmission{
+		ID:           id,
+		Year:         candidate.Year,
+		SequentialID: candidate.SequencialCandidate,
+		Email:        strings.ToLower(candidate.Email),
+		SessionID:    sessionID,
+		CreatedAt:    time.Now().UTC(),
+		Status:       db.SubmissionPending,
+		Biography:    biography,
+		Proposals:    proposals,
+	})
+}
+
+// submissionChanges returns the fields of the profile changed by the
+// submission.
+func submissionChanges(candidate *descritor.CandidateForDB, s *db.ProfileSubmission) []*db.FieldChange {
+	after := *candidate
+	after.Biography = s.Biography
+	after.Proposals = s.Proposals
+	return profileChanges(candidate, &after)
+}
+
+// approveSubmission publishes the submission. The profile change is recorded
+// as made by the candidate, through the session which sent it, and the
+// approval as made by the operator. The submission is marked approved first,
+// so it is published only once, and returned to the queue if publishing
+// fails.
+func approveSubmission(store db.Store, s *db.ProfileSubmission, operator *db.Operator) error {
+	candidate, err := store.FindCandidateBySequencialIDAndYear(s.Year, s.SequentialID)
+	if err != nil {
+		return err
+	}
+	if err := store.ReviewSubmission(s.ID, db.SubmissionApproved, operator.ID, "", time.Now().UTC()); err != nil {
+		return err
+	}
+	changes := submissionChanges(candidate, s)
+	candidate.Biography = s.Biography
+	candidate.Proposals = s.Proposals
+	candidate.Transparency = 100 // Since we made all fields mandatory, if the candidate has registered, its transparency will be 100%
+	if err := saveCandidateProfile(store, candidate, candidateActor(candidate), s.SessionID, db.AuditUpdateProfile); err != nil {
+		if err := store.ReopenSubmission(s.ID); err != nil {
+			log.Printf("failed to reopen submission (%s), error %v\n", s.ID, err)
+		}
+		return err
+	}
+	if err := audit(store, operatorActor(operator), "", db.AuditApproveProfile, s.Year, s.SequentialID, changes); err != nil {
+		log.Printf("failed to audit submission approved (%s), error %v\n", s.ID, err)
+	}
+	return nil
+}
+
+// rejectSubmission discards the submission and emails the reason to the
+// candidate.
+func rejectSubmission(store db.Store, s *db.ProfileSubmission, operator *db.Operator, reason string) error {
+	candidate, err := store.FindCandidateBySequencialIDAndYear(s.Year, s.SequentialID)
+	if err != nil {
+		return err
+	}
+	if err := store.ReviewSubmission(s.ID, db.SubmissionRejected, operator.ID, reason, time.Now().UTC()); err != nil {
+		return err
+	}
+	message, err := buildSubmissionRejectedEmail(candidate, reason)
+	if err != nil {
+		return err
+	}
+	message.From = emailFrom
+	message.To = []string{candidate.Email}
+	if _, err := enqueueEmail(store, "moderacao:"+s.ID, message); err != nil {
+		return err
+	}
+	changes := []*db.FieldChange{{Field: "motivo da rejeição", After: reason}}
+	if err := audit(store, operatorActor(operator), "", db.AuditRejectProfile, s.Year, s.SequentialID, changes); err != nil {
+		log.Printf("failed to audit submission rejected (%s), error %v\n", s.ID, err)
+	}
+	return nil
+}
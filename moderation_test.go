@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+)
+
+func TestModeration(t *testing.T) {
+	e, store := newTestServer(t)
+	e.POST("/atualizar-candidatura", newAtualizarCandidaturaFormHandler(store, nil, false, true), newCandidateAuthMiddleware(store, tokenService))
+	link, err := newLoginLink(store, tokenService, "maria.jose@exemplo.com", "20000000001", "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	u, _ := url.Parse(link)
+	location, err := url.Parse(postForm(t, e, "/entrar", url.Values{"token": {u.Query().Get("token")}}).Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	accessToken := location.Query().Get("access_token")
+	postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}})
+	submit := func(bio string) string {
+		rec := postForm(t, e, "/atualizar-candidatura", url.Values{
+			"access_token":                 {accessToken},
+			"numTags":                      {"1"},
+			"descriptions[0][tag]":         {"Educação"},
+			"descriptions[0][description]": {"Creches em todos os bairros."},
+			"biography":                    {bio},
+			"contact":                      {"mariajose"},
+			"provider":                     {"twitter"},
+		})
+		return rec.Body.String()
+	}
+	publicPage := func() string {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/c/2020/20000000001", nil))
+		return rec.Body.String()
+	}
+
+	if body := submit("Primeira versão."); !strings.Contains(body, "enviadas para moderação") {
+		t.Fatalf("want changes sent to moderation")
+	}
+	submit("Segunda versão.")
+	pending, err := store.ListSubmissions(db.SubmissionPending, 10)
+	if err != nil || len(pending) != 1 || pending[0].Biography != "Segunda versão." {
+		t.Fatalf("want only the last submission pending, got %v (error %v)", pending, err)
+	}
+	candidate, err := store.FindCandidateBySequencialIDAndYear(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if candidate.Biography != "Professora da rede pública há 15 anos." || candidate.Contacts[0].Value != "mariajose" {
+		t.Errorf("want contacts published and biography waiting for moderation, got %+v", candidate)
+	}
+	if strings.Contains(publicPage(), "Segunda versão.") {
+		t.Errorf("want pending biography not published")
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/atualizar-candidatura?access_token="+url.QueryEscape(accessToken), nil))
+	if body := rec.Body.String(); !strings.Contains(body, "aguardam moderação") || !strings.Contains(body, "Segunda versão.") {
+		t.Errorf("want pending changes in the profile form")
+	}
+
+	createTestOperator(t, store, "leitor@exemplo.com", db.RoleViewer)
+	approve := "/admin/moderacao/" + pending[0].ID + "/aprovar"
+	if rec := adminRequest(t, e, http.MethodGet, "/admin/moderacao/"+pending[0].ID, "leitor@exemplo.com", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Segunda versão.") {
+		t.Errorf("want submission page, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, approve, "leitor@exemplo.com", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d for viewer approving, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, approve, testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after approving, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, approve, testAdminUser, nil); rec.Code != http.StatusConflict {
+		t.Errorf("want status %d approving twice, got %d", http.StatusConflict, rec.Code)
+	}
+	if !strings.Contains(publicPage(), "Segunda versão.") {
+		t.Errorf("want approved biography published")
+	}
+	revisions, err := store.ListProfileRevisions(2020, "20000000001")
+	if err != nil || len(revisions) == 0 || revisions[0].Actor != "candidato:maria.jose@exemplo.com" || revisions[0].SessionID != pending[0].SessionID {
+		t.Errorf("want approved revision made by the candidate session, got %v (error %v)", revisions, err)
+	}
+	entries, err := store.ListAuditEntries(2020, "20000000001", 10)
+	if err != nil || entries[0].Action != db.AuditApproveProfile || entries[0].Actor != operatorActor(&db.Operator{ID: testAdminUser}) {
+		t.Errorf("want approval in the audit trail, got %v (error %v)", entries, err)
+	}
+
+	submit("Terceira versão.")
+	pending, _ = store.ListSubmissions(db.SubmissionPending, 10)
+	reject := "/admin/moderacao/" + pending[0].ID + "/rejeitar"
+	if rec := adminRequest(t, e, http.MethodPost, reject, testAdminUser, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d rejecting without reason, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, reject, testAdminUser, url.Values{"motivo": {"Conteúdo ofensivo."}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after rejecting, got status %d", rec.Code)
+	}
+	if body := publicPage(); strings.Contains(body, "Terceira versão.") || !strings.Contains(body, "Segunda versão.") {
+		t.Errorf("want last approved biography published")
+	}
+	emails, err := store.ListEmails("", 10)
+	if err != nil || len(emails) != 1 || !strings.Contains(emails[0].Text, "Conteúdo ofensivo.") || emails[0].To[0] != "MARIA.JOSE@EXEMPLO.COM" {
+		t.Errorf("want rejection reason emailed to the candidate, got %v (error %v)", emails, err)
+	}
+
+	// Saving the published values discards the changes waiting for moderation.
+	submit("Quarta versão.")
+	pending, _ = store.ListSubmissions(db.SubmissionPending, 10)
+	if body := submit("Segunda versão."); !strings.Contains(body, "atualizado com sucesso") {
+		t.Fatalf("want published values saved without moderation")
+	}
+	if s, err := store.FindSubmission(pending[0].ID); err != nil || s.Status != db.SubmissionSuperseded {
+		t.Errorf("want pending submission superseded, got %+v (error %v)", s, err)
+	}
+}
+
+// failingProfileStore fails to update every profile.
+type failingProfileStore struct {
+	db.Store
+}
+
+func (s *failingProfileStore) UpdateCandidateProfile(candidate *descritor.CandidateForDB) (*descritor.CandidateForDB, error) {
+	return nil, exception.New(exception.ProcessmentError, "Falha ao atualizar perfil de candidato", nil)
+}
+
+func TestApproveSubmissionFailure(t *testing.T) {
+	_, store := newTestServer(t)
+	s := &db.ProfileSubmission{ID: "s1", Year: 2020, SequentialID: "20000000001", CreatedAt: time.Now(), Status: db.SubmissionPending, Biography: "Nova versão."}
+	if err := store.CreateSubmission(s); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := approveSubmission(&failingProfileStore{store}, s, &db.Operator{ID: testAdminUser}); err == nil {
+		t.Fatalf("want error when the profile can not be saved")
+	}
+	if pending, err := store.FindPendingSubmission(2020, "20000000001"); err != nil || pending.ID != "s1" {
+		t.Errorf("want submission back in the queue, got %+v (error %v)", pending, err)
+	}
+	if err := approveSubmission(store, s, &db.Operator{ID: testAdminUser}); err != nil {
+		t.Fatalf("want approving again to succeed, got %q", err)
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.Biography != "Nova versão." {
+		t.Errorf("want submission published, got %q", candidate.Biography)
+	}
+}
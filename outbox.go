@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/email"
+)
+
+const (
+	outboxInterval    = 10 * time.Second
+	outboxBatchSize   = 20
+	outboxLease       = 5 * time.Minute // time to send a claimed email before it can be claimed again.
+	outboxMaxAttempts = 8
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = 2 * time.Hour
+)
+
+// enqueueEmail persists the message to be sent by the outbox worker. When
+// the idempotency key is not empty and was already used, the message is not
+// queued and false is returned.
+func enqueueEmail(outbox db.OutboxStore, idempotencyKey string, m *email.Message) (bool, error) {
+	id, err := newID()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().UTC()
+	return outbox.EnqueueEmail(&db.OutboxEmail{
+		ID:             id,
+		IdempotencyKey: idempotencyKey,
+		From:           m.From,
+		To:             m.To,
+		Subject:        m.Subject,
+		HTML:           m.HTML,
+		Text:           m.Text,
+		Status:         db.EmailQueued,
+		CreatedAt:      now,
+		NextAttemptAt:  now,
+	})
+}
+
+// outboxBackoff returns how long to wait before the next attempt of an email
+// that already failed the given number of attempts.
+func outboxBackoff(attempts int) time.Duration {
+	d := time.Duration(float64(outboxBaseBackoff) * math.Pow(2, float64(attempts-1)))
+	if d <= 0 || d > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return d
+}
+
+// deliverDueEmails sends the emails due at now and returns how many were
+// sent. Failed emails are scheduled for a new attempt with exponential
+// backoff, up to outboxMaxAttempts, when they are dead-lettered.
+func deliverDueEmails(outbox db.OutboxStore, mailer email.Mailer, now time.Time) (int, error) {
+	emails, err := outbox.ClaimDueEmails(now, outboxLease, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, e := range emails {
+		err := mailer.Send(&email.Message{
+			From:    e.From,
+			To:      e.To,
+			Subject: e.Subject,
+			HTML:    e.HTML,
+			Text:    e.Text,
+		})
+		if err == nil {
+			if err := outbox.MarkEmailSent(e.ID, time.Now().UTC()); err != nil {
+				log.Printf("failed to mark email (%s) as sent, error %v\n", e.ID, err)
+			}
+			sent++
+			continue
+		}
+		attempts := e.Attempts + 1
+		dead := attempts >= outboxMaxAttempts
+		log.Printf("failed to send email (%s) attempt %d, dead %t, error %v\n", e.ID, attempts, dead, err)
+		if err := outbox.MarkEmailFailed(e.ID, attempts, err.Error(), now.Add(outboxBackoff(attempts)), dead); err != nil {
+			log.Printf("failed to record failure of email (%s), error %v\n", e.ID, err)
+		}
+	}
+	return sent, nil
+}
+
+// keepDeliveringEmails periodically sends the queued emails. It never
+// returns.
+func keepDeliveringEmails(outbox db.OutboxStore, mailer email.Mailer, interval time.Duration) {
+	for {
+		if _, err := deliverDueEmails(outbox, mailer, time.Now().UTC()); err != nil {
+			log.Printf("failed to deliver queued emails, error %v\n", err)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/email"
+)
+
+type failingMailer struct{}
+
+func (failingMailer) Send(m *email.Message) error {
+	return errors.New("connection refused")
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:   30 * time.Second,
+		2:   time.Minute,
+		3:   2 * time.Minute,
+		10:  outboxMaxBackoff,
+		100: outboxMaxBackoff,
+	} {
+		if got := outboxBackoff(attempts); got != want {
+			t.Errorf("want backoff %s after %d attempts, got %s", want, attempts, got)
+		}
+	}
+}
+
+func TestDeliverDueEmails(t *testing.T) {
+	_, store := newTestServer(t)
+	m := &email.Message{From: "contato@candidatos.info", To: []string{"a@b.com"}, Subject: "Olá", HTML: "<p>Olá</p>"}
+	if queued, err := enqueueEmail(store, "", m); err != nil || !queued {
+		t.Fatalf("want email queued, got %v (error %v)", queued, err)
+	}
+
+	// Failures are retried with backoff until the email is dead-lettered.
+	now := time.Now().UTC()
+	for i := 1; i <= outboxMaxAttempts; i++ {
+		if sent, err := deliverDueEmails(store, failingMailer{}, now); err != nil || sent != 0 {
+			t.Fatalf("want no email sent, got %d (error %v)", sent, err)
+		}
+		emails, _ := store.ListEmails("", 10)
+		if emails[0].Attempts != i {
+			t.Fatalf("want %d attempts, got %d", i, emails[0].Attempts)
+		}
+		if sent, _ := deliverDueEmails(store, failingMailer{}, now); sent != 0 {
+			t.Fatalf("want email not retried before backoff")
+		}
+		now = emails[0].NextAttemptAt
+	}
+	dead, _ := store.ListEmails(db.EmailDead, 10)
+	if len(dead) != 1 || dead[0].LastError != "connection refused" {
+		t.Fatalf("want dead email, got %v", dead)
+	}
+
+	// Requeued emails are sent once the mail server is back.
+	if err := store.RequeueEmail(dead[0].ID, now); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	mailer := email.NewMemoryMailer()
+	if sent, err := deliverDueEmails(store, mailer, now); err != nil || sent != 1 {
+		t.Fatalf("want 1 email sent, got %d (error %v)", sent, err)
+	}
+	if len(mailer.Messages()) != 1 {
+		t.Errorf("want message delivered to mailer")
+	}
+	if sent, _ := store.ListEmails(db.EmailSent, 10); len(sent) != 1 {
+		t.Errorf("want email marked as sent")
+	}
+}
+
+func TestAdminEmails(t *testing.T) {
+	e, store := newTestServer(t)
+	m := &email.Message{From: "contato@candidatos.info", To: []string{"a@b.com"}, Subject: "Assunto com falha", HTML: "<p>Olá</p>"}
+	if _, err := enqueueEmail(store, "", m); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	emails, _ := store.ListEmails("", 10)
+	if err := store.MarkEmailFailed(emails[0].ID, outboxMaxAttempts, "connection refused", time.Now(), true); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	do := func(method, target string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if auth {
+			req.SetBasicAuth(testAdminUser, testAdminPassword)
+			req.Header.Set("Origin", "http://"+req.Host)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do(http.MethodGet, "/admin/emails", false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("want status %d without credentials, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	rec := do(http.MethodGet, "/admin/emails?status=dead", true)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Assunto com falha") || !strings.Contains(rec.Body.String(), "connection refused") {
+		t.Errorf("want dead email listed, got status %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/admin/emails?status=foo", true); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d for invalid status, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/admin/emails/"+emails[0].ID+"/reenviar", true); rec.Code != http.StatusSeeOther {
+		t.Errorf("want redirect after requeue, got %d", rec.Code)
+	}
+	if queued, _ := store.ListEmails(db.EmailQueued, 10); len(queued) != 1 {
+		t.Errorf("want email requeued")
+	}
+	if rec := do(http.MethodPost, "/admin/emails/"+emails[0].ID+"/reenviar", true); rec.Code != http.StatusNotFound {
+		t.Errorf("want status %d requeueing queued email, got %d", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/candidatos-info/site/db"
+	"github.com/labstack/echo"
+)
+
+const (
+	pageQueryParam               = "pagina"
+	nonTransparentPageQueryParam = "pagina_sem_propostas"
+	seedQueryParam               = "semente"
+	searchSeedCookie             = "searchSeed"
+)
+
+// pagination holds what is needed to render the navigation of a paginated
+// section of the search results.
+type pagination struct {
+	Page       int    `json:"page"`
+	TotalPages int    `json:"total_pages"`
+	Total      int    `json:"total"`
+	PrevURL    string `json:"prev_url,omitempty"`
+	NextURL    string `json:"next_url,omitempty"`
+}
+
+// searchSeed returns the seed used to order the search results. The seed
+// comes from the query string (shared links), from the session cookie or is
+// generated and stored in a new session cookie.
+func searchSeed(c echo.Context) int64 {
+	if seed, err := strconv.ParseInt(c.QueryParam(seedQueryParam), 10, 64); err == nil {
+		return seed
+	}
+	if cookie, err := c.Cookie(searchSeedCookie); err == nil {
+		if seed, err := strconv.ParseInt(cookie.Value, 10, 64); err == nil {
+			return seed
+		}
+	}
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		log.Printf("failed to generate search seed, error %v\n", err)
+		b = [8]byte{}
+		binary.BigEndian.PutUint64(b[:], uint64(time.Now().UnixNano()))
+	}
+	seed := int64(binary.BigEndian.Uint64(b[:]) >> 1) // positive to keep URLs short and clean.
+	c.SetCookie(&http.Cookie{
+		Name:  searchSeedCookie,
+		Value: strconv.FormatInt(seed, 10),
+		Path:  "/",
+	})
+	return seed
+}
+
+// pageNumber returns the page number in the query param, defaulting to 1.
+func pageNumber(c echo.Context, param string) int {
+	page, err := strconv.Atoi(c.QueryParam(param))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// newPagination builds the pagination of a section. Links keep the current
+// query and include the seed, so they can be shared.
+func newPagination(u *url.URL, param string, page db.Page, result *db.CandidaturesPage) *pagination {
+	p := &pagination{
+		Page:       page.Number,
+		TotalPages: result.TotalPages(page.Size),
+		Total:      result.Total,
+	}
+	pageURL := func(n int) string {
+		q := u.Query()
+		q.Set(param, strconv.Itoa(n))
+		q.Set(seedQueryParam, strconv.FormatInt(page.Seed, 10))
+		return u.Path + "?" + q.Encode()
+	}
+	if p.Page > 1 {
+		p.PrevURL = pageURL(p.Page - 1)
+	}
+	if p.Page < p.TotalPages {
+		p.NextURL = pageURL(p.Page + 1)
+	}
+	return p
+}
@@ -0,0 +1,75 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	d := New()
+	d.Text(40, 60, Bold, 18, "Cola eleitoral")
+	d.Line(40, 70, 555, 70, 0.5)
+	d.AddPage()
+	d.Text(40, 60, Regular, 12, "Vereador(a): MARIA JOSÉ (PARTIDO A) \\ 12345")
+
+	var buf bytes.Buffer
+	n, err := d.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	out := buf.Bytes()
+	if n != int64(len(out)) {
+		t.Errorf("want %d bytes written, got %d", len(out), n)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Errorf("want PDF header and trailer, got %q", out)
+	}
+	if !strings.Contains(string(out), "/Count 2") {
+		t.Errorf("want 2 pages, got %q", out)
+	}
+	if !bytes.Contains(out, []byte("(Vereador\\(a\\): MARIA JOS\xc9 \\(PARTIDO A\\) \\\\ 12345) Tj")) {
+		t.Errorf("want text escaped and encoded as Windows-1252, got %q", out)
+	}
+
+	// Every entry of the cross-reference table points to its object.
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("want startxref, got %q", out)
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("want xref at offset %d, got %q", xref, out[xref:])
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 8 {
+		t.Errorf("want 8 objects, got %d", len(entries))
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[off:], []byte(want)) {
+			t.Errorf("want object %d at offset %d, got %q", i+1, off, out[off:off+10])
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{"Saúde", "Sa\xfade"},
+		{"a (b)", "a \\(b\\)"},
+		{"linha\nnova", "linha nova"},
+		{"“aspas”", "\x93aspas\x94"},
+		{"日本", "??"},
+	}
+	for _, tc := range testCases {
+		if got := escape(tc.in); got != tc.want {
+			t.Errorf("want escape(%q) = %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
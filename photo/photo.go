@@ -0,0 +1,185 @@
+// Package photo validates profile photos uploaded by candidates and turns
+// them into the renditions shown by the site.
+//
+// Photos are cropped to the aspect ratio of the candidate cards (3:4) and
+// resized using only the standard library. Renditions are always encoded as
+// JPEG, which also drops any metadata of the uploaded file, like the place
+// the photo was taken.
+package photo
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder.
+	"net/http"
+)
+
+const (
+	// MaxFileSize is the maximum size of uploaded files, in bytes.
+	MaxFileSize = 5 << 20
+
+	// MinWidth and MinHeight are the minimum dimensions of uploaded photos,
+	// the dimensions of the largest rendition.
+	MinWidth  = 480
+	MinHeight = 640
+
+	// maxPixels protects the server from images which are small files but
+	// take too much memory once decoded.
+	maxPixels = 40000000
+
+	jpegQuality = 85
+)
+
+// Size is the name and dimensions of a rendition.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Sizes are the renditions created for each photo, smallest first.
+var Sizes = []Size{
+	{Name: "pequena", Width: 120, Height: 160},
+	{Name: "media", Width: 240, Height: 320},
+	{Name: "grande", Width: MinWidth, Height: MinHeight},
+}
+
+// ContentType is the content type of the renditions.
+const ContentType = "image/jpeg"
+
+// Rendition is a photo resized to one of the Sizes, encoded as JPEG.
+type Rendition struct {
+	Size
+	Data []byte
+}
+
+// Error is returned when the uploaded file is not an acceptable photo. Its
+// message can be shown to the candidate.
+type Error struct {
+	msg string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func invalid(format string, a ...interface{}) error {
+	return &Error{msg: fmt.Sprintf(format, a...)}
+}
+
+// Process validates the uploaded file and returns its renditions, in the
+// order of Sizes.
+func Process(data []byte) ([]*Rendition, error) {
+	if len(data) > MaxFileSize {
+		return nil, invalid("A foto deve ter no máximo %d MB.", MaxFileSize>>20)
+	}
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png":
+	default:
+		return nil, invalid("A foto deve estar no formato JPEG ou PNG.")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalid("Não foi possível ler a foto. Por favor, envie outro arquivo.")
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, invalid("A foto tem resolução muito alta. Por favor, envie uma foto menor.")
+	}
+	orientation := exifOrientation(data)
+	width, height := cfg.Width, cfg.Height
+	if orientation >= 5 { // rotated by 90 degrees.
+		width, height = height, width
+	}
+	if width < MinWidth || height < MinHeight {
+		return nil, invalid("A foto deve ter pelo menos %dx%d pixels.", MinWidth, MinHeight)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalid("Não foi possível ler a foto. Por favor, envie outro arquivo.")
+	}
+	src := orient(flatten(img), orientation)
+	src = crop(src, MinWidth, MinHeight)
+	var renditions []*Rendition
+	for _, s := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(src, s.Width, s.Height), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("falha ao codificar foto %s, erro %q", s.Name, err)
+		}
+		renditions = append(renditions, &Rendition{Size: s, Data: buf.Bytes()})
+	}
+	return renditions, nil
+}
+
+// flatten draws the image over a white background, so transparent areas of
+// PNG files do not turn black in the JPEG renditions.
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// crop returns the largest centered area of the image with the aspect ratio
+// of width by height.
+func crop(img *image.RGBA, width, height int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	r := b
+	if w*height > h*width { // wider than the aspect ratio.
+		cw := h * width / height
+		r.Min.X += (w - cw) / 2
+		r.Max.X = r.Min.X + cw
+	} else {
+		ch := w * height / width
+		r.Min.Y += (h - ch) / 2
+		r.Max.Y = r.Min.Y + ch
+	}
+	return img.SubImage(r).(*image.RGBA)
+}
+
+// resize scales the image down to width by height pixels, averaging the
+// pixels of the source covered by each pixel of the result.
+func resize(img *image.RGBA, width, height int) *image.RGBA {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, height, sh)
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, width, sw)
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := img.PixOffset(b.Min.X+x0, b.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(img.Pix[i])
+					g += int(img.Pix[i+1])
+					bl += int(img.Pix[i+2])
+					a += int(img.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(bl / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span returns the range of source pixels covered by the pixel i of a
+// result with n pixels, out of a source with size pixels.
+func span(i, n, size int) (int, int) {
+	start, end := i*size/n, (i+1)*size/n
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
@@ -0,0 +1,169 @@
+package photo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// newTestImage returns an image with the left half of the first color and the
+// right half of the second.
+func newTestImage(width, height int, left, right color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, left)
+			} else {
+				img.Set(x, y, right)
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation adds an EXIF segment with the orientation tag to the JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	segment := append([]byte("Exif\x00\x00"), append(tiff, entry...)...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func decode(t *testing.T, r *Rendition) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(r.Data))
+	if err != nil || format != "jpeg" {
+		t.Fatalf("want JPEG rendition, got %s (error %v)", format, err)
+	}
+	if b := img.Bounds(); b.Dx() != r.Width || b.Dy() != r.Height {
+		t.Errorf("want rendition %s of %dx%d, got %dx%d", r.Name, r.Width, r.Height, b.Dx(), b.Dy())
+	}
+	return img
+}
+
+// near reports whether the color is close to the wanted one, as JPEG
+// compression changes colors slightly.
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(a uint32, b uint8) bool {
+		d := int(a>>8) - int(b)
+		return d > -40 && d < 40
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+func TestProcess(t *testing.T) {
+	// A square photo is cropped at the sides, keeping both halves.
+	renditions, err := Process(encodePNG(t, newTestImage(800, 800, red, blue)))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if len(renditions) != len(Sizes) {
+		t.Fatalf("want %d renditions, got %d", len(Sizes), len(renditions))
+	}
+	for i, r := range renditions {
+		if r.Size != Sizes[i] {
+			t.Errorf("want rendition %v, got %v", Sizes[i], r.Size)
+		}
+		img := decode(t, r)
+		if left, right := img.At(2, r.Height/2), img.At(r.Width-3, r.Height/2); !near(left, red) || !near(right, blue) {
+			t.Errorf("want rendition %s cropped at the center, got %v and %v at the sides", r.Name, left, right)
+		}
+	}
+
+	renditions, err = Process(encodePNG(t, newTestImage(MinWidth, MinHeight, color.Transparent, green)))
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if c := decode(t, renditions[0]).At(2, 2); !near(c, white) {
+		t.Errorf("want transparent areas white, got %v", c)
+	}
+}
+
+func TestProcessOrientation(t *testing.T) {
+	// Stored rotated: the top half is red and the bottom half green. Rotated
+	// clockwise, the green half is shown on the left.
+	stored := image.NewNRGBA(image.Rect(0, 0, MinHeight, MinWidth))
+	for y := 0; y < MinWidth; y++ {
+		for x := 0; x < MinHeight; x++ {
+			if y < MinWidth/2 {
+				stored.Set(x, y, red)
+			} else {
+				stored.Set(x, y, green)
+			}
+		}
+	}
+	data := withOrientation(encodeJPEG(t, stored), 6)
+	if o := exifOrientation(data); o != 6 {
+		t.Fatalf("want orientation 6, got %d", o)
+	}
+	renditions, err := Process(data)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	r := renditions[len(renditions)-1]
+	img := decode(t, r)
+	if left, right := img.At(2, r.Height/2), img.At(r.Width-3, r.Height/2); !near(left, green) || !near(right, red) {
+		t.Errorf("want photo rotated, got %v and %v at the sides", left, right)
+	}
+}
+
+func TestProcessInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"not an image", []byte("<html><body>foto</body></html>")},
+		{"truncated", encodePNG(t, newTestImage(MinWidth, MinHeight, red, blue))[:200]},
+		{"too small", encodeJPEG(t, newTestImage(MinWidth-1, MinHeight, red, blue))},
+		{"rotated too small", withOrientation(encodeJPEG(t, newTestImage(MinWidth, MinHeight, red, blue)), 8)},
+		{"too large", append(encodeJPEG(t, newTestImage(MinWidth, MinHeight, red, blue)), make([]byte, MaxFileSize)...)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Process(tc.data)
+			if _, ok := err.(*Error); !ok {
+				t.Errorf("want *Error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/blob"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+	"github.com/candidatos-info/site/photo"
+)
+
+// Candidates may replace the photo published by the TSE with their own. The
+// uploaded photo is resized into the renditions of photo.Sizes, kept in the
+// blob store, and only replaces the photo of the candidature after approved
+// by an editor, whether pre-moderation is enabled or not. The candidature
+// points to the largest rendition, the others are found by name.
+
+const (
+	photoCardSize    = "media"  // rendition shown in candidate cards.
+	photoProfileSize = "grande" // rendition the candidature points to.
+)
+
+// photoKey returns the key of a rendition of the photo in the blob store.
+func photoKey(year int, sequentialID, id, size string) string {
+	return fmt.Sprintf("fotos/%d/%s/%s-%s.jpg", year, sequentialID, id, size)
+}
+
+// photoRenditionURL returns the URL of the rendition of an uploaded photo
+// given the URL of its largest rendition. Photos published by the TSE have a
+// single size, their URL is returned as is.
+func photoRenditionURL(photoURL, size string) string {
+	suffix := "-" + photoProfileSize + ".jpg"
+	if !strings.Contains(photoURL, "/fotos/") || !strings.HasSuffix(photoURL, suffix) {
+		return photoURL
+	}
+	return strings.TrimSuffix(photoURL, suffix) + "-" + size + ".jpg"
+}
+
+// submitPhoto processes the uploaded file and sends the photo to the
+// moderation queue, superseding pending photos of the candidature. Invalid
+// files fail with a *photo.Error.
+func submitPhoto(store db.PhotoStore, files blob.Store, candidate *descritor.CandidateForDB, sessionID string, data []byte) error {
+	renditions, err := photo.Process(data)
+	if err != nil {
+		return err
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	s := &db.PhotoSubmission{
+		ID:           id,
+		Year:         candidate.Year,
+		SequentialID: candidate.SequencialCandidate,
+		Email:        strings.ToLower(candidate.Email),
+		SessionID:    sessionID,
+		CreatedAt:    time.Now().UTC(),
+		Status:       db.SubmissionPending,
+	}
+	for _, r := range renditions {
+		key := photoKey(candidate.Year, candidate.SequencialCandidate, id, r.Name)
+		if err := files.Put(key, photo.ContentType, r.Data); err != nil {
+			return err
+		}
+		s.Renditions = append(s.Renditions, &db.PhotoRendition{Name: r.Name, Width: r.Width, Height: r.Height, URL: files.URL(key)})
+	}
+	return store.CreatePhotoSubmission(s)
+}
+
+// approvePhoto replaces the photo of the candidature. As with profile
+// submissions, the change is recorded as made by the candidate and the
+// approval as made by the operator, and the photo is returned to the queue if
+// publishing it fails.
+func approvePhoto(store db.Store, s *db.PhotoSubmission, operator *db.Operator) error {
+	candidate, err := store.FindCandidateBySequencialIDAndYear(s.Year, s.SequentialID)
+	if err != nil {
+		return err
+	}
+	r := s.Rendition(photoProfileSize)
+	if r == nil {
+		return exception.New(exception.ProcessmentError, fmt.Sprintf("Foto [%s] não tem o tamanho %s", s.ID, photoProfileSize), nil)
+	}
+	if err := store.ReviewPhotoSubmission(s.ID, db.SubmissionApproved, operator.ID, "", time.Now().UTC()); err != nil {
+		return err
+	}
+	changes := []*db.FieldChange{{Field: "foto", Before: candidate.PhotoURL, After: r.URL}}
+	candidate.PhotoURL = r.URL
+	if err := saveCandidateProfile(store, candidate, candidateActor(candidate), s.SessionID, db.AuditUpdateProfile); err != nil {
+		if err := store.ReopenPhotoSubmission(s.ID); err != nil {
+			log.Printf("failed to reopen photo (%s), error %v\n", s.ID, err)
+		}
+		return err
+	}
+	if err := audit(store, operatorActor(operator), "", db.AuditApproveProfile, s.Year, s.SequentialID, changes); err != nil {
+		log.Printf("failed to audit photo approved (%s), error %v\n", s.ID, err)
+	}
+	return nil
+}
+
+// rejectPhoto discards the photo and emails the reason to the candidate. The
+// files are kept, as they are referenced by the submission.
+func rejectPhoto(store db.Store, s *db.PhotoSubmission, operator *db.Operator, reason string) error {
+	candidate, err := store.FindCandidateBySequencialIDAndYear(s.Year, s.SequentialID)
+	if err != nil {
+		return err
+	}
+	if err := store.ReviewPhotoSubmission(s.ID, db.SubmissionRejected, operator.ID, reason, time.Now().UTC()); err != nil {
+		return err
+	}
+	message, err := buildPhotoRejectedEmail(candidate, reason)
+	if err != nil {
+		return err
+	}
+	message.From = emailFrom
+	message.To = []string{candidate.Email}
+	if _, err := enqueueEmail(store, "foto:"+s.ID, message); err != nil {
+		return err
+	}
+	changes := []*db.FieldChange{{Field: "motivo da rejeição da foto", After: reason}}
+	if err := audit(store, operatorActor(operator), "", db.AuditRejectProfile, s.Year, s.SequentialID, changes); err != nil {
+		log.Printf("failed to audit photo rejected (%s), error %v\n", s.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candidatos-info/site/blob"
+	"github.com/candidatos-info/site/db"
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// postPhoto uploads the file as the photo of the candidature.
+func postPhoto(t *testing.T, e *echo.Echo, accessToken string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("access_token", accessToken); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	part, err := w.CreateFormFile(photoFieldName, "foto.png")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	part.Write(data)
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/atualizar-candidatura/foto", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestPhoto(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPhotoUpload(t *testing.T) {
+	e, store := newTestServer(t)
+	files := blob.NewMemoryStore("http://localhost/arquivos")
+	e.POST("/atualizar-candidatura/foto", newAtualizarFotoHandler(store, files), middleware.BodyLimit(photoUploadLimit), newCandidateAuthMiddleware(store, tokenService))
+	accessToken := loginTestCandidate(t, e, store, "maria.jose@exemplo.com", "20000000001")
+	postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}})
+	candidate, err := store.FindCandidateBySequencialIDAndYear(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	tsePhoto := candidate.PhotoURL
+
+	if rec := postPhoto(t, e, accessToken, []byte("não é uma foto")); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "JPEG ou PNG") {
+		t.Errorf("want invalid file rejected, got status %d", rec.Code)
+	}
+	if rec := postPhoto(t, e, accessToken, newTestPhoto(t, 100, 100)); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "pelo menos") {
+		t.Errorf("want small photo rejected, got status %d", rec.Code)
+	}
+	if rec := postPhoto(t, e, accessToken, make([]byte, 7<<20)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("want status %d for large upload, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+	if rec := postPhoto(t, e, accessToken, newTestPhoto(t, 800, 800)); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "enviada para moderação") {
+		t.Fatalf("want photo sent to moderation, got status %d", rec.Code)
+	}
+	pending, err := store.FindPendingPhotoSubmission(2020, "20000000001")
+	if err != nil || len(pending.Renditions) != 3 {
+		t.Fatalf("want pending photo with 3 renditions, got %+v (error %v)", pending, err)
+	}
+	for _, r := range pending.Renditions {
+		f := files.Get(strings.TrimPrefix(r.URL, "http://localhost/arquivos/"))
+		if f == nil || f.ContentType != "image/jpeg" {
+			t.Fatalf("want rendition %s stored, got %+v", r.Name, f)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(f.Data))
+		if err != nil || cfg.Width != r.Width || cfg.Height != r.Height {
+			t.Errorf("want rendition %s of %dx%d, got %+v (error %v)", r.Name, r.Width, r.Height, cfg, err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/atualizar-candidatura?access_token="+url.QueryEscape(accessToken), nil))
+	if !strings.Contains(rec.Body.String(), "aguarda moderação") {
+		t.Errorf("want pending photo in the profile form")
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.PhotoURL != tsePhoto {
+		t.Errorf("want TSE photo until approved, got %s", candidate.PhotoURL)
+	}
+
+	createTestOperator(t, store, "leitor@exemplo.com", db.RoleViewer)
+	approve := "/admin/moderacao/fotos/" + pending.ID + "/aprovar"
+	if rec := adminRequest(t, e, http.MethodGet, "/admin/moderacao", "leitor@exemplo.com", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), pending.Rendition(photoCardSize).URL) {
+		t.Errorf("want photo in the moderation queue, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, approve, "leitor@exemplo.com", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("want status %d for viewer approving, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, approve, testAdminUser, nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after approving, got status %d", rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, approve, testAdminUser, nil); rec.Code != http.StatusConflict {
+		t.Errorf("want status %d approving twice, got %d", http.StatusConflict, rec.Code)
+	}
+	photoURL := pending.Rendition(photoProfileSize).URL
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.PhotoURL != photoURL {
+		t.Errorf("want uploaded photo published, got %s", candidate.PhotoURL)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/c/2020/20000000001", nil))
+	if !strings.Contains(rec.Body.String(), photoURL) {
+		t.Errorf("want uploaded photo in the candidate page")
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/candidatos?ano=2020&estado=AL&cidade=MACEI%C3%93", nil))
+	if body := rec.Body.String(); !strings.Contains(body, pending.Rendition(photoCardSize).URL) {
+		t.Errorf("want card rendition in the candidate cards, got %s", body)
+	}
+	revisions, err := store.ListProfileRevisions(2020, "20000000001")
+	if err != nil || len(revisions) == 0 || revisions[0].PhotoURL != photoURL || revisions[0].SessionID == "" {
+		t.Errorf("want revision with the photo made through the candidate session, got %v (error %v)", revisions, err)
+	}
+
+	postPhoto(t, e, accessToken, newTestPhoto(t, 480, 640))
+	pending, err = store.FindPendingPhotoSubmission(2020, "20000000001")
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	reject := "/admin/moderacao/fotos/" + pending.ID + "/rejeitar"
+	if rec := adminRequest(t, e, http.MethodPost, reject, testAdminUser, url.Values{"motivo": {" "}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("want status %d without reason, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := adminRequest(t, e, http.MethodPost, reject, testAdminUser, url.Values{"motivo": {"A foto não mostra o rosto."}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("want redirect after rejecting, got status %d", rec.Code)
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.PhotoURL != photoURL {
+		t.Errorf("want rejected photo not published, got %s", candidate.PhotoURL)
+	}
+	emails, _ := store.ListEmails("", 10)
+	found := false
+	for _, m := range emails {
+		if strings.Contains(m.Text, "A foto não mostra o rosto.") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("want reason emailed to the candidate")
+	}
+}
+
+func TestApprovePhotoFailure(t *testing.T) {
+	_, store := newTestServer(t)
+	s := &db.PhotoSubmission{ID: "f1", Year: 2020, SequentialID: "20000000001", CreatedAt: time.Now(), Status: db.SubmissionPending, Renditions: []*db.PhotoRendition{{Name: photoProfileSize, URL: "/arquivos/f1.jpg"}}}
+	if err := store.CreatePhotoSubmission(s); err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if err := approvePhoto(&failingProfileStore{store}, s, &db.Operator{ID: testAdminUser}); err == nil {
+		t.Fatalf("want error when the profile can not be saved")
+	}
+	if pending, err := store.FindPendingPhotoSubmission(2020, "20000000001"); err != nil || pending.ID != "f1" {
+		t.Errorf("want photo back in the queue, got %+v (error %v)", pending, err)
+	}
+	if err := approvePhoto(store, s, &db.Operator{ID: testAdminUser}); err != nil {
+		t.Fatalf("want approving again to succeed, got %q", err)
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.PhotoURL != "/arquivos/f1.jpg" {
+		t.Errorf("want photo published, got %q", candidate.PhotoURL)
+	}
+}
+
+func TestPhotoRenditionURL(t *testing.T) {
+	testCases := []struct {
+		url  string
+		want string
+	}{
+		{"http://localhost/arquivos/fotos/2020/1/abc-grande.jpg", "http://localhost/arquivos/fotos/2020/1/abc-media.jpg"},
+		{"http://divulgacandcontas.tse.jus.br/foto/1.jpg", "http://divulgacandcontas.tse.jus.br/foto/1.jpg"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := photoRenditionURL(tc.url, photoCardSize); got != tc.want {
+			t.Errorf("want %s, got %s", tc.want, got)
+		}
+	}
+}
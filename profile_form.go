@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/candidatos-info/descritor"
+)
+
+// Sizes of the profile fields are counted in characters, not bytes, so
+// accented text is not penalized. All fields are validated at once and the
+// form is shown again with the submitted values and the messages next to the
+// invalid fields.
+
+// formErrors holds the validation messages of a form, one for each field and
+// in the order they were found.
+type formErrors struct {
+	fields   map[string]string
+	messages []string
+}
+
+func newFormErrors() *formErrors {
+	return &formErrors{fields: make(map[string]string)}
+}
+
+// add records the message of the field, keeping the first one of each field.
+func (e *formErrors) add(field, message string) {
+	if _, ok := e.fields[field]; ok {
+		return
+	}
+	e.fields[field] = message
+	e.messages = append(e.messages, message)
+}
+
+func (e *formErrors) empty() bool {
+	return len(e.messages) == 0
+}
+
+// Field returns the message of the field, empty if it is valid.
+func (e *formErrors) Field(name string) string {
+	return e.fields[name]
+}
+
+// Messages returns all messages.
+func (e *formErrors) Messages() []string {
+	return e.messages
+}
+
+func proposalField(i int) string {
+	return fmt.Sprintf("descriptions[%d]", i)
+}
+
+func contactField(i int) string {
+	return fmt.Sprintf("contact[%d]", i)
+}
+
+// validateProfileForm returns the values of the profile form and the
+// validation messages. The values are returned even if invalid, so the form
+// can be shown again as submitted.
+func validateProfileForm(form url.Values) (*atualizarCandidaturaParams, *formErrors) {
+	errs := newFormErrors()
+	params := &atualizarCandidaturaParams{Bio: strings.TrimSpace(form.Get(bioFieldName))}
+	switch n := utf8.RuneCountInString(params.Bio); {
+	case n == 0:
+		errs.add(bioFieldName, "Biografia é um campo obrigatório.")
+	case n > maxBiographyTextSize:
+		errs.add(bioFieldName, fmt.Sprintf("A biografia tem %d caracteres. O tamanho máximo é de %d.", n, maxBiographyTextSize))
+	}
+
+	numTags, err := strconv.Atoi(form.Get(numTagsFieldName))
+	switch {
+	case err != nil || numTags <= 0:
+		numTags = 0
+		errs.add("proposals", "É necessário o preenchimento de, ao menos, uma pauta.")
+	case numTags > maxProposals:
+		numTags = maxProposals
+		errs.add("proposals", fmt.Sprintf("O número máximo de pautas é %d.", maxProposals))
+	}
+	params.NumTags = numTags
+	for i := 0; i < numTags; i++ {
+		p := &descritor.Proposal{
+			Topic:       strings.TrimSpace(form.Get(fmt.Sprintf("descriptions[%d][tag]", i))),
+			Description: strings.TrimSpace(form.Get(fmt.Sprintf("descriptions[%d][description]", i))),
+		}
+		params.Proposals = append(params.Proposals, p)
+		n := utf8.RuneCountInString(p.Description)
+		switch {
+		case p.Topic == "":
+			errs.add(proposalField(i), fmt.Sprintf("Escolha a pauta da proposta %d.", i+1))
+		case n == 0:
+			errs.add(proposalField(i), fmt.Sprintf("O campo proposta da pauta %s é obrigatório.", p.Topic))
+		case n > maxProposalsTextSize:
+			errs.add(proposalField(i), fmt.Sprintf("A proposta da pauta %s tem %d caracteres. O tamanho máximo é de %d.", p.Topic, n, maxProposalsTextSize))
+		}
+	}
+
+	params.Contacts = parseContacts(form[providerFieldName], form[contactFieldName], errs)
+	if strings.TrimSpace(strings.Join(form[contactFieldName], "")) == "" {
+		errs.add("contacts", "Informe ao menos um contato.")
+	}
+	return params, errs
+}
+
+// submittedContacts returns the contacts as sent in the form, including the
+// empty and invalid ones.
+func submittedContacts(networks, values []string) []*descritor.Contact {
+	var rows []*descritor.Contact
+	for i := 0; i < len(networks) && i < len(values); i++ {
+		rows = append(rows, &descritor.Contact{SocialNetwork: networks[i], Value: values[i]})
+	}
+	return rows
+}
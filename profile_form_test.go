@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidateProfileForm(t *testing.T) {
+	valid := func() url.Values {
+		return url.Values{
+			"numTags":                      {"1"},
+			"descriptions[0][tag]":         {"Educação"},
+			"descriptions[0][description]": {"Creches em todos os bairros."},
+			"biography":                    {"Professora da rede pública há 15 anos."},
+			"provider":                     {"instagram"},
+			"contact":                      {"@professoramariajose"},
+		}
+	}
+	if _, errs := validateProfileForm(valid()); !errs.empty() {
+		t.Fatalf("want valid form, got %v", errs.Messages())
+	}
+
+	// Limits are counted in characters: "ç" and "ã" take two bytes each.
+	form := valid()
+	form.Set("biography", strings.Repeat("ção", maxBiographyTextSize/3))
+	form.Set("descriptions[0][description]", strings.Repeat("ã", maxProposalsTextSize))
+	if _, errs := validateProfileForm(form); !errs.empty() {
+		t.Errorf("want accented text within the limits accepted, got %v", errs.Messages())
+	}
+	form.Set("biography", strings.Repeat("é", maxBiographyTextSize+1))
+	if _, errs := validateProfileForm(form); errs.Field(bioFieldName) == "" {
+		t.Errorf("want biography over the limit rejected")
+	}
+
+	form = valid()
+	form.Set("numTags", "2")
+	form.Set("biography", " ")
+	form.Set("descriptions[1][tag]", "Saúde")
+	form["contact"] = []string{"maria jose", ""}
+	form["provider"] = []string{"instagram", "email"}
+	params, errs := validateProfileForm(form)
+	for _, field := range []string{bioFieldName, proposalField(1), contactField(0)} {
+		if errs.Field(field) == "" {
+			t.Errorf("want error in %s, got %v", field, errs.Messages())
+		}
+	}
+	if errs.Field(proposalField(0)) != "" || errs.Field(contactField(1)) != "" {
+		t.Errorf("want errors only in the invalid fields, got %v", errs.Messages())
+	}
+	if len(params.Proposals) != 2 || params.Proposals[0].Description != "Creches em todos os bairros." {
+		t.Errorf("want submitted proposals returned, got %+v", params.Proposals)
+	}
+	if _, errs := validateProfileForm(url.Values{"numTags": {"1000000"}}); !strings.Contains(errs.Field("proposals"), "máximo") {
+		t.Errorf("want too many proposals rejected, got %v", errs.Messages())
+	}
+}
+
+func TestProfileFormPreservesInput(t *testing.T) {
+	e, store := newTestServer(t)
+	e.POST("/atualizar-candidatura", newAtualizarCandidaturaFormHandler(store, nil, false, false), newCandidateAuthMiddleware(store, tokenService))
+	accessToken := loginTestCandidate(t, e, store, "maria.jose@exemplo.com", "20000000001")
+	postForm(t, e, "/aceitar-termo", url.Values{"access_token": {accessToken}})
+	rec := postForm(t, e, "/atualizar-candidatura", url.Values{
+		"access_token":                 {accessToken},
+		"numTags":                      {"1"},
+		"descriptions[0][tag]":         {"Educação"},
+		"descriptions[0][description]": {strings.Repeat("a", maxProposalsTextSize+1)},
+		"biography":                    {"Nova biografia da professora."},
+		"provider":                     {"whatsapp"},
+		"contact":                      {"9999-8888"},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("want status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Nova biografia da professora.", `value="9999-8888"`, "A proposta da pauta Educação tem 281 caracteres", "Contato 1 (Whatsapp)", `name="token" value="` + accessToken + `"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("want %q in the form", want)
+		}
+	}
+	if candidate, _ := store.FindCandidateBySequencialIDAndYear(2020, "20000000001"); candidate.Biography == "Nova biografia da professora." {
+		t.Errorf("want invalid profile not saved")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/candidatos-info/descritor"
+	"github.com/candidatos-info/site/db"
+	"github.com/candidatos-info/site/exception"
+)
+
+// Actor of the first revision of profiles, as they were before the first
+// change made through the site.
+const importActor = "importacao"
+
+// saveCandidateProfile updates the profile of the candidate, stores the new
+// revision of the profile and records in the audit trail who changed which
+// fields. The sessionID is the session of the candidate making the change,
+// empty when it is made by an operator. Every change to a profile must go
+// through it instead of calling UpdateCandidateProfile directly.
+func saveCandidateProfile(store db.Store, candidate *descritor.CandidateForDB, actor, sessionID, action string) error {
+	return saveProfileRevision(store, candidate, actor, sessionID, action, 0)
+}
+
+func saveProfileRevision(store db.Store, candidate *descritor.CandidateForDB, actor, sessionID, action string, restoredFrom int) error {
+	before, err := store.FindCandidateBySequencialIDAndYear(candidate.Year, candidate.SequencialCandidate)
+	if err != nil {
+		return err
+	}
+	if _, err := store.UpdateCandidateProfile(candidate); err != nil {
+		return err
+	}
+	// The profile is already updated, failures to keep its history must not
+	// be reported as failures to update it.
+	changes := profileChanges(before, candidate)
+	if len(changes) > 0 {
+		if err := addProfileRevisions(store, before, candidate, actor, sessionID, changes, restoredFrom); err != nil {
+			log.Printf("failed to store profile revision (%s/%d), error %v\n", candidate.SequencialCandidate, candidate.Year, err)
+		}
+	}
+	if err := audit(store, actor, sessionID, action, candidate.Year, candidate.SequencialCandidate, changes); err != nil {
+		log.Printf("failed to audit profile update (%s/%d), error %v\n", candidate.SequencialCandidate, candidate.Year, err)
+	}
+	return nil
+}
+
+// addProfileRevisions stores the revision of the profile after a change. The
+// profile before the first change is stored as the first revision, so it can
+// be restored too.
+func addProfileRevisions(store db.RevisionStore, before, after *descritor.CandidateForDB, actor, sessionID string, changes []*db.FieldChange, restoredFrom int) error {
+	revisions, err := store.ListProfileRevisions(after.Year, after.SequencialCandidate)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if len(revisions) == 0 {
+		if err := store.AddProfileRevision(newProfileRevision(before, importActor, "", nil, now)); err != nil {
+			return err
+		}
+	}
+	r := newProfileRevision(after, actor, sessionID, changes, now)
+	r.RestoredFrom = restoredFrom
+	return store.AddProfileRevision(r)
+}
+
+func newProfileRevision(candidate *descritor.CandidateForDB, actor, sessionID string, changes []*db.FieldChange, createdAt time.Time) *db.ProfileRevision {
+	return &db.ProfileRevision{
+		Year:          candidate.Year,
+		SequentialID:  candidate.SequencialCandidate,
+		CreatedAt:     createdAt,
+		Actor:         actor,
+		SessionID:     sessionID,
+		Biography:     candidate.Biography,
+		Proposals:     candidate.Proposals,
+		Contacts:      candidate.Contacts,
+		AcceptedTerms: candidate.AcceptedTerms,
+		Transparency:  candidate.Transparency,
+		PhotoURL:      candidate.PhotoURL,
+		Changes:       changes,
+	}
+}
+
+// restoreProfileRevision rolls the profile back to the given revision, which
+// is stored as a new revision.
+func restoreProfileRevision(store db.Store, candidate *descritor.CandidateForDB, r *db.ProfileRevision, actor string) error {
+	candidate.Biography = r.Biography
+	candidate.Proposals = r.Proposals
+	candidate.Contacts = r.Contacts
+	candidate.AcceptedTerms = r.AcceptedTerms
+	candidate.Transparency = r.Transparency
+	if r.PhotoURL != "" { // revisions stored before photos were uploaded have none.
+		candidate.PhotoURL = r.PhotoURL
+	}
+	return saveProfileRevision(store, candidate, actor, "", db.AuditRollback, r.Number)
+}
+
+// audit records an entry in the audit trail.
+func audit(store db.AuditStore, actor, sessionID, action string, year int, sequentialID string, changes []*db.FieldChange) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	entry := &db.AuditEntry{
+		ID:           id,
+		CreatedAt:    time.Now().UTC(),
+		Actor:        actor,
+		SessionID:    sessionID,
+		Action:       action,
+		Year:         year,
+		SequentialID: sequentialID,
+		Changes:      changes,
+	}
+	return store.AddAuditEntry(entry)
+}
+
+// profileChanges returns the fields of the profile that differ.
+func profileChanges(before, after *descritor.CandidateForDB) []*db.FieldChange {
+	var changes []*db.FieldChange
+	add := func(field, b, a string) {
+		if b != a {
+			changes = append(changes, &db.FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	add("biografia", before.Biography, after.Biography)
+	add("propostas", formatProposals(before.Proposals), formatProposals(after.Proposals))
+	add("contatos", formatContacts(before.Contacts), formatContacts(after.Contacts))
+	add("termo aceito em", formatTime(before.AcceptedTerms), formatTime(after.AcceptedTerms))
+	add("foto", before.PhotoURL, after.PhotoURL)
+	return changes
+}
+
+func formatProposals(proposals []*descritor.Proposal) string {
+	lines := make([]string, len(proposals))
+	for i, p := range proposals {
+		lines[i] = fmt.Sprintf("%s: %s", p.Topic, p.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatContacts(contacts []*descritor.Contact) string {
+	lines := make([]string, len(contacts))
+	for i, c := range contacts {
+		lines[i] = fmt.Sprintf("%s: %s", c.SocialNetwork, c.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// candidateActor identifies a candidate in the audit trail.
+func candidateActor(candidate *descritor.CandidateForDB) string {
+	return "candidato:" + strings.ToLower(candidate.Email)
+}
+
+// operatorActor identifies an operator in the audit trail.
+func operatorActor(operator *db.Operator) string {
+	return "operador:" + operator.ID
+}
+
+// isProfileDisabled reports whether the profile of the candidature was
+// disabled by an operator.
+func isProfileDisabled(store db.DisabledProfileStore, year int, sequentialID string) (bool, error) {
+	_, err := store.FindDisabledProfile(year, sequentialID)
+	switch {
+	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// findPublicCandidate returns the candidature to be shown to voters. The
+// biography, proposals and contacts of disabled profiles are hidden.
+func findPublicCandidate(store db.Store, year int, sequentialID string) (*descritor.CandidateForDB, error) {
+	candidate, err := store.FindCandidateBySequencialIDAndYear(year, sequentialID)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := isProfileDisabled(store, year, sequentialID)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		hideProfile(candidate)
+	}
+	return candidate, nil
+}
+
+// hideProfile clears what the candidate wrote in the profile.
+func hideProfile(candidate *descritor.CandidateForDB) {
+	candidate.Biography = ""
+	candidate.Proposals = nil
+	candidate.Contacts = nil
+	candidate.Transparency = 0
+}
@@ -0,0 +1,99 @@
+// Package ratelimit limits how many times something happens in a time
+// window, using counters kept by a pluggable store.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store keeps counters identified by keys. It is implemented by the database
+// clients and by MemoryStore.
+type Store interface {
+	// IncrementRateCounter increments the counter identified by key and
+	// returns its new value. A counter that does not exist is created with
+	// the given expiration time.
+	IncrementRateCounter(key string, expiresAt time.Time) (int, error)
+}
+
+// Limiter allows up to limit events per subject in each fixed time window.
+type Limiter struct {
+	store  Store
+	name   string
+	limit  int
+	window time.Duration
+}
+
+// New returns a new limiter. The name separates the counters of limiters
+// sharing the same store.
+func New(store Store, name string, limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		store:  store,
+		name:   name,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow counts one event of the subject and reports whether it is within
+// the limit.
+func (l *Limiter) Allow(subject string, now time.Time) (bool, error) {
+	start := now.Truncate(l.window)
+	key := l.name + ":" + subject + ":" + strconv.FormatInt(start.Unix(), 10)
+	n, err := l.store.IncrementRateCounter(key, start.Add(l.window))
+	if err != nil {
+		return false, fmt.Errorf("failed to increment rate counter %s, error %v", key, err)
+	}
+	return n <= l.limit, nil
+}
+
+// Expired counters of a MemoryStore are removed at most once in this
+// interval, so increments do not go through all the counters.
+const sweepInterval = time.Minute
+
+type counter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-memory Store. Counters are not shared between
+// instances of the site.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+// IncrementRateCounter increments the counter identified by key and returns
+// its new value. An expired counter starts again from zero.
+func (s *MemoryStore) IncrementRateCounter(key string, expiresAt time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if !now.Before(s.nextSweep) {
+		for k, c := range s.counters {
+			if !now.Before(c.expiresAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.nextSweep = now.Add(sweepInterval)
+	}
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expiresAt) {
+		c = &counter{expiresAt: expiresAt}
+		s.counters[key] = c
+	}
+	c.count++
+	return c.count, nil
+}
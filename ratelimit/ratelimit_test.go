@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	l := New(store, "ip", 2, time.Hour)
+	testCases := []struct {
+		subject string
+		want    bool
+	}{
+		{"1.1.1.1", true},
+		{"1.1.1.1", true},
+		{"1.1.1.1", false},
+		{"2.2.2.2", true},
+	}
+	for i, tt := range testCases {
+		if got, err := l.Allow(tt.subject, now); err != nil || got != tt.want {
+			t.Errorf("event %d: want %v, got %v (error %v)", i, tt.want, got, err)
+		}
+	}
+	if got, _ := New(store, "email", 2, time.Hour).Allow("1.1.1.1", now); !got {
+		t.Errorf("want limiters with different names to be independent")
+	}
+	now = now.Add(time.Hour)
+	if got, _ := l.Allow("1.1.1.1", now); !got {
+		t.Errorf("want limit to be reset in the next window")
+	}
+	if len(store.counters) != 1 {
+		t.Errorf("want expired counters removed, got %d counters", len(store.counters))
+	}
+}
+
+func TestMemoryStoreExpiredCounters(t *testing.T) {
+	now := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	store.IncrementRateCounter("a", now.Add(time.Second))
+	store.IncrementRateCounter("b", now.Add(time.Hour))
+	now = now.Add(2 * time.Second)
+	if n, _ := store.IncrementRateCounter("a", now.Add(time.Second)); n != 1 {
+		t.Errorf("want expired counter to restart, got %d", n)
+	}
+	store.IncrementRateCounter("b", now.Add(time.Hour))
+	if len(store.counters) != 2 {
+		t.Errorf("want no sweep before %s, got %d counters", sweepInterval, len(store.counters))
+	}
+	now = now.Add(sweepInterval)
+	store.IncrementRateCounter("c", now.Add(time.Hour))
+	if len(store.counters) != 2 {
+		t.Errorf("want expired counters removed, got %d counters", len(store.counters))
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) IncrementRateCounter(key string, expiresAt time.Time) (int, error) {
+	return 0, errors.New("unavailable")
+}
+
+func TestLimiterStoreError(t *testing.T) {
+	if ok, err := New(failingStore{}, "ip", 1, time.Hour).Allow("1.1.1.1", time.Now()); ok || err == nil {
+		t.Errorf("want error, got %v (error %v)", ok, err)
+	}
+}
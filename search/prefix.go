@@ -0,0 +1,200 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of suggestions returned by the prefix index.
+const (
+	KindBallotName = "candidatura" // Ballot name of a candidature.
+	KindName       = "nome"        // Civil name of a candidate.
+	KindCity       = "cidade"      // City where an election happens.
+	KindParty      = "partido"     // Party with candidatures in a city.
+)
+
+const (
+	// MinPrefixSize is the minimum size (in runes) of a prefix query.
+	MinPrefixSize = 2
+)
+
+var kindWeight = map[string]int{
+	KindBallotName: 4,
+	KindName:       3,
+	KindCity:       2,
+	KindParty:      1,
+}
+
+// Suggestion is an autocomplete entry.
+type Suggestion struct {
+	Kind         string `json:"kind"`
+	Text         string `json:"text"`
+	State        string `json:"state,omitempty"`
+	City         string `json:"city,omitempty"`
+	Year         int    `json:"year,omitempty"`
+	SequentialID string `json:"sequential_id,omitempty"`
+}
+
+// Scope restricts the suggestions returned by a lookup. Empty fields do not
+// restrict anything. Suggestions without year (e.g. cities) match any year.
+type Scope struct {
+	State string
+	City  string
+	Year  int
+}
+
+func (s Scope) contains(sg *Suggestion) bool {
+	return (s.State == "" || s.State == sg.State) &&
+		(s.City == "" || s.City == sg.City) &&
+		(s.Year == 0 || sg.Year == 0 || s.Year == sg.Year)
+}
+
+type indexKey struct {
+	word string
+	id   int
+}
+
+// PrefixIndex is an in-memory index that finds suggestions by the prefix of
+// any of their words. It is safe for concurrent use and can be rebuilt
+// while serving lookups.
+type PrefixIndex struct {
+	mu          sync.RWMutex
+	keys        []indexKey // Sorted by word.
+	suggestions []*Suggestion
+	words       [][]string // Normalized words of each suggestion.
+}
+
+// NewPrefixIndex returns an empty index.
+func NewPrefixIndex() *PrefixIndex {
+	return &PrefixIndex{}
+}
+
+// Rebuild replaces the content of the index. Repeated suggestions are
+// indexed only once.
+func (idx *PrefixIndex) Rebuild(suggestions []*Suggestion) {
+	var (
+		keys   []indexKey
+		unique []*Suggestion
+		words  [][]string
+		seen   = make(map[Suggestion]bool)
+	)
+	for _, sg := range suggestions {
+		if sg.Text == "" || seen[*sg] {
+			continue
+		}
+		seen[*sg] = true
+		id := len(unique)
+		unique = append(unique, sg)
+		w := Tokenize(sg.Text)
+		words = append(words, w)
+		for _, word := range w {
+			keys = append(keys, indexKey{word, id})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].word < keys[j].word
+	})
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.keys = keys
+	idx.suggestions = unique
+	idx.words = words
+}
+
+// Len returns the number of suggestions in the index.
+func (idx *PrefixIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.suggestions)
+}
+
+// Lookup returns up to limit suggestions within scope having words starting
+// with every token of the query, ranked by relevance.
+func (idx *PrefixIndex) Lookup(query string, scope Scope, limit int) []*Suggestion {
+	tokens := QueryTokens(query)
+	if len(tokens) == 0 || len([]rune(strings.Join(tokens, ""))) < MinPrefixSize || limit <= 0 {
+		return nil
+	}
+	// The longest token is the most selective one.
+	longest := tokens[0]
+	for _, t := range tokens {
+		if len(t) > len(longest) {
+			longest = t
+		}
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	type candidate struct {
+		sg    *Suggestion
+		score int
+	}
+	var found []candidate
+	seen := make(map[int]bool)
+	start := sort.Search(len(idx.keys), func(i int) bool {
+		return idx.keys[i].word >= longest
+	})
+	for i := start; i < len(idx.keys) && strings.HasPrefix(idx.keys[i].word, longest); i++ {
+		id := idx.keys[i].id
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		sg := idx.suggestions[id]
+		if !scope.contains(sg) || !matchAllPrefixes(tokens, idx.words[id]) {
+			continue
+		}
+		found = append(found, candidate{sg, rank(tokens, idx.words[id], sg)})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score > found[j].score
+		}
+		if len(found[i].sg.Text) != len(found[j].sg.Text) {
+			return len(found[i].sg.Text) < len(found[j].sg.Text)
+		}
+		return found[i].sg.Text < found[j].sg.Text
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	result := make([]*Suggestion, len(found))
+	for i, c := range found {
+		result[i] = c.sg
+	}
+	return result
+}
+
+func matchAllPrefixes(tokens, words []string) bool {
+	for _, t := range tokens {
+		ok := false
+		for _, w := range words {
+			if strings.HasPrefix(w, t) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// rank favours the kind of the suggestion, then suggestions starting with
+// the query and then exact word matches.
+func rank(tokens, words []string, sg *Suggestion) int {
+	score := kindWeight[sg.Kind] * 10
+	if len(words) > 0 && strings.HasPrefix(words[0], tokens[0]) {
+		score += 5
+	}
+	for _, t := range tokens {
+		for _, w := range words {
+			if w == t {
+				score += 2
+				break
+			}
+		}
+	}
+	return score
+}
@@ -0,0 +1,56 @@
+package search
+
+import "testing"
+
+func newTestIndex() *PrefixIndex {
+	idx := NewPrefixIndex()
+	idx.Rebuild([]*Suggestion{
+		{Kind: KindBallotName, Text: "PROFESSORA MARIA JOSÉ", State: "AL", City: "MACEIÓ", Year: 2020, SequentialID: "1"},
+		{Kind: KindName, Text: "MARIA JOSÉ DA SILVA", State: "AL", City: "MACEIÓ", Year: 2020, SequentialID: "1"},
+		{Kind: KindBallotName, Text: "JOSEFA", State: "PE", City: "RECIFE", Year: 2020, SequentialID: "2"},
+		{Kind: KindParty, Text: "PARTIDO A", State: "AL", City: "MACEIÓ", Year: 2020},
+		{Kind: KindParty, Text: "PARTIDO A", State: "AL", City: "MACEIÓ", Year: 2020},
+		{Kind: KindCity, Text: "MACEIÓ", State: "AL", City: "MACEIÓ"},
+		{Kind: KindCity, Text: "MARAGOGI", State: "AL", City: "MARAGOGI"},
+	})
+	return idx
+}
+
+func TestPrefixIndexRebuild(t *testing.T) {
+	if got := newTestIndex().Len(); got != 6 {
+		t.Errorf("want repeated suggestions indexed once (6 entries), got %d", got)
+	}
+}
+
+func TestPrefixIndexLookup(t *testing.T) {
+	idx := newTestIndex()
+	testCases := []struct {
+		query string
+		scope Scope
+		want  []string
+	}{
+		{"jos", Scope{}, []string{"JOSEFA", "PROFESSORA MARIA JOSÉ", "MARIA JOSÉ DA SILVA"}},
+		{"jos", Scope{State: "AL"}, []string{"PROFESSORA MARIA JOSÉ", "MARIA JOSÉ DA SILVA"}},
+		{"maria jo", Scope{}, []string{"PROFESSORA MARIA JOSÉ", "MARIA JOSÉ DA SILVA"}},
+		{"mace", Scope{Year: 2020}, []string{"MACEIÓ"}},
+		{"ma", Scope{City: "MARAGOGI"}, []string{"MARAGOGI"}},
+		{"part", Scope{Year: 2016}, nil},
+		{"m", Scope{}, nil},
+		{"xyz", Scope{}, nil},
+	}
+	for _, tc := range testCases {
+		got := idx.Lookup(tc.query, tc.scope, 10)
+		if len(got) != len(tc.want) {
+			t.Errorf("Lookup(%q, %+v): want %v, got %d suggestions", tc.query, tc.scope, tc.want, len(got))
+			continue
+		}
+		for i := range got {
+			if got[i].Text != tc.want[i] {
+				t.Errorf("Lookup(%q, %+v)[%d]: want %s, got %s", tc.query, tc.scope, i, tc.want[i], got[i].Text)
+			}
+		}
+	}
+	if got := idx.Lookup("ma", Scope{}, 2); len(got) != 2 {
+		t.Errorf("want limit to be respected, got %d suggestions", len(got))
+	}
+}
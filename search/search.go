@@ -0,0 +1,180 @@
+// Package search implements the candidate name search: normalisation of
+// accents and case, tokenisation, relevance ranking and the construction of
+// safe, accent-insensitive regular expressions for the database.
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxTokens is the maximum number of tokens considered in a query.
+	MaxTokens = 5
+	// MaxTokenSize is the maximum size (in runes) of a query token.
+	MaxTokenSize = 30
+	// Tokens smaller than minSubstringSize only match the beginning of words.
+	minSubstringSize = 3
+)
+
+// Scores given to a query token depending on where and how it matched.
+const (
+	exactMatch     = 3
+	prefixMatch    = 2
+	substringMatch = 1
+
+	ballotNameWeight   = 4
+	ballotNumberWeight = 4
+	nameWeight         = 2
+	partyWeight        = 1
+)
+
+var (
+	// Accent-insensitive character classes used when building regular
+	// expressions. Both cases are listed because the case-insensitive flag
+	// is not reliable for non-ASCII letters in every database.
+	accentClasses = map[rune]string{
+		'a': "aáàâãäAÁÀÂÃÄ",
+		'e': "eéèêëEÉÈÊË",
+		'i': "iíìîïIÍÌÎÏ",
+		'o': "oóòôõöOÓÒÔÕÖ",
+		'u': "uúùûüUÚÙÛÜ",
+		'c': "cçCÇ",
+		'n': "nñNÑ",
+	}
+)
+
+// Document holds the searchable fields of a candidature.
+type Document struct {
+	BallotName   string
+	Name         string
+	Party        string
+	BallotNumber int
+}
+
+// Normalize lowercases s and removes its diacritics, so "JOSÉ" and "jose"
+// are considered equal.
+func Normalize(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	r, _, err := transform.String(t, s)
+	if err != nil {
+		r = s
+	}
+	return strings.ToLower(r)
+}
+
+// Tokenize normalizes s and splits it into words. Any character which is
+// not a letter or a digit is a separator.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(Normalize(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// QueryTokens tokenizes a user query, discarding repeated tokens and
+// limiting the number and size of tokens.
+func QueryTokens(query string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, t := range Tokenize(query) {
+		if r := []rune(t); len(r) > MaxTokenSize {
+			t = string(r[:MaxTokenSize])
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		tokens = append(tokens, t)
+		if len(tokens) == MaxTokens {
+			break
+		}
+	}
+	return tokens
+}
+
+// Score returns the relevance of doc for the query. Every query token must
+// match at least one of the document fields, otherwise the score is 0.
+// Tokens match words exactly, as a prefix or, when long enough, anywhere
+// inside the word.
+func Score(query string, doc Document) float64 {
+	tokens := QueryTokens(query)
+	if len(tokens) == 0 {
+		return 0
+	}
+	ballotName := Tokenize(doc.BallotName)
+	name := Tokenize(doc.Name)
+	party := Tokenize(doc.Party)
+	number := strconv.Itoa(doc.BallotNumber)
+	var total float64
+	for _, t := range tokens {
+		best := ballotNameWeight * matchTokens(t, ballotName)
+		if s := nameWeight * matchTokens(t, name); s > best {
+			best = s
+		}
+		if s := partyWeight * matchTokens(t, party); s > best {
+			best = s
+		}
+		// Ballot numbers must match exactly, as people either know it or not.
+		if doc.BallotNumber != 0 && t == number && ballotNumberWeight*exactMatch > best {
+			best = ballotNumberWeight * exactMatch
+		}
+		if best == 0 {
+			return 0
+		}
+		total += float64(best)
+	}
+	return total
+}
+
+// Match reports whether doc matches the query.
+func Match(query string, doc Document) bool {
+	return Score(query, doc) > 0
+}
+
+func matchTokens(t string, tokens []string) int {
+	best := 0
+	for _, dt := range tokens {
+		switch {
+		case dt == t:
+			return exactMatch
+		case strings.HasPrefix(dt, t):
+			best = prefixMatch
+		case best == 0 && len([]rune(t)) >= minSubstringSize && strings.Contains(dt, t):
+			best = substringMatch
+		}
+	}
+	return best
+}
+
+// Pattern returns an accent-insensitive regular expression which matches
+// any text containing the token. All regular expression metacharacters in
+// the token are escaped. As it does not consider word boundaries, it must
+// be used only to pre-filter documents that will be ranked by Score.
+func Pattern(token string) string {
+	var b strings.Builder
+	for _, r := range Normalize(token) {
+		if class, ok := accentClasses[r]; ok {
+			b.WriteString("[" + class + "]")
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(string(r)))
+	}
+	return b.String()
+}
+
+// Number returns the token as a ballot number, if it is one.
+func Number(token string) (int, bool) {
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(token)
+	return n, err == nil
+}
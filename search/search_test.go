@@ -0,0 +1,78 @@
+package search
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct{ in, want string }{
+		{"JOSÉ", "jose"},
+		{"Conceição", "conceicao"},
+		{"ÁÉÍÓÚ àâãõü", "aeiou aaaou"},
+		{"Dr. Antônio", "dr. antonio"},
+	}
+	for _, tc := range testCases {
+		if got := Normalize(tc.in); got != tc.want {
+			t.Errorf("Normalize(%q): want %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestQueryTokens(t *testing.T) {
+	want := []string{"maria", "jose", "12"}
+	if got := QueryTokens("  Maria-JOSÉ (12) maria "); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got := QueryTokens("a b c d e f g"); len(got) != MaxTokens {
+		t.Errorf("want %d tokens, got %d", MaxTokens, len(got))
+	}
+}
+
+func TestScore(t *testing.T) {
+	doc := Document{BallotName: "PROFESSORA MARIA JOSÉ", Name: "MARIA JOSÉ DA SILVA", Party: "PARTIDO A", BallotNumber: 12345}
+	testCases := []struct {
+		query string
+		match bool
+	}{
+		{"jose", true},
+		{"José", true},
+		{"prof maria", true},
+		{"silva", true},
+		{"partido", true},
+		{"12345", true},
+		{"123", false},
+		{"ss", false},
+		{"sso", true},
+		{"da", true},
+		{"maria pedro", false},
+		{".*", false},
+		{"", false},
+	}
+	for _, tc := range testCases {
+		if got := Match(tc.query, doc); got != tc.match {
+			t.Errorf("Match(%q): want %t, got %t", tc.query, tc.match, got)
+		}
+	}
+	other := Document{BallotName: "JOSEFA", Name: "JOSEFA DE SOUZA"}
+	if Score("jose", doc) <= Score("jose", other) {
+		t.Errorf("want exact token match to rank higher than a prefix match")
+	}
+	civil := Document{BallotName: "ZÉ", Name: "JOSÉ PEREIRA"}
+	if Score("jose", doc) <= Score("jose", civil) {
+		t.Errorf("want ballot name match to rank higher than a civil name match")
+	}
+}
+
+func TestPattern(t *testing.T) {
+	re := regexp.MustCompile("(?i)" + Pattern("José"))
+	for _, s := range []string{"JOSÉ", "jose", "MARIA JOSE"} {
+		if !re.MatchString(s) {
+			t.Errorf("want %q to match %s", s, re)
+		}
+	}
+	if got := Pattern("a.b(c)"); got != "[aáàâãäAÁÀÂÃÄ]\\.b\\([cçCÇ]\\)" {
+		t.Errorf("want metacharacters escaped, got %s", got)
+	}
+}
@@ -1,73 +1,101 @@
 package main
 
 import (
-	b64 "encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/candidatos-info/descritor"
 	"github.com/candidatos-info/site/db"
-	"github.com/candidatos-info/site/email"
-	"github.com/candidatos-info/site/exception"
 	"github.com/candidatos-info/site/token"
 	"github.com/labstack/echo"
 )
 
 const (
-	imageWidth  = 100
-	imageHeight = 30
-	logoURL     = "https://s3.amazonaws.com/candidatos-info-public/Logo-1px.png"
+	// Submissions of the same email without a form key are considered
+	// repeated within this window.
+	loginIdempotencyWindow = 5 * time.Minute
 )
 
-func newSouCandidatoFormHandler(db *db.Client, tokenService *token.Token, emailClient *email.Client) echo.HandlerFunc {
+func newSouCandidatoFormHandler(db db.Store, tokenService *token.Token, guard *loginGuard) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		email := c.FormValue("email")
-		return c.Render(http.StatusOK, "sou-candidato-success.html", map[string]interface{}{
-			"Text": login(db, tokenService, emailClient, email),
+		address := c.FormValue("email")
+		status, text := guard.check(c, address, time.Now())
+		if text == "" {
+			text = login(db, tokenService, address, c.FormValue("chave"), c.RealIP(), c.Request().UserAgent())
+		}
+		return c.Render(status, "sou-candidato-success.html", map[string]interface{}{
+			"Text": text,
 		})
 	}
 }
 
-func login(db *db.Client, tokenService *token.Token, emailClient *email.Client, email string) string {
-	if !emailRegex.MatchString(email) {
-		return fmt.Sprintf("email inválido %s", email)
+// loginRequestedMessage is shown whether the email is registered or not, so
+// the form can not be used to find out which emails are registered.
+func loginRequestedMessage(address string) string {
+	return fmt.Sprintf("Se o email %s estiver registrado no TSE para a eleição de %d, você receberá em instantes um link de acesso para cada candidatura registrada com ele. Verifique sua caixa de spam caso não encontre.", address, globals.Year)
+}
+
+// login queues an email with one single use link for each candidature
+// registered with the email in the current year, so campaign staff sharing
+// the same email can manage each candidature separately. The formKey is
+// generated when the form is rendered and avoids sending the email twice
+// when the form is submitted twice.
+func login(db db.Store, tokenService *token.Token, address, formKey, ip, userAgent string) string {
+	if !emailRegex.MatchString(address) {
+		return fmt.Sprintf("email inválido %s", address)
 	}
-	foundCandidate, err := db.GetCandidateByEmail(strings.ToUpper(email), globals.Year)
-	switch {
-	case err != nil && err.(*exception.Exception).Code == exception.NotFound:
-		return fmt.Sprintf("O email %s não foi encontrado no registro do TSE. Por favor verifique se houve algum erro na digitação.", email)
-	case err != nil:
-		log.Printf("erro searching for candidates by e-mail (%s):%q", email, err)
+	candidates, err := db.FindCandidatesByEmail(strings.ToUpper(address), globals.Year)
+	if err != nil {
+		log.Printf("erro searching for candidates by e-mail (%s):%q", address, err)
 		return "Erro inesperado. Por favor tentar novamente mais tarde."
 	}
-	accessToken, err := tokenService.GetToken(email)
+	if len(candidates) == 0 {
+		return loginRequestedMessage(address)
+	}
+	links := make([]string, len(candidates))
+	for i, c := range candidates {
+		link, err := newLoginLink(db, tokenService, address, c.SequencialCandidate, ip, userAgent)
+		if err != nil {
+			log.Printf("failed to get login link for e-mail (%s):%q", address, err)
+			return "Erro inesperado. Por favor tentar novamente mais tarde."
+		}
+		links[i] = link
+	}
+	message, err := buildProfileAccessEmail(address, candidates, links)
 	if err != nil {
-		log.Printf("failed to get acess token for e-mail (%s):%q", email, err)
+		log.Printf("failed to build access email (%s):%q\n", address, err)
 		return "Erro inesperado. Por favor tentar novamente mais tarde."
 	}
-	encodedAccessToken := b64.StdEncoding.EncodeToString([]byte(accessToken))
-	emailMessage := buildProfileAccessEmail(foundCandidate, encodedAccessToken)
-	subject := fmt.Sprintf("Link para acesso à candidatura %d de %s/%s", foundCandidate.BallotNumber, foundCandidate.City, foundCandidate.State)
-	if err := emailClient.Send(emailClient.Email, []string{foundCandidate.Email}, subject, emailMessage); err != nil {
-		log.Printf("failed on sending email (%s):%q\n", email, err)
+	message.From = emailFrom
+	message.To = []string{candidates[0].Email}
+	if formKey == "" {
+		formKey = strconv.FormatInt(time.Now().Unix()/int64(loginIdempotencyWindow.Seconds()), 10)
+	}
+	idempotencyKey := fmt.Sprintf("sou-candidato/%s/%s", strings.ToLower(address), formKey)
+	if _, err := enqueueEmail(db, idempotencyKey, message); err != nil {
+		log.Printf("failed on queueing email (%s):%q\n", address, err)
 		return "Erro inesperado. Por favor tentar novamente mais tarde."
 	}
-	return fmt.Sprintf("Email com código de acesso enviado para %s. Verifique sua caixa de spam caso não encontre.", email)
+	return loginRequestedMessage(address)
 }
 
-func buildProfileAccessEmail(candidate *descritor.CandidateForDB, accessToken string) string {
-	link := fmt.Sprintf("%s/atualizar-candidatura?access_token=%s", siteURL, accessToken)
-	var emailBodyBuilder strings.Builder
-	emailBodyBuilder.WriteString(fmt.Sprintf("Olá, %s!<br><br>", candidate.Name))
-	emailBodyBuilder.WriteString(fmt.Sprintf("Identificamos através dos dados públicos do TSE que você está cadastrado na eleição de %d na cidade de %s no estado de %s como %s.<br><br><br>", globals.Year, candidate.City, candidate.State, candidate.Role))
-	emailBodyBuilder.WriteString(fmt.Sprintf("Recebemos sua solicitação para acessar a plataforma candidatos.info e editar seu perfil. Para acessar <a href=\"%s\">clique aqui</a>. <br><br>Caso o link não esteja funcionando copie e cole no navegador o seguinte link:<br> %s", link, link))
-	emailBodyBuilder.WriteString("<br><br><br>Caso tenha recebido este email por engano, por favor desconsidere-o.<br>")
-	emailBodyBuilder.WriteString(fmt.Sprintf("Atenciosamente, <br><img src=%s width=%d height=%d>", logoURL, imageWidth, imageHeight))
-	return emailBodyBuilder.String()
-}
-
-func souCandidatoGET(c echo.Context) error {
-	return c.Render(http.StatusOK, "sou-candidato.html", nil)
+func newSouCandidatoHandler(guard *loginGuard) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		formKey, err := newID()
+		if err != nil {
+			log.Printf("failed to create form key:%q\n", err)
+		}
+		desafio, err := guard.challenges.Issue(time.Now())
+		if err != nil {
+			log.Printf("failed to create login challenge:%q\n", err)
+		}
+		return c.Render(http.StatusOK, "sou-candidato.html", map[string]interface{}{
+			"FormKey":    formKey,
+			"Challenge":  desafio,
+			"Difficulty": guard.challenges.Difficulty(),
+		})
+	}
 }
@@ -1,22 +1,34 @@
 package token
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+// Kinds of tokens issued by the service.
+const (
+	// LoginKind tokens are sent by email (magic links) and can be used only
+	// once, to start a session.
+	LoginKind = "login"
+
+	// SessionKind tokens identify a session started through a login token.
+	SessionKind = "session"
+)
+
+// Errors returned when getting the claims of a token.
+var (
+	ErrInvalid = errors.New("invalid token")
+	ErrExpired = errors.New("expired token")
+)
+
 // Token struct
 type Token struct {
 	secret string
 }
 
-var (
-	// We are going to expire all tokens at zero hour (Brasilia Standard Time) of the day of the election.
-	expirationDate = time.Date(2020, 11, 29, 0, 0, 0, 0, time.UTC).Add(-3 * time.Hour).Unix() // 3 is the difference between UTC and BST.
-)
-
 // New returns a new token service
 func New(secret string) *Token {
 	return &Token{
@@ -24,44 +36,60 @@ func New(secret string) *Token {
 	}
 }
 
-// GetToken returns a new token
-func (t *Token) GetToken(email string) (string, error) {
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// Issue returns a new signed token of the given kind. The id must match a
+// record kept by the server (login token or session), so the token can be
+// tracked and revoked. When seqID is not empty the token is bound to that
+// candidature through the "seqid" claim.
+func (t *Token) Issue(kind, id, email, seqID string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"kind":  kind,
+		"jti":   id,
 		"email": email,
-		"exp":   expirationDate,
-	})
+		"exp":   time.Now().Add(ttl).Unix(),
+	}
+	if seqID != "" {
+		claims["seqid"] = seqID
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(t.secret))
 }
 
 // IsValid checks if token is valid
 func (t *Token) IsValid(auhtorization string) bool {
+	_, err := t.GetClaims(auhtorization)
+	return err == nil
+}
+
+// GetClaims verifies the token signature and expiration and transforms the
+// token string into a map with its claims. It returns ErrExpired when the
+// token is well signed but expired and ErrInvalid on any other failure.
+func (t *Token) GetClaims(auhtorization string) (map[string]string, error) {
 	token, err := jwt.Parse(auhtorization, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("error on validating auth token")
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
 		}
 		return []byte(t.secret), nil
 	})
 	if err != nil {
-		return false
+		if vErr, ok := err.(*jwt.ValidationError); ok && vErr.Errors == jwt.ValidationErrorExpired {
+			return nil, ErrExpired
+		}
+		return nil, ErrInvalid
 	}
-	return token.Valid
-}
-
-// GetClaims transforms the token string into a map with its claims
-func GetClaims(auhtorization string) (map[string]string, error) {
-	token, _ := jwt.Parse(auhtorization, func(token *jwt.Token) (interface{}, error) {
-		return []byte(""), nil
-	})
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		claimsMap := make(map[string]string)
-		for key, value := range claims {
-			if key != "exp" {
-				claimsMap[key] = value.(string)
-			}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalid
+	}
+	claimsMap := make(map[string]string)
+	for key, value := range claims {
+		if key == "exp" {
+			continue
 		}
-		return claimsMap, nil
+		s, ok := value.(string)
+		if !ok {
+			return nil, ErrInvalid
+		}
+		claimsMap[key] = s
 	}
-	return nil, fmt.Errorf("could not get claims")
-
+	return claimsMap, nil
 }
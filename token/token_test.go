@@ -1,13 +1,18 @@
 package token
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
 
 const secret = "hgde34jnbvcdewscvbhytrewq5678kncxcnbvcxswqw34fvbkuytr"
 
-func TestGetToken(t *testing.T) {
+func TestIssue(t *testing.T) {
 	authService := New(secret)
 	email := "abuarquemf@gmail.com"
-	if _, err := authService.GetToken(email); err != nil {
+	if _, err := authService.Issue(LoginKind, "id", email, "", time.Hour); err != nil {
 		t.Errorf("want error nil, got %q", err)
 	}
 }
@@ -15,7 +20,7 @@ func TestGetToken(t *testing.T) {
 func TestIsValid(t *testing.T) {
 	authService := New(secret)
 	email := "abuarquemf@gmail.com"
-	token, err := authService.GetToken(email)
+	token, err := authService.Issue(SessionKind, "id", email, "", time.Hour)
 	if err != nil {
 		t.Errorf("want error nil, got %q", err)
 	}
@@ -23,20 +28,64 @@ func TestIsValid(t *testing.T) {
 	if isValid == false {
 		t.Errorf("expected to have a valid token")
 	}
+	expired, err := authService.Issue(SessionKind, "id", email, "", -time.Minute)
+	if err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if authService.IsValid(expired) {
+		t.Errorf("expected expired token to be invalid")
+	}
+	if New("other secret").IsValid(token) {
+		t.Errorf("expected token signed with another secret to be invalid")
+	}
 }
 
 func TestGetClaims(t *testing.T) {
 	authService := New(secret)
 	email := "abuarquemf@gmail.com"
-	token, err := authService.GetToken(email)
+	token, err := authService.Issue(LoginKind, "id", email, "", time.Hour)
 	if err != nil {
 		t.Errorf("want error nil, got %q", err)
 	}
-	claims, err := GetClaims(token)
+	claims, err := authService.GetClaims(token)
 	if err != nil {
 		t.Errorf("want err nil when getting claims")
 	}
 	if email != claims["email"] {
 		t.Errorf("want email %s, got %s", email, claims["email"])
 	}
+	if claims["kind"] != LoginKind || claims["jti"] != "id" {
+		t.Errorf("want kind %s and id id, got %v", LoginKind, claims)
+	}
+	if _, ok := claims["seqid"]; ok {
+		t.Errorf("want no seqid claim when issued without sequential ID, got %v", claims)
+	}
+	bound, err := authService.Issue(SessionKind, "id", email, "123", time.Hour)
+	if err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if claims, err := authService.GetClaims(bound); err != nil || claims["seqid"] != "123" {
+		t.Errorf("want seqid 123, got %v (error %v)", claims, err)
+	}
+	if _, err := New("other secret").GetClaims(token); err != ErrInvalid {
+		t.Errorf("want ErrInvalid for token signed with another secret, got %v", err)
+	}
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"kind": SessionKind, "jti": "id", "email": email})
+	unsignedToken, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("want error nil, got %q", err)
+	}
+	if _, err := authService.GetClaims(unsignedToken); err != ErrInvalid {
+		t.Errorf("want ErrInvalid for unsigned token, got %v", err)
+	}
+	expired, err := authService.Issue(SessionKind, "id", email, "", -time.Minute)
+	if err != nil {
+		t.Errorf("want error nil, got %q", err)
+	}
+	if _, err := authService.GetClaims(expired); err != ErrExpired {
+		t.Errorf("want ErrExpired, got %v", err)
+	}
+	if _, err := authService.GetClaims("not a token"); err != ErrInvalid {
+		t.Errorf("want ErrInvalid for malformed token, got %v", err)
+	}
 }